@@ -47,6 +47,8 @@ func (src *RKE2Config) ConvertTo(dstRaw conversion.Hub) error {
 		dst.Spec.AgentConfig.AirGappedChecksum = restored.Spec.AgentConfig.AirGappedChecksum
 	}
 
+	dst.Spec.AgentConfig.JoinServerFailover = restored.Spec.AgentConfig.JoinServerFailover
+
 	return nil
 }
 
@@ -102,6 +104,8 @@ func (src *RKE2ConfigTemplate) ConvertTo(dstRaw conversion.Hub) error {
 		dst.Spec.Template.Spec.AgentConfig.AirGappedChecksum = restored.Spec.Template.Spec.AgentConfig.AirGappedChecksum
 	}
 
+	dst.Spec.Template.Spec.AgentConfig.JoinServerFailover = restored.Spec.Template.Spec.AgentConfig.JoinServerFailover
+
 	return nil
 }
 
@@ -143,6 +147,6 @@ func Convert_v1alpha1_RKE2AgentConfig_To_v1beta1_RKE2AgentConfig(in *RKE2AgentCo
 }
 
 func Convert_v1beta1_RKE2AgentConfig_To_v1alpha1_RKE2AgentConfig(in *bootstrapv1.RKE2AgentConfig, out *RKE2AgentConfig, s apiconversion.Scope) error {
-	// We have to invoke conversion manually because of the added AirGappedChecksum field.
+	// We have to invoke conversion manually because of the added AirGappedChecksum and JoinServerFailover fields.
 	return autoConvert_v1beta1_RKE2AgentConfig_To_v1alpha1_RKE2AgentConfig(in, out, s)
 }
@@ -429,6 +429,7 @@ func autoConvert_v1beta1_RKE2AgentConfig_To_v1alpha1_RKE2AgentConfig(in *v1beta1
 	out.LoadBalancerPort = in.LoadBalancerPort
 	out.AirGapped = in.AirGapped
 	// WARNING: in.AirGappedChecksum requires manual conversion: does not exist in peer-type
+	// WARNING: in.JoinServerFailover requires manual conversion: does not exist in peer-type
 	out.Format = Format(in.Format)
 	if err := Convert_v1beta1_AdditionalUserData_To_v1alpha1_AdditionalUserData(&in.AdditionalUserData, &out.AdditionalUserData, s); err != nil {
 		return err
@@ -40,6 +40,11 @@ const (
 	//
 	// NOTE: Having the cluster infrastructure ready is a pre-condition for starting to create machines.
 	WaitingForClusterInfrastructureReason string = "WaitingForClusterInfrastructure"
+
+	// IncompatibleControlPlaneVersionReason (Severity=Warning) documents a bootstrap secret generation process
+	// refusing to generate the data secret of an agent whose version is newer than the control plane version
+	// or too many minor versions behind it.
+	IncompatibleControlPlaneVersionReason string = "IncompatibleControlPlaneVersion"
 )
 
 const (
@@ -23,6 +23,11 @@ import (
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
+// RegenerateBootstrapDataAnnotation requests the bootstrap data of a RKE2Config to be generated again,
+// e.g. after the token of the cluster was rotated, while the machine is not provisioned yet.
+// The annotation is removed once the bootstrap data has been generated.
+const RegenerateBootstrapDataAnnotation = "bootstrap.cluster.x-k8s.io/regenerate-bootstrap-data"
+
 // Format specifies the output format of the bootstrap data
 // +kubebuilder:validation:Enum=cloud-config;ignition
 type Format string
@@ -156,6 +161,11 @@ type RKE2AgentConfig struct {
 	//+optional
 	AirGappedChecksum string `json:"airGappedChecksum,omitempty"`
 
+	// JoinServerFailover renders a script in the bootstrap data which checks the available servers in turn before
+	// starting RKE2, and joins the node through the first reachable one instead of only the server selected by the provider.
+	//+optional
+	JoinServerFailover bool `json:"joinServerFailover,omitempty"`
+
 	// Format specifies the output format of the bootstrap data. Defaults to cloud-config.
 	// +optional
 	Format Format `json:"format,omitempty"`
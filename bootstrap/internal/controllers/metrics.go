@@ -0,0 +1,81 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+)
+
+const (
+	metricsNamespace = "capi_rke2"
+	metricsSubsystem = "bootstrap"
+
+	// The buckets of the duration to Ready histogram start at 15 seconds, each one twice as large as the previous one.
+	readyDurationBucketStart = 15
+	readyDurationBucketCount = 10
+	readyDurationBucketRatio = 2
+)
+
+var (
+	bootstrapDataErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "data_generation_errors_total",
+		Help:      "Number of failed attempts to generate the bootstrap data of a RKE2Config, by format.",
+	}, []string{"format"})
+
+	readyDurationHistogram = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "config_ready_duration_seconds",
+		Help:      "Time from the creation of a RKE2Config to its bootstrap data being ready.",
+		Buckets:   prometheus.ExponentialBuckets(readyDurationBucketStart, readyDurationBucketRatio, readyDurationBucketCount),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(bootstrapDataErrorsCounter, readyDurationHistogram)
+}
+
+// recordBootstrapMetrics records the outcome of the reconciliation of a RKE2Config which was not ready
+// before it: either a failure to generate its bootstrap data, or the time it took to become ready.
+// The time to become ready is not recorded when the bootstrap data is regenerated, as the config was ready before.
+func recordBootstrapMetrics(config *bootstrapv1.RKE2Config, reconcileErr error, now time.Time) {
+	if reconcileErr != nil {
+		format := config.Spec.AgentConfig.Format
+		if format == "" {
+			format = bootstrapv1.CloudConfig
+		}
+
+		bootstrapDataErrorsCounter.WithLabelValues(string(format)).Inc()
+
+		return
+	}
+
+	if _, ok := config.Annotations[bootstrapv1.RegenerateBootstrapDataAnnotation]; ok {
+		return
+	}
+
+	if config.Status.Ready {
+		readyDurationHistogram.Observe(now.Sub(config.CreationTimestamp.Time).Seconds())
+	}
+}
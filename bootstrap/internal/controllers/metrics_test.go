@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+)
+
+var _ = Describe("Bootstrap metrics", func() {
+	var (
+		now    time.Time
+		config *bootstrapv1.RKE2Config
+	)
+
+	readyDurationSamples := func() uint64 {
+		metric := &dto.Metric{}
+		Expect(readyDurationHistogram.Write(metric)).To(Succeed())
+
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		config = &bootstrapv1.RKE2Config{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "metrics-config",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(now.Add(-time.Minute)),
+			},
+		}
+	})
+
+	It("should record the time for the bootstrap data to be ready", func() {
+		samples := readyDurationSamples()
+
+		recordBootstrapMetrics(config, nil, now)
+		Expect(readyDurationSamples()).To(Equal(samples))
+
+		config.Status.Ready = true
+		recordBootstrapMetrics(config, nil, now)
+		Expect(readyDurationSamples()).To(Equal(samples + 1))
+	})
+
+	It("should not record the time to be ready of regenerated bootstrap data", func() {
+		samples := readyDurationSamples()
+
+		config.CreationTimestamp = metav1.NewTime(now.Add(-72 * time.Hour))
+		config.Annotations = map[string]string{bootstrapv1.RegenerateBootstrapDataAnnotation: ""}
+		config.Status.Ready = true
+
+		recordBootstrapMetrics(config, nil, now)
+		Expect(readyDurationSamples()).To(Equal(samples))
+	})
+
+	It("should record the bootstrap data generation errors by format", func() {
+		failures := testutil.ToFloat64(bootstrapDataErrorsCounter.WithLabelValues(string(bootstrapv1.Ignition)))
+
+		config.Spec.AgentConfig.Format = bootstrapv1.Ignition
+		recordBootstrapMetrics(config, errors.New("failed to generate the bootstrap data"), now)
+
+		Expect(testutil.ToFloat64(bootstrapDataErrorsCounter.WithLabelValues(string(bootstrapv1.Ignition)))).To(Equal(failures + 1))
+	})
+})
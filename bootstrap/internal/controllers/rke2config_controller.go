@@ -19,6 +19,8 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -48,6 +50,7 @@ import (
 	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/locking"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/registration"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/secret"
 	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
@@ -56,7 +59,6 @@ import (
 const (
 	filePermissions  string = "0640"
 	registrationPort int    = 9345
-	serverURLFormat  string = "https://%v:%v"
 	tokenPrefix      string = "-token"
 )
 
@@ -148,7 +150,18 @@ func (r *RKE2ConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	if scope.Machine.Spec.Bootstrap.DataSecretName != nil && (!scope.Config.Status.Ready || scope.Config.Status.DataSecretName == nil) {
+	if _, ok := scope.Config.Annotations[bootstrapv1.RegenerateBootstrapDataAnnotation]; ok {
+		// The bootstrap data is generated again in the same Secret, which is still referenced by the machine.
+		logger.Info("Regenerating bootstrap data")
+
+		scope.Config.Status.Ready = false
+
+		defer func() {
+			if rerr == nil && scope.Config.Status.Ready {
+				delete(scope.Config.Annotations, bootstrapv1.RegenerateBootstrapDataAnnotation)
+			}
+		}()
+	} else if scope.Machine.Spec.Bootstrap.DataSecretName != nil && (!scope.Config.Status.Ready || scope.Config.Status.DataSecretName == nil) {
 		scope.Config.Status.Ready = true
 		scope.Config.Status.DataSecretName = scope.Machine.Spec.Bootstrap.DataSecretName
 		conditions.MarkTrue(scope.Config, bootstrapv1.DataSecretAvailableCondition)
@@ -159,6 +172,10 @@ func (r *RKE2ConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
+	defer func() {
+		recordBootstrapMetrics(scope.Config, rerr, time.Now())
+	}()
+
 	// Note: can't use IsFalse here because we need to handle the absence of the condition as well as false.
 	if !conditions.IsTrue(scope.Cluster, clusterv1.ControlPlaneInitializedCondition) {
 		return r.handleClusterNotInitialized(ctx, scope)
@@ -354,6 +371,13 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 		return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
 	}
 
+	// Machines of an imported cluster must join its existing servers, never initialize a new cluster.
+	if scope.ControlPlane.Spec.Import != nil {
+		scope.Logger.Info("Requeuing until the imported control plane is initialized")
+
+		return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
+	}
+
 	if !r.RKE2InitLock.Lock(ctx, scope.Cluster, scope.Machine) {
 		scope.Logger.Info("A control plane is already being initialized, requeuing until control plane is ready")
 
@@ -387,40 +411,35 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 
 	conditions.MarkTrue(scope.Config, bootstrapv1.CertificatesAvailableCondition)
 
-	// RKE2 server token must only be generated once, so all nodes join the cluster with the same registration token.
-	var token string
-
-	tokenName := bsutil.TokenName(scope.Cluster.Name)
-	token, err := r.generateAndStoreToken(ctx, scope, tokenName)
-
+	// RKE2 server and agent tokens must only be generated once, so all nodes join the cluster with the same registration tokens.
+	token, err := r.getOrGenerateToken(ctx, scope, bsutil.TokenName(scope.Cluster.Name), "server")
 	if err != nil {
-		if !apierrors.IsAlreadyExists(err) {
-			scope.Logger.Error(err, "unable to generate and store an RKE2 server token")
-
-			return ctrl.Result{}, err
-		}
-
-		token, err = r.getRegistrationTokenFromSecretValue(ctx, tokenName, scope.Cluster.Namespace)
-		if err != nil {
-			scope.Logger.Error(err, "unable to retrieve an RKE2 server token from existing secret")
+		return ctrl.Result{}, err
+	}
 
-			return ctrl.Result{}, err
-		}
-	} else {
-		scope.Logger.Info("RKE2 server token generated and stored in Secret!")
+	// Agents join the cluster with a distinct token, so the bootstrap data of the workers can't be used to join servers.
+	agentToken, err := r.getOrGenerateToken(ctx, scope, bsutil.AgentTokenName(scope.Cluster.Name), "agent")
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
+	nodeIP, nodeExternalIP := nodeIPs(scope)
+
 	configStruct, configFiles, err := rke2.GenerateInitControlPlaneConfig(
 		rke2.ServerConfigOpts{
 			Cluster:              *scope.Cluster,
 			ControlPlaneEndpoint: scope.Cluster.Spec.ControlPlaneEndpoint.Host,
 			Token:                token,
-			ServerURL:            fmt.Sprintf(serverURLFormat, scope.Cluster.Spec.ControlPlaneEndpoint.Host, registrationPort),
+			AgentToken:           agentToken,
+			ServerURL:            serverURL(scope.Cluster.Spec.ControlPlaneEndpoint.Host),
 			ServerConfig:         scope.ControlPlane.Spec.ServerConfig,
 			AgentConfig:          scope.Config.Spec.AgentConfig,
 			Ctx:                  ctx,
 			Client:               r.Client,
 			Version:              scope.getDesiredVersion(),
+			KubeVIP:              scope.ControlPlane.Spec.KubeVIP,
+			NodeIP:               nodeIP,
+			NodeExternalIP:       nodeExternalIP,
 		})
 	if err != nil {
 		return ctrl.Result{}, err
@@ -445,23 +464,8 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 		return ctrl.Result{}, err
 	}
 
-	manifestFiles, err := generateFilesFromManifestConfig(ctx, r.Client, scope.ControlPlane.Spec.ManifestsConfigMapReference)
-	if err != nil {
-		manifestCm := scope.ControlPlane.Spec.ManifestsConfigMapReference.Name
-		ns := scope.ControlPlane.Spec.ManifestsConfigMapReference.Namespace
-
-		if apierrors.IsNotFound(err) {
-			scope.Logger.Error(err, "Manifest ConfigMap referenced!", "namespace", ns, "name", manifestCm)
-
-			return ctrl.Result{}, err
-		}
-
-		scope.Logger.Error(err, "Problem when getting Manifest ConfigMap!", "namespace", ns, "name", manifestCm)
-
-		return ctrl.Result{}, err
-	}
-
-	files = append(files, manifestFiles...)
+	// The manifests are not written in the bootstrap data: once the control plane is initialized, the objects are
+	// applied and pruned by the control plane controller, and files left on the servers would create them again.
 
 	var ntpServers []string
 	if scope.Config.Spec.AgentConfig.NTP != nil {
@@ -610,23 +614,37 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 
 	scope.Logger.Info("RKE2 server token found in Secret!")
 
+	agentToken, err := r.getOrCreateAgentToken(ctx, scope)
+	if err != nil {
+		scope.Logger.Error(err, "unable to get the RKE2 agent token")
+
+		return ctrl.Result{}, err
+	}
+
 	if len(scope.ControlPlane.Status.AvailableServerIPs) == 0 {
 		scope.Logger.Info("No ControlPlane IP Address found for node registration")
 
 		return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
 	}
 
+	serverURLs := joinServerURLs(scope)
+	nodeIP, nodeExternalIP := nodeIPs(scope)
+
 	configStruct, configFiles, err := rke2.GenerateJoinControlPlaneConfig(
 		rke2.ServerConfigOpts{
 			Cluster:              *scope.Cluster,
 			Token:                token,
+			AgentToken:           agentToken,
 			ControlPlaneEndpoint: scope.Cluster.Spec.ControlPlaneEndpoint.Host,
-			ServerURL:            fmt.Sprintf(serverURLFormat, scope.ControlPlane.Status.AvailableServerIPs[0], registrationPort),
+			ServerURL:            serverURLs[0],
 			ServerConfig:         scope.ControlPlane.Spec.ServerConfig,
 			AgentConfig:          scope.Config.Spec.AgentConfig,
 			Ctx:                  ctx,
 			Client:               r.Client,
 			Version:              scope.getDesiredVersion(),
+			KubeVIP:              scope.ControlPlane.Spec.KubeVIP,
+			NodeIP:               nodeIP,
+			NodeExternalIP:       nodeExternalIP,
 		},
 	)
 	if err != nil {
@@ -657,23 +675,7 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 		return ctrl.Result{}, err
 	}
 
-	manifestFiles, err := generateFilesFromManifestConfig(ctx, r.Client, scope.ControlPlane.Spec.ManifestsConfigMapReference)
-	if err != nil {
-		manifestCm := scope.ControlPlane.Spec.ManifestsConfigMapReference.Name
-		ns := scope.ControlPlane.Spec.ManifestsConfigMapReference.Namespace
-
-		if apierrors.IsNotFound(err) {
-			scope.Logger.Error(err, "Manifest ConfigMap not found!", "namespace", ns, "name", manifestCm)
-
-			return ctrl.Result{}, err
-		}
-
-		scope.Logger.Error(err, "Problem when getting ConfigMap referenced by manifestsConfigMapReference", "namespace", ns, "name", manifestCm)
-
-		return ctrl.Result{}, err
-	}
-
-	files = append(files, manifestFiles...)
+	files, preRKE2Commands := joinServerFailover(scope, serverURLs, files)
 
 	var ntpServers []string
 	if scope.Config.Spec.AgentConfig.NTP != nil {
@@ -685,7 +687,7 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 			AirGapped:           scope.Config.Spec.AgentConfig.AirGapped,
 			AirGappedChecksum:   scope.Config.Spec.AgentConfig.AirGappedChecksum,
 			CISEnabled:          scope.Config.Spec.AgentConfig.CISProfile != "",
-			PreRKE2Commands:     scope.Config.Spec.PreRKE2Commands,
+			PreRKE2Commands:     preRKE2Commands,
 			PostRKE2Commands:    scope.Config.Spec.PostRKE2Commands,
 			ConfigFile:          initConfigFile,
 			RKE2Version:         scope.getDesiredVersion(),
@@ -721,26 +723,38 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 // joinWorker implements the part of the Reconciler which bootstraps a worker node
 // after the cluster has been initialized.
 func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (res ctrl.Result, rerr error) {
-	tokenSecret := &corev1.Secret{}
+	if scope.Machine.Spec.Version != nil {
+		controlPlaneVersion := bsutil.ControlPlaneVersion(scope.ControlPlane)
+		if err := bsutil.CheckWorkerVersionSkew(scope.getDesiredVersion(), controlPlaneVersion); err != nil {
+			scope.Logger.Info("Agent version is not compatible with the control plane version, waiting",
+				"version", scope.getDesiredVersion(), "control-plane-version", controlPlaneVersion, "reason", err.Error())
+			conditions.MarkFalse(
+				scope.Config,
+				bootstrapv1.DataSecretAvailableCondition,
+				bootstrapv1.IncompatibleControlPlaneVersionReason,
+				clusterv1.ConditionSeverityWarning,
+				err.Error())
 
-	secretKey := types.NamespacedName{
-		Namespace: scope.Cluster.Namespace,
-		Name:      scope.Cluster.Name + tokenPrefix,
+			return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
+		}
 	}
-	if err := r.Client.Get(ctx, secretKey, tokenSecret); err != nil {
+
+	// Workers only get the agent token, which can't be used to join servers.
+	token, err := r.getOrCreateAgentToken(ctx, scope)
+	if err != nil {
 		scope.Logger.Info(
-			"Token for already initialized RKE2 Cluster not found",
+			"Agent token for already initialized RKE2 Cluster not found",
 			"token-namespace",
 			scope.Cluster.Namespace,
 			"token-name",
-			scope.Cluster.Name+tokenPrefix)
+			bsutil.AgentTokenName(scope.Cluster.Name),
+			"reason",
+			err.Error())
 
 		return ctrl.Result{}, err
 	}
 
-	token := string(tokenSecret.Data["value"])
-
-	scope.Logger.Info("RKE2 server token found in Secret!")
+	scope.Logger.Info("RKE2 agent token found in Secret!")
 
 	if len(scope.ControlPlane.Status.AvailableServerIPs) == 0 {
 		scope.Logger.V(1).Info("No ControlPlane IP Address found for node registration")
@@ -748,9 +762,12 @@ func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (re
 		return ctrl.Result{RequeueAfter: DefaultRequeueAfter}, nil
 	}
 
+	serverURLs := joinServerURLs(scope)
+	nodeIP, nodeExternalIP := nodeIPs(scope)
+
 	configStruct, configFiles, err := rke2.GenerateWorkerConfig(
 		rke2.AgentConfigOpts{
-			ServerURL:              fmt.Sprintf(serverURLFormat, scope.ControlPlane.Status.AvailableServerIPs[0], registrationPort),
+			ServerURL:              serverURLs[0],
 			Token:                  token,
 			AgentConfig:            scope.Config.Spec.AgentConfig,
 			Ctx:                    ctx,
@@ -758,6 +775,8 @@ func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (re
 			CloudProviderName:      scope.ControlPlane.Spec.ServerConfig.CloudProviderName,
 			CloudProviderConfigMap: scope.ControlPlane.Spec.ServerConfig.CloudProviderConfigMap,
 			Version:                scope.getDesiredVersion(),
+			NodeIP:                 nodeIP,
+			NodeExternalIP:         nodeExternalIP,
 		})
 	if err != nil {
 		return ctrl.Result{}, err
@@ -785,13 +804,15 @@ func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (re
 		return ctrl.Result{}, err
 	}
 
+	files, preRKE2Commands := joinServerFailover(scope, serverURLs, files)
+
 	var ntpServers []string
 	if scope.Config.Spec.AgentConfig.NTP != nil {
 		ntpServers = scope.Config.Spec.AgentConfig.NTP.Servers
 	}
 
 	wkInput := &cloudinit.BaseUserData{
-		PreRKE2Commands:         scope.Config.Spec.PreRKE2Commands,
+		PreRKE2Commands:         preRKE2Commands,
 		AirGapped:               scope.Config.Spec.AgentConfig.AirGapped,
 		AirGappedChecksum:       scope.Config.Spec.AgentConfig.AirGappedChecksum,
 		CISEnabled:              scope.Config.Spec.AgentConfig.CISProfile != "",
@@ -827,6 +848,52 @@ func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (re
 	return ctrl.Result{}, nil
 }
 
+// joinServerURLs returns the URLs of the servers the machine can join, in the order to try them.
+// The order depends on the machine, so the joins are spread across the available servers.
+func joinServerURLs(scope *Scope) []string {
+	addresses := registration.OrderJoinAddresses(scope.ControlPlane.Status.AvailableServerIPs, scope.Machine.Name)
+
+	serverURLs := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		serverURLs = append(serverURLs, serverURL(address))
+	}
+
+	return serverURLs
+}
+
+// nodeIPs returns the internal and external IP addresses of the node of each IP family, in dual-stack clusters.
+// The machine addresses are reported by the infrastructure provider once the machine is provisioned, which usually
+// happens after the bootstrap data is generated: they are only known beforehand when the provider assigns them up front,
+// e.g. from an IPAM pool, or when the bootstrap data is generated again. RKE2 detects the node addresses otherwise.
+func nodeIPs(scope *Scope) (string, string) {
+	if !rke2.DualStack(scope.Cluster) {
+		return "", ""
+	}
+
+	return rke2.NodeIPs(scope.Machine.Status.Addresses, clusterv1.MachineInternalIP),
+		rke2.NodeIPs(scope.Machine.Status.Addresses, clusterv1.MachineExternalIP)
+}
+
+// serverURL returns the URL of the RKE2 supervisor on the given host, with IPv6 addresses in brackets.
+func serverURL(host string) string {
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(registrationPort))
+}
+
+// joinServerFailover adds the script selecting the first reachable server to join to the files and the commands
+// run before RKE2 is started, when the join server failover is enabled and more than one server is available.
+func joinServerFailover(scope *Scope, serverURLs []string, files []bootstrapv1.File) ([]bootstrapv1.File, []string) {
+	preRKE2Commands := scope.Config.Spec.PreRKE2Commands
+
+	if !scope.Config.Spec.AgentConfig.JoinServerFailover || len(serverURLs) <= 1 {
+		return files, preRKE2Commands
+	}
+
+	files = append(files, rke2.GenerateJoinServerFailoverFile(serverURLs))
+	preRKE2Commands = append(append([]string{}, preRKE2Commands...), rke2.JoinServerFailoverScriptLocation)
+
+	return files, preRKE2Commands
+}
+
 // getRegistrationTokenFromSecretValue retrieves the registration token from an existing secret's value.
 func (r *RKE2ConfigReconciler) getRegistrationTokenFromSecretValue(ctx context.Context, name, namespace string) (string, error) {
 	tokenSecret := &corev1.Secret{}
@@ -843,6 +910,62 @@ func (r *RKE2ConfigReconciler) getRegistrationTokenFromSecretValue(ctx context.C
 	return string(tokenSecret.Data["value"]), nil
 }
 
+// getOrGenerateToken returns the token stored in the Secret with the given name,
+// after generating and storing it if the Secret doesn't exist yet.
+func (r *RKE2ConfigReconciler) getOrGenerateToken(ctx context.Context, scope *Scope, name, tokenType string) (string, error) {
+	token, err := r.generateAndStoreToken(ctx, scope, name)
+	if err == nil {
+		scope.Logger.Info(fmt.Sprintf("RKE2 %s token generated and stored in Secret!", tokenType))
+
+		return token, nil
+	}
+
+	if !apierrors.IsAlreadyExists(err) {
+		scope.Logger.Error(err, fmt.Sprintf("unable to generate and store an RKE2 %s token", tokenType))
+
+		return "", err
+	}
+
+	token, err = r.getRegistrationTokenFromSecretValue(ctx, name, scope.Cluster.Namespace)
+	if err != nil {
+		scope.Logger.Error(err, fmt.Sprintf("unable to retrieve an RKE2 %s token from existing secret", tokenType))
+
+		return "", err
+	}
+
+	return token, nil
+}
+
+// getOrCreateAgentToken returns the agent token of an initialized cluster.
+// Clusters initialized before the agent token was introduced have no agent token Secret: their nodes joined with the server
+// token, which RKE2 also uses as agent token when none is set. The agent token Secret of these clusters is created
+// with the server token, so the servers and agents joining them keep working without changing the token of the cluster.
+func (r *RKE2ConfigReconciler) getOrCreateAgentToken(ctx context.Context, scope *Scope) (string, error) {
+	agentTokenName := bsutil.AgentTokenName(scope.Cluster.Name)
+
+	agentToken, err := r.getRegistrationTokenFromSecretValue(ctx, agentTokenName, scope.Cluster.Namespace)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return agentToken, err
+	}
+
+	token, err := r.getRegistrationTokenFromSecretValue(ctx, bsutil.TokenName(scope.Cluster.Name), scope.Cluster.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.storeToken(ctx, scope, agentTokenName, token); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", err
+		}
+
+		return r.getRegistrationTokenFromSecretValue(ctx, agentTokenName, scope.Cluster.Namespace)
+	}
+
+	scope.Logger.Info("RKE2 agent token of the existing cluster stored in Secret!")
+
+	return token, nil
+}
+
 // generateAndStoreToken generates a random token with 16 characters then stores it in a Secret in the API.
 func (r *RKE2ConfigReconciler) generateAndStoreToken(ctx context.Context, scope *Scope, name string) (string, error) {
 	token, err := bsutil.Random(defaultTokenLength)
@@ -850,6 +973,15 @@ func (r *RKE2ConfigReconciler) generateAndStoreToken(ctx context.Context, scope
 		return "", err
 	}
 
+	if err := r.storeToken(ctx, scope, name, token); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// storeToken stores the token in a Secret in the API, owned by the cluster.
+func (r *RKE2ConfigReconciler) storeToken(ctx context.Context, scope *Scope, name, token string) error {
 	scope.Logger = scope.Logger.WithValues("cluster-name", scope.Cluster.Name)
 
 	secret := &corev1.Secret{
@@ -875,11 +1007,7 @@ func (r *RKE2ConfigReconciler) generateAndStoreToken(ctx context.Context, scope
 		Type: clusterv1.ClusterSecretType,
 	}
 
-	if err := r.createSecretFromObject(ctx, *secret, scope.Logger, "token", *scope.Config); err != nil {
-		return "", err
-	}
-
-	return token, nil
+	return r.createSecretFromObject(ctx, *secret, scope.Logger, "token", *scope.Config)
 }
 
 // storeBootstrapData creates a new secret with the data passed in as input,
@@ -970,33 +1098,3 @@ func (r *RKE2ConfigReconciler) createOrUpdateSecretFromObject(
 
 	return
 }
-
-func generateFilesFromManifestConfig(
-	ctx context.Context,
-	cl client.Client,
-	manifestConfigMap corev1.ObjectReference,
-) (files []bootstrapv1.File, err error) {
-	if (manifestConfigMap == corev1.ObjectReference{}) {
-		return []bootstrapv1.File{}, nil
-	}
-
-	manifestSec := &corev1.ConfigMap{}
-
-	err = cl.Get(ctx, types.NamespacedName{
-		Namespace: manifestConfigMap.Namespace,
-		Name:      manifestConfigMap.Name,
-	}, manifestSec)
-
-	if err != nil {
-		return
-	}
-
-	for filename, content := range manifestSec.Data {
-		files = append(files, bootstrapv1.File{
-			Path:    DefaultManifestDirectory + "/" + filename,
-			Content: content,
-		})
-	}
-
-	return
-}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/locking"
+	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+const (
+	testServerToken = "server-token-value"
+	testAgentToken  = "agent-token-value"
+)
+
+var _ = Describe("Registration tokens", func() {
+	var (
+		cl      client.Client
+		r       *RKE2ConfigReconciler
+		cluster *clusterv1.Cluster
+	)
+
+	tokenSecret := func(name, token string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string][]byte{"value": []byte(token)},
+		}
+	}
+
+	newReconciler := func(objects ...client.Object) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		r = &RKE2ConfigReconciler{
+			Client:       cl,
+			Scheme:       scheme,
+			RKE2InitLock: locking.NewControlPlaneInitMutex(cl),
+		}
+	}
+
+	newScope := func(name string, controlPlaneOwner bool) *Scope {
+		return &Scope{
+			Logger: klog.Background(),
+			Config: &bootstrapv1.RKE2Config{
+				TypeMeta:   metav1.TypeMeta{APIVersion: bootstrapv1.GroupVersion.String(), Kind: "RKE2Config"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: "config-uid"},
+			},
+			Machine: &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: clusterv1.MachineSpec{
+					ClusterName: cluster.Name,
+					Version:     ptr.To("v1.29.3+rke2r1"),
+				},
+			},
+			Cluster:              cluster,
+			HasControlPlaneOwner: controlPlaneOwner,
+			ControlPlane: &controlplanev1.RKE2ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+				Spec:       controlplanev1.RKE2ControlPlaneSpec{Version: "v1.29.3+rke2r1"},
+				Status:     controlplanev1.RKE2ControlPlaneStatus{AvailableServerIPs: []string{"10.0.0.10"}},
+			},
+		}
+	}
+
+	bootstrapData := func(scope *Scope) string {
+		Expect(scope.Config.Status.Ready).To(BeTrue())
+
+		secret := &corev1.Secret{}
+		Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: *scope.Config.Status.DataSecretName}, secret)).To(Succeed())
+
+		return string(secret.Data["value"])
+	}
+
+	storedToken := func(name string) string {
+		secret := &corev1.Secret{}
+		Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, secret)).To(Succeed())
+
+		return string(secret.Data["value"])
+	}
+
+	BeforeEach(func() {
+		cluster = &clusterv1.Cluster{
+			TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "cluster-uid"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "10.0.0.1", Port: 6443},
+			},
+		}
+	})
+
+	It("should store the server token as agent token of a cluster initialized without agent token", func() {
+		newReconciler(cluster, tokenSecret(bsutil.TokenName(cluster.Name), testServerToken))
+
+		token, err := r.getOrCreateAgentToken(ctx, newScope("worker", false))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal(testServerToken))
+		Expect(storedToken(bsutil.AgentTokenName(cluster.Name))).To(Equal(testServerToken))
+		Expect(storedToken(bsutil.TokenName(cluster.Name))).To(Equal(testServerToken))
+	})
+
+	It("should keep the agent token of a cluster", func() {
+		newReconciler(cluster,
+			tokenSecret(bsutil.TokenName(cluster.Name), testServerToken),
+			tokenSecret(bsutil.AgentTokenName(cluster.Name), testAgentToken))
+
+		token, err := r.getOrCreateAgentToken(ctx, newScope("worker", false))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal(testAgentToken))
+	})
+
+	It("should only write the agent token in the bootstrap data of the workers", func() {
+		newReconciler(cluster,
+			tokenSecret(bsutil.TokenName(cluster.Name), testServerToken),
+			tokenSecret(bsutil.AgentTokenName(cluster.Name), testAgentToken))
+
+		scope := newScope("worker", false)
+		_, err := r.joinWorker(ctx, scope)
+		Expect(err).ToNot(HaveOccurred())
+
+		data := bootstrapData(scope)
+		Expect(data).To(ContainSubstring("token: " + testAgentToken))
+		Expect(data).ToNot(ContainSubstring(testServerToken))
+	})
+
+	It("should join the workers of a cluster initialized without agent token with the server token", func() {
+		newReconciler(cluster, tokenSecret(bsutil.TokenName(cluster.Name), testServerToken))
+
+		scope := newScope("worker", false)
+		_, err := r.joinWorker(ctx, scope)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bootstrapData(scope)).To(ContainSubstring("token: " + testServerToken))
+		Expect(storedToken(bsutil.AgentTokenName(cluster.Name))).To(Equal(testServerToken))
+	})
+
+	It("should write both tokens in the bootstrap data of the joining servers", func() {
+		newReconciler(cluster,
+			tokenSecret(bsutil.TokenName(cluster.Name), testServerToken),
+			tokenSecret(bsutil.AgentTokenName(cluster.Name), testAgentToken))
+
+		scope := newScope("server", true)
+		_, err := r.joinControlplane(ctx, scope)
+		Expect(err).ToNot(HaveOccurred())
+
+		data := bootstrapData(scope)
+		Expect(data).To(ContainSubstring("token: " + testServerToken))
+		Expect(data).To(ContainSubstring("agent-token: " + testAgentToken))
+	})
+
+	It("should generate both tokens for the first server", func() {
+		newReconciler(cluster)
+
+		scope := newScope("server", true)
+		_, err := r.handleClusterNotInitialized(ctx, scope)
+		Expect(err).ToNot(HaveOccurred())
+
+		serverToken := storedToken(bsutil.TokenName(cluster.Name))
+		agentToken := storedToken(bsutil.AgentTokenName(cluster.Name))
+		Expect(serverToken).ToNot(Equal(agentToken))
+
+		data := bootstrapData(scope)
+		Expect(data).To(ContainSubstring("token: " + serverToken))
+		Expect(data).To(ContainSubstring("agent-token: " + agentToken))
+	})
+})
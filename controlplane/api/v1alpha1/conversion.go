@@ -51,7 +51,29 @@ func (src *RKE2ControlPlane) ConvertTo(dstRaw conversion.Hub) error {
 		dst.Spec.AgentConfig.AirGappedChecksum = restored.Spec.AgentConfig.AirGappedChecksum
 	}
 
+	dst.Spec.AgentConfig.JoinServerFailover = restored.Spec.AgentConfig.JoinServerFailover
+
 	dst.Spec.MachineTemplate = restored.Spec.MachineTemplate
+	dst.Spec.RemediationStrategy = restored.Spec.RemediationStrategy
+	dst.Spec.RolloutBefore = restored.Spec.RolloutBefore
+	dst.Spec.RolloutAfter = restored.Spec.RolloutAfter
+	dst.Spec.MaintenanceWindow = restored.Spec.MaintenanceWindow
+	dst.Spec.EtcdMaintenance = restored.Spec.EtcdMaintenance
+	dst.Spec.ScaleDownPolicy = restored.Spec.ScaleDownPolicy
+	dst.Spec.Import = restored.Spec.Import
+	dst.Spec.KubeVIP = restored.Spec.KubeVIP
+	dst.Spec.RegistrationIPFamily = restored.Spec.RegistrationIPFamily
+	dst.Spec.TokenRotateAfter = restored.Spec.TokenRotateAfter
+	dst.Spec.AgentTokenRotateAfter = restored.Spec.AgentTokenRotateAfter
+	dst.Spec.SecretsEncryptionKeyRotateAfter = restored.Spec.SecretsEncryptionKeyRotateAfter
+	dst.Spec.ServerConfig.SecretsEncryption = restored.Spec.ServerConfig.SecretsEncryption
+	dst.Spec.ServerConfig.ChartValues = restored.Spec.ServerConfig.ChartValues
+	dst.Spec.ManifestSources = restored.Spec.ManifestSources
+
+	if dst.Spec.RolloutStrategy != nil && restored.Spec.RolloutStrategy != nil {
+		dst.Spec.RolloutStrategy.InPlace = restored.Spec.RolloutStrategy.InPlace
+		dst.Spec.RolloutStrategy.PausePolicy = restored.Spec.RolloutStrategy.PausePolicy
+	}
 	dst.Status = restored.Status
 
 	return nil
@@ -123,6 +145,8 @@ func (src *RKE2ControlPlaneTemplate) ConvertTo(dstRaw conversion.Hub) error {
 		dst.Spec.Template.Spec.AgentConfig.AirGappedChecksum = restored.Spec.Template.Spec.AgentConfig.AirGappedChecksum
 	}
 
+	dst.Spec.Template.Spec.AgentConfig.JoinServerFailover = restored.Spec.Template.Spec.AgentConfig.JoinServerFailover
+
 	dst.Spec.Template = restored.Spec.Template
 	dst.Status = restored.Status
 
@@ -179,6 +203,16 @@ func Convert_v1beta1_RKE2ControlPlaneSpec_To_v1alpha1_RKE2ControlPlaneSpec(in *c
 	return autoConvert_v1beta1_RKE2ControlPlaneSpec_To_v1alpha1_RKE2ControlPlaneSpec(in, out, s)
 }
 
+func Convert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(in *controlplanev1.RKE2ServerConfig, out *RKE2ServerConfig, s apiconversion.Scope) error {
+	// SecretsEncryption and ChartValues were added in v1beta1.
+	return autoConvert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(in, out, s)
+}
+
+func Convert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(in *controlplanev1.RolloutStrategy, out *RolloutStrategy, s apiconversion.Scope) error {
+	// InPlace and PausePolicy were added in v1beta1.
+	return autoConvert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(in, out, s)
+}
+
 func Convert_v1beta1_RKE2ControlPlaneStatus_To_v1alpha1_RKE2ControlPlaneStatus(in *controlplanev1.RKE2ControlPlaneStatus, out *RKE2ControlPlaneStatus, s apiconversion.Scope) error {
 	return autoConvert_v1beta1_RKE2ControlPlaneStatus_To_v1alpha1_RKE2ControlPlaneStatus(in, out, s)
 }
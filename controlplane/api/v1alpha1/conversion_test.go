@@ -17,12 +17,16 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
 	"testing"
 
+	fuzz "github.com/google/gofuzz"
 	. "github.com/onsi/gomega"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
 	"k8s.io/apimachinery/pkg/runtime"
+	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
 
 	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
 	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
@@ -38,13 +42,24 @@ func TestFuzzyConversion(t *testing.T) {
 		Scheme:      scheme,
 		Hub:         &controlplanev1.RKE2ControlPlane{},
 		Spoke:       &RKE2ControlPlane{},
-		FuzzerFuncs: []fuzzer.FuzzerFuncs{},
+		FuzzerFuncs: []fuzzer.FuzzerFuncs{fuzzFuncs},
 	}))
 
 	t.Run("for RKE2ControlPlaneTemplate", utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
 		Scheme:      scheme,
 		Hub:         &controlplanev1.RKE2ControlPlaneTemplate{},
 		Spoke:       &RKE2ControlPlaneTemplate{},
-		FuzzerFuncs: []fuzzer.FuzzerFuncs{},
+		FuzzerFuncs: []fuzzer.FuzzerFuncs{fuzzFuncs},
 	}))
 }
+
+func fuzzFuncs(_ runtimeserializer.CodecFactory) []interface{} {
+	return []interface{}{
+		jsonFuzzer,
+	}
+}
+
+// jsonFuzzer generates valid JSON values, as the chart values are restored from their JSON representation.
+func jsonFuzzer(obj *apiextensionsv1.JSON, c fuzz.Continue) {
+	obj.Raw = []byte(fmt.Sprintf(`{"value":%d}`, c.Int()))
+}
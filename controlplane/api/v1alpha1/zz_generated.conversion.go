@@ -131,11 +131,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.RKE2ServerConfig)(nil), (*RKE2ServerConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(a.(*v1beta1.RKE2ServerConfig), b.(*RKE2ServerConfig), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*RollingUpdate)(nil), (*v1beta1.RollingUpdate)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_RollingUpdate_To_v1beta1_RollingUpdate(a.(*RollingUpdate), b.(*v1beta1.RollingUpdate), scope)
 	}); err != nil {
@@ -151,11 +146,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.RolloutStrategy)(nil), (*RolloutStrategy)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(a.(*v1beta1.RolloutStrategy), b.(*RolloutStrategy), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddConversionFunc((*RKE2ControlPlaneStatus)(nil), (*v1beta1.RKE2ControlPlaneStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_RKE2ControlPlaneStatus_To_v1beta1_RKE2ControlPlaneStatus(a.(*RKE2ControlPlaneStatus), b.(*v1beta1.RKE2ControlPlaneStatus), scope)
 	}); err != nil {
@@ -191,6 +181,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta1.RKE2ServerConfig)(nil), (*RKE2ServerConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(a.(*v1beta1.RKE2ServerConfig), b.(*RKE2ServerConfig), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.RolloutStrategy)(nil), (*RolloutStrategy)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(a.(*v1beta1.RolloutStrategy), b.(*RolloutStrategy), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -409,7 +409,15 @@ func autoConvert_v1alpha1_RKE2ControlPlaneSpec_To_v1beta1_RKE2ControlPlaneSpec(i
 	out.NodeDrainTimeout = (*metav1.Duration)(unsafe.Pointer(in.NodeDrainTimeout))
 	out.RegistrationMethod = v1beta1.RegistrationMethod(in.RegistrationMethod)
 	out.RegistrationAddress = in.RegistrationAddress
-	out.RolloutStrategy = (*v1beta1.RolloutStrategy)(unsafe.Pointer(in.RolloutStrategy))
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(v1beta1.RolloutStrategy)
+		if err := Convert_v1alpha1_RolloutStrategy_To_v1beta1_RolloutStrategy(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RolloutStrategy = nil
+	}
 	return nil
 }
 
@@ -429,11 +437,32 @@ func autoConvert_v1beta1_RKE2ControlPlaneSpec_To_v1alpha1_RKE2ControlPlaneSpec(i
 		return err
 	}
 	out.ManifestsConfigMapReference = in.ManifestsConfigMapReference
+	// WARNING: in.ManifestSources requires manual conversion: does not exist in peer-type
 	out.InfrastructureRef = in.InfrastructureRef
 	out.NodeDrainTimeout = (*metav1.Duration)(unsafe.Pointer(in.NodeDrainTimeout))
 	out.RegistrationMethod = RegistrationMethod(in.RegistrationMethod)
 	out.RegistrationAddress = in.RegistrationAddress
-	out.RolloutStrategy = (*RolloutStrategy)(unsafe.Pointer(in.RolloutStrategy))
+	// WARNING: in.RegistrationIPFamily requires manual conversion: does not exist in peer-type
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(RolloutStrategy)
+		if err := Convert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RolloutStrategy = nil
+	}
+	// WARNING: in.RemediationStrategy requires manual conversion: does not exist in peer-type
+	// WARNING: in.RolloutBefore requires manual conversion: does not exist in peer-type
+	// WARNING: in.RolloutAfter requires manual conversion: does not exist in peer-type
+	// WARNING: in.MaintenanceWindow requires manual conversion: does not exist in peer-type
+	// WARNING: in.EtcdMaintenance requires manual conversion: does not exist in peer-type
+	// WARNING: in.ScaleDownPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.Import requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeVIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.TokenRotateAfter requires manual conversion: does not exist in peer-type
+	// WARNING: in.AgentTokenRotateAfter requires manual conversion: does not exist in peer-type
+	// WARNING: in.SecretsEncryptionKeyRotateAfter requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -467,6 +496,15 @@ func autoConvert_v1beta1_RKE2ControlPlaneStatus_To_v1alpha1_RKE2ControlPlaneStat
 	out.UpdatedReplicas = in.UpdatedReplicas
 	out.UnavailableReplicas = in.UnavailableReplicas
 	out.AvailableServerIPs = *(*[]string)(unsafe.Pointer(&in.AvailableServerIPs))
+	// WARNING: in.LastRemediation requires manual conversion: does not exist in peer-type
+	// WARNING: in.Rollout requires manual conversion: does not exist in peer-type
+	// WARNING: in.EtcdMembers requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastEtcdMaintenanceTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastTokenRotationTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastAgentTokenRotationTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.SecretsEncryptionKeyRotation requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastSecretsEncryptionKeyRotationTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.AppliedManifests requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -671,14 +709,11 @@ func autoConvert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(in *v1bet
 	}
 	out.CloudProviderName = in.CloudProviderName
 	out.CloudProviderConfigMap = (*v1.ObjectReference)(unsafe.Pointer(in.CloudProviderConfigMap))
+	// WARNING: in.SecretsEncryption requires manual conversion: does not exist in peer-type
+	// WARNING: in.ChartValues requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig is an autogenerated conversion function.
-func Convert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(in *v1beta1.RKE2ServerConfig, out *RKE2ServerConfig, s conversion.Scope) error {
-	return autoConvert_v1beta1_RKE2ServerConfig_To_v1alpha1_RKE2ServerConfig(in, out, s)
-}
-
 func autoConvert_v1alpha1_RollingUpdate_To_v1beta1_RollingUpdate(in *RollingUpdate, out *v1beta1.RollingUpdate, s conversion.Scope) error {
 	out.MaxSurge = (*intstr.IntOrString)(unsafe.Pointer(in.MaxSurge))
 	return nil
@@ -713,10 +748,7 @@ func Convert_v1alpha1_RolloutStrategy_To_v1beta1_RolloutStrategy(in *RolloutStra
 func autoConvert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(in *v1beta1.RolloutStrategy, out *RolloutStrategy, s conversion.Scope) error {
 	out.Type = RolloutStrategyType(in.Type)
 	out.RollingUpdate = (*RollingUpdate)(unsafe.Pointer(in.RollingUpdate))
+	// WARNING: in.InPlace requires manual conversion: does not exist in peer-type
+	// WARNING: in.PausePolicy requires manual conversion: does not exist in peer-type
 	return nil
 }
-
-// Convert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy is an autogenerated conversion function.
-func Convert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(in *v1beta1.RolloutStrategy, out *RolloutStrategy, s conversion.Scope) error {
-	return autoConvert_v1beta1_RolloutStrategy_To_v1alpha1_RolloutStrategy(in, out, s)
-}
@@ -77,6 +77,14 @@ const (
 	// RollingUpdateInProgressReason (Severity=Warning) documents a RKE2ControlPlane object executing a
 	// rolling upgrade for aligning the machines spec to the desired state.
 	RollingUpdateInProgressReason = "RollingUpdateInProgress"
+
+	// RolloutDeferredReason (Severity=Info) documents a RKE2ControlPlane object waiting for the next
+	// maintenance window to roll out machines with an outdated spec.
+	RolloutDeferredReason = "RolloutDeferred"
+
+	// RolloutPausedReason (Severity=Info) documents a RKE2ControlPlane object waiting for an approval
+	// to continue rolling out machines with an outdated spec.
+	RolloutPausedReason = "RolloutPaused"
 )
 
 const (
@@ -110,3 +118,87 @@ const (
 	// CertificatesGenerationFailedReason documents a failure in generating the certificates.
 	CertificatesGenerationFailedReason string = "CertificateGenerationFailed"
 )
+
+const (
+	// EtcdSnapshotCompletedCondition documents the completion of an on-demand etcd snapshot.
+	EtcdSnapshotCompletedCondition clusterv1.ConditionType = "SnapshotCompleted"
+
+	// WaitingForControlPlaneReason (Severity=Info) documents an etcd snapshot waiting for the control plane
+	// of the cluster to be initialized.
+	WaitingForControlPlaneReason = "WaitingForControlPlane"
+
+	// EtcdSnapshotInProgressReason (Severity=Info) documents an etcd snapshot being taken.
+	EtcdSnapshotInProgressReason = "SnapshotInProgress"
+
+	// EtcdSnapshotFailedReason (Severity=Error) documents an etcd snapshot that failed.
+	EtcdSnapshotFailedReason = "SnapshotFailed"
+)
+
+const (
+	// EtcdSnapshotRestoredCondition documents the progress of the restore of an etcd snapshot.
+	EtcdSnapshotRestoredCondition clusterv1.ConditionType = "SnapshotRestored"
+
+	// EtcdRestoreScalingDownReason (Severity=Info) documents the control plane being scaled down to a single machine
+	// before restoring an etcd snapshot.
+	EtcdRestoreScalingDownReason = "ScalingDownControlPlane"
+
+	// EtcdRestoreInProgressReason (Severity=Info) documents the cluster reset from an etcd snapshot being run.
+	EtcdRestoreInProgressReason = "RestoreInProgress"
+
+	// EtcdRestoreRejoiningMachinesReason (Severity=Info) documents new control plane machines joining the restored etcd cluster.
+	EtcdRestoreRejoiningMachinesReason = "RejoiningMachines"
+
+	// EtcdRestoreFailedReason (Severity=Error) documents an etcd snapshot restore that failed.
+	EtcdRestoreFailedReason = "RestoreFailed"
+)
+
+const (
+	// EtcdMaintenanceCondition documents the status of the periodic maintenance of the etcd cluster.
+	EtcdMaintenanceCondition clusterv1.ConditionType = "EtcdMaintenance"
+
+	// EtcdDefragmentingReason (Severity=Info) documents etcd members being defragmented one at a time.
+	EtcdDefragmentingReason = "Defragmenting"
+
+	// EtcdNoSpaceAlarmReason (Severity=Warning) documents an etcd member that raised a NOSPACE alarm,
+	// which is disarmed once space has been reclaimed by compaction and defragmentation.
+	EtcdNoSpaceAlarmReason = "NoSpaceAlarm"
+
+	// EtcdCorruptAlarmReason (Severity=Error) documents an etcd member that raised a CORRUPT alarm;
+	// the machine hosting the member must be remediated, as the alarm cannot be handled automatically.
+	EtcdCorruptAlarmReason = "CorruptAlarm"
+
+	// EtcdMaintenanceFailedReason (Severity=Warning) documents a failure while maintaining the etcd cluster.
+	EtcdMaintenanceFailedReason = "EtcdMaintenanceFailed"
+)
+
+const (
+	// TokenRotationCondition documents the status of the rotation of the server token requested by TokenRotateAfter.
+	TokenRotationCondition clusterv1.ConditionType = "TokenRotation"
+
+	// TokenRotationInProgressReason (Severity=Info) documents the server token being rotated on a server.
+	TokenRotationInProgressReason = "TokenRotationInProgress"
+
+	// TokenRotationFailedReason (Severity=Warning) documents a failure while rotating the server token.
+	TokenRotationFailedReason = "TokenRotationFailed"
+)
+
+const (
+	// SecretsEncryptionKeyRotationCondition documents the status of the rotation of the secrets encryption keys
+	// requested by SecretsEncryptionKeyRotateAfter.
+	SecretsEncryptionKeyRotationCondition clusterv1.ConditionType = "SecretsEncryptionKeyRotation"
+
+	// SecretsEncryptionKeyRotationInProgressReason (Severity=Info) documents a stage of the rotation being run on a server.
+	SecretsEncryptionKeyRotationInProgressReason = "SecretsEncryptionKeyRotationInProgress"
+
+	// SecretsEncryptionKeyRotationFailedReason (Severity=Warning) documents a failure while rotating the secrets encryption keys.
+	SecretsEncryptionKeyRotationFailedReason = "SecretsEncryptionKeyRotationFailed"
+)
+
+const (
+	// ManifestsSyncedCondition documents the status of the synchronization of the manifests of ManifestsConfigMapReference
+	// and ManifestSources into the workload cluster.
+	ManifestsSyncedCondition clusterv1.ConditionType = "ManifestsSynced"
+
+	// ManifestsSyncFailedReason (Severity=Warning) documents a failure while applying or pruning the manifests.
+	ManifestsSyncFailedReason = "ManifestsSyncFailed"
+)
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 SUSE LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+)
+
+const daysPerWeek = 7
+
+// Location returns the time zone in which the maintenance windows are defined.
+func (m *MaintenanceWindow) Location() (*time.Location, error) {
+	if m.TimeZone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(m.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", m.TimeZone, err)
+	}
+
+	return loc, nil
+}
+
+// IsOpen returns true if the given time is within one of the maintenance windows.
+func (m *MaintenanceWindow) IsOpen(t time.Time) (bool, error) {
+	loc, err := m.Location()
+	if err != nil {
+		return false, err
+	}
+
+	t = t.In(loc)
+
+	for _, window := range m.Windows {
+		// A window spanning midnight may have opened the day before.
+		for _, dayOffset := range []int{0, -1} {
+			start, end, ok, err := window.on(t.AddDate(0, 0, dayOffset))
+			if err != nil {
+				return false, err
+			}
+
+			if ok && !t.Before(start) && t.Before(end) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// NextOpening returns the time at which the next maintenance window after the given time opens.
+func (m *MaintenanceWindow) NextOpening(t time.Time) (time.Time, error) {
+	loc, err := m.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t = t.In(loc)
+
+	var next time.Time
+
+	for _, window := range m.Windows {
+		for dayOffset := 0; dayOffset <= daysPerWeek; dayOffset++ {
+			start, _, ok, err := window.on(t.AddDate(0, 0, dayOffset))
+			if err != nil {
+				return time.Time{}, err
+			}
+
+			if ok && start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("maintenance window has no opening")
+	}
+
+	return next, nil
+}
+
+// on returns the start and end of the window opening on the day of the given time,
+// and false if the window does not apply to that day.
+func (w TimeWindow) on(day time.Time) (time.Time, time.Time, bool, error) {
+	if !w.appliesTo(day.Weekday()) {
+		return time.Time{}, time.Time{}, false, nil
+	}
+
+	startHour, startMinute, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	endHour, endMinute, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), startHour, startMinute, 0, 0, day.Location())
+	end := time.Date(day.Year(), day.Month(), day.Day(), endHour, endMinute, 0, 0, day.Location())
+
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return start, end, true, nil
+}
+
+func (w TimeWindow) appliesTo(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, day := range w.Days {
+		if string(day) == weekday.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTimeOfDay parses a time in the HH:MM format.
+func parseTimeOfDay(value string) (int, int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM: %w", value, err)
+	}
+
+	return parsed.Hour(), parsed.Minute(), nil
+}
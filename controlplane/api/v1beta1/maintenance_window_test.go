@@ -0,0 +1,131 @@
+/*
+Copyright 2024 SUSE LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	// 2024-06-03 is a Monday.
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2024, time.June, 3, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		window      MaintenanceWindow
+		now         time.Time
+		wantOpen    bool
+		wantOpening time.Time
+	}{
+		{
+			name:     "within a daily window",
+			window:   MaintenanceWindow{Windows: []TimeWindow{{Start: "02:00", End: "04:00"}}},
+			now:      monday(3, 0),
+			wantOpen: true,
+		},
+		{
+			name:        "before a daily window",
+			window:      MaintenanceWindow{Windows: []TimeWindow{{Start: "02:00", End: "04:00"}}},
+			now:         monday(1, 0),
+			wantOpening: monday(2, 0),
+		},
+		{
+			name:        "at the end of a daily window",
+			window:      MaintenanceWindow{Windows: []TimeWindow{{Start: "02:00", End: "04:00"}}},
+			now:         monday(4, 0),
+			wantOpening: monday(2, 0).AddDate(0, 0, 1),
+		},
+		{
+			name:     "within a window spanning midnight, opened the day before",
+			window:   MaintenanceWindow{Windows: []TimeWindow{{Days: []Weekday{"Sunday"}, Start: "22:00", End: "03:00"}}},
+			now:      monday(1, 0),
+			wantOpen: true,
+		},
+		{
+			name:        "window restricted to other days",
+			window:      MaintenanceWindow{Windows: []TimeWindow{{Days: []Weekday{"Saturday", "Sunday"}, Start: "00:00", End: "23:59"}}},
+			now:         monday(12, 0),
+			wantOpening: monday(0, 0).AddDate(0, 0, 5),
+		},
+		{
+			name: "window in another time zone",
+			window: MaintenanceWindow{
+				TimeZone: "Europe/Berlin",
+				Windows:  []TimeWindow{{Start: "02:00", End: "04:00"}},
+			},
+			now:      monday(1, 0),
+			wantOpen: true,
+		},
+		{
+			name: "earliest opening of several windows",
+			window: MaintenanceWindow{Windows: []TimeWindow{
+				{Days: []Weekday{"Friday"}, Start: "20:00", End: "23:00"},
+				{Days: []Weekday{"Wednesday"}, Start: "08:00", End: "09:00"},
+			}},
+			now:         monday(12, 0),
+			wantOpening: monday(8, 0).AddDate(0, 0, 2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			open, err := tt.window.IsOpen(tt.now)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(open).To(Equal(tt.wantOpen))
+
+			if !tt.wantOpen {
+				opening, err := tt.window.NextOpening(tt.now)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(opening).To(BeTemporally("==", tt.wantOpening))
+			}
+		})
+	}
+}
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := &RKE2ControlPlaneSpec{
+		MaintenanceWindow: &MaintenanceWindow{
+			TimeZone: "Mars/Olympus_Mons",
+			Windows:  []TimeWindow{{Start: "02:00", End: "02:00"}},
+		},
+	}
+	g.Expect(validateMaintenanceWindow(spec, field.NewPath("spec"))).To(HaveLen(2))
+
+	template := &RKE2ControlPlaneTemplate{Spec: RKE2ControlPlaneTemplateSpec{
+		Template: RKE2ControlPlaneTemplateResource{Spec: *spec},
+	}}
+	_, err := template.ValidateCreate()
+	g.Expect(err).To(HaveOccurred())
+	_, err = template.ValidateUpdate(template.DeepCopy())
+	g.Expect(err).To(HaveOccurred())
+
+	spec.MaintenanceWindow = &MaintenanceWindow{
+		TimeZone: "America/New_York",
+		Windows:  []TimeWindow{{Start: "22:00", End: "02:00"}},
+	}
+	g.Expect(validateMaintenanceWindow(spec, field.NewPath("spec"))).To(BeEmpty())
+}
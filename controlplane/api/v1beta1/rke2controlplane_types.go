@@ -17,7 +17,11 @@ limitations under the License.
 package v1beta1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -36,6 +40,45 @@ const (
 	// RKE2ServerConfigurationAnnotation is a machine annotation that stores the json-marshalled string of RKE2Config
 	// This annotation is used to detect any changes in RKE2Config and trigger machine rollout.
 	RKE2ServerConfigurationAnnotation = "controlplane.cluster.x-k8s.io/rke2-server-configuration"
+
+	// RemediationInProgressAnnotation is used to keep track that a RCP remediation is in progress, and more
+	// specifically it tracks that the system is in between having deleted an unhealthy machine and recreating its replacement.
+	// NOTE: if something external to CAPI removes this annotation the system cannot detect the above situation; this can lead to
+	// failures in updating remediation retry or remediation count (both counters restart from zero).
+	RemediationInProgressAnnotation = "controlplane.cluster.x-k8s.io/remediation-in-progress"
+
+	// RemediationForAnnotation is used to link a new machine to the unhealthy machine it is replacing;
+	// please note that in case of retry, when also the remediating machine fails, the system keeps track of
+	// the first machine of the sequence only.
+	// NOTE: if something external to CAPI removes this annotation the system this can lead to
+	// failures in updating remediation retry (the counter restarts from zero).
+	RemediationForAnnotation = "controlplane.cluster.x-k8s.io/remediation-for"
+
+	// InPlaceUpgradeAnnotation is a machine annotation that stores the RKE2 version a machine is being upgraded to
+	// when using the InPlace rollout strategy. It is removed once the upgrade of the machine is completed.
+	InPlaceUpgradeAnnotation = "controlplane.cluster.x-k8s.io/in-place-upgrade"
+
+	// RolloutApprovedAnnotation is a RKE2ControlPlane annotation used to approve a rollout paused by the
+	// rollout pause policy. It is removed by the controller once the rollout resumes.
+	RolloutApprovedAnnotation = "controlplane.cluster.x-k8s.io/rollout-approved"
+
+	// ScaleDownProtectedAnnotation is a machine annotation that prevents a control plane machine from being selected
+	// for scale down or rollout, unless the machine is not ready or marked as unhealthy by a MachineHealthCheck.
+	ScaleDownProtectedAnnotation = "controlplane.cluster.x-k8s.io/scale-down-protected"
+
+	// PreTerminateHookCleanupAnnotation is the pre-terminate hook set on control plane machines, so the etcd member of
+	// a deleted machine is removed only once the node has been drained. It is removed by the controller afterwards.
+	PreTerminateHookCleanupAnnotation = clusterv1.PreTerminateDeleteHookAnnotationPrefix + "/rke2-cleanup"
+
+	// DefaultInPlaceUpgradeImage is the image used to replace the RKE2 binaries on a node during an in-place upgrade.
+	DefaultInPlaceUpgradeImage = "rancher/rke2-upgrade"
+
+	// DefaultKubeVIPImage is the image of kube-vip used to manage the virtual IP of the API server.
+	DefaultKubeVIPImage = "ghcr.io/kube-vip/kube-vip:v0.8.0"
+
+	// DefaultMinHealthyPeriod defines the default minimum period before we consider a remediation on a
+	// machine unrelated from the previous remediation.
+	DefaultMinHealthyPeriod = 1 * time.Hour
 )
 
 // RKE2ControlPlaneSpec defines the desired state of RKE2ControlPlane.
@@ -61,11 +104,20 @@ type RKE2ControlPlaneSpec struct {
 	//+optional
 	ServerConfig RKE2ServerConfig `json:"serverConfig,omitempty"`
 
-	// ManifestsConfigMapReference references a ConfigMap which contains Kubernetes manifests to be deployed automatically on the cluster
-	// Each data entry in the ConfigMap will be will be copied to a folder on the control plane nodes that RKE2 scans and uses to deploy manifests.
+	// ManifestsConfigMapReference references a ConfigMap which contains Kubernetes manifests to be deployed automatically on the cluster.
+	// Once the control plane is initialized, the manifests of each data entry are applied to the workload cluster with server-side apply
+	// whenever the ConfigMap changes, and the objects removed from the ConfigMap are deleted. The manifests are not written on the nodes.
 	//+optional
 	ManifestsConfigMapReference corev1.ObjectReference `json:"manifestsConfigMapReference,omitempty"`
 
+	// ManifestSources are ConfigMaps or Secrets, in the namespace of the RKE2ControlPlane, which contain Kubernetes manifests
+	// to be deployed automatically on the cluster, in addition to the ManifestsConfigMapReference. They are deployed like the
+	// manifests of the ManifestsConfigMapReference.
+	// The names of the manifest files must be unique across all the sources.
+	//+optional
+	//+listType=atomic
+	ManifestSources []ManifestSource `json:"manifestSources,omitempty"`
+
 	// InfrastructureRef is a required reference to a custom resource
 	// offered by an infrastructure provider.
 	InfrastructureRef corev1.ObjectReference `json:"infrastructureRef"`
@@ -86,8 +138,88 @@ type RKE2ControlPlaneSpec struct {
 	// +optional
 	RegistrationAddress string `json:"registrationAddress,omitempty"`
 
+	// RegistrationIPFamily is the IP family of the machine addresses to prefer for registering nodes, in dual-stack
+	// clusters, with the internal-first, internal-only-ips and external-only-ips registration methods. The address
+	// of the other family is used for the machines without an address of the preferred family.
+	// By default, the first address of each machine is used.
+	// +kubebuilder:validation:Enum=IPv4;IPv6
+	// +optional
+	RegistrationIPFamily IPFamily `json:"registrationIPFamily,omitempty"`
+
 	// The RolloutStrategy to use to replace control plane machines with new ones.
 	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy"`
+
+	// The RemediationStrategy that controls how control plane machine remediation happens.
+	// +optional
+	RemediationStrategy *RemediationStrategy `json:"remediationStrategy,omitempty"`
+
+	// RolloutBefore is a field to indicate a rollout should be performed
+	// if the specified criteria is met.
+	// +optional
+	RolloutBefore *RolloutBefore `json:"rolloutBefore,omitempty"`
+
+	// RolloutAfter is a field to indicate a rollout should be performed
+	// after the specified time even if no changes have been made to the
+	// RKE2ControlPlane.
+	// +optional
+	RolloutAfter *metav1.Time `json:"rolloutAfter,omitempty"`
+
+	// MaintenanceWindow restricts the replacement of control plane machines to the given time windows.
+	// Outside a window, rollouts and scale downs are deferred, while scale ups are still performed.
+	// If not set, machines can be replaced at any time.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// EtcdMaintenance enables the periodic maintenance of the etcd cluster: the database size and alarms of
+	// each member are checked, fragmented members are defragmented one at a time, and NOSPACE alarms are
+	// disarmed once space has been reclaimed.
+	// If not set, the etcd cluster is not maintained.
+	// +optional
+	EtcdMaintenance *EtcdMaintenance `json:"etcdMaintenance,omitempty"`
+
+	// ScaleDownPolicy is the policy used to select the machine to remove among the candidates of the failure domain
+	// with the most machines, when scaling down or rolling out the control plane.
+	// Oldest selects the oldest machine, Newest the newest machine, and AvoidEtcdLeader the oldest machine
+	// which is not the etcd leader.
+	// If not set, the oldest machine is selected.
+	// +kubebuilder:validation:Enum=Oldest;Newest;AvoidEtcdLeader
+	// +optional
+	ScaleDownPolicy ScaleDownPolicy `json:"scaleDownPolicy,omitempty"`
+
+	// Import configures the import of an existing RKE2 cluster, not created by Cluster API. The control plane
+	// machines join the existing servers instead of initializing a new cluster, so they can gradually replace them.
+	// +optional
+	Import *RKE2ClusterImport `json:"import,omitempty"`
+
+	// KubeVIP configures a virtual IP for the API server, managed by kube-vip on the control plane machines.
+	// The kube-vip manifests are deployed by every server, the virtual IP is added to the TLS SANs of the servers
+	// and, when no registration method is set, it is used as the registration address.
+	// +optional
+	KubeVIP *KubeVIP `json:"kubeVIP,omitempty"`
+
+	// TokenRotateAfter is a field to indicate a rotation of the server token of the cluster should be performed
+	// after the specified time, e.g. after the token leaked. The token is rotated once for each new value of the field
+	// by running `rke2 token rotate` on a server, and the bootstrap data of the machines not provisioned yet is then generated again.
+	// Only the server token is rotated, the agent token being rotated by AgentTokenRotateAfter: the agent token is only
+	// updated with the server token when they are the same, as for clusters provisioned before the agents had their own token.
+	// +optional
+	TokenRotateAfter *metav1.Time `json:"tokenRotateAfter,omitempty"`
+
+	// AgentTokenRotateAfter is a field to indicate a rotation of the agent token of the cluster should be performed
+	// after the specified time. The token is rotated once for each new value of the field: a new agent token is stored,
+	// the bootstrap data of the machines not provisioned yet is generated again, and the control plane machines created
+	// before the rotation are rolled out, so that the servers only accept the new agent token.
+	// The workers already provisioned keep the previous agent token, so their MachineDeployments should be rolled out as well.
+	// The rotation is not supported for imported clusters, as the configuration of their existing servers can't be updated.
+	// +optional
+	AgentTokenRotateAfter *metav1.Time `json:"agentTokenRotateAfter,omitempty"`
+
+	// SecretsEncryptionKeyRotateAfter is a field to indicate a rotation of the keys encrypting the Kubernetes Secrets should be
+	// performed after the specified time. The keys are rotated once for each new value of the field, by running the
+	// `rke2 secrets-encrypt` prepare, rotate and reencrypt stages on the oldest server, each stage being followed by
+	// a restart of all the servers in order.
+	// +optional
+	SecretsEncryptionKeyRotateAfter *metav1.Time `json:"secretsEncryptionKeyRotateAfter,omitempty"`
 }
 
 // RKE2ControlPlaneMachineTemplate defines the template for Machines
@@ -132,6 +264,9 @@ type RKE2ServerConfig struct {
 	ServiceNodePortRange string `json:"serviceNodePortRange,omitempty"`
 
 	// ClusterDNS is the cluster IP for CoreDNS service. Should be in your service-cidr range (default: 10.43.0.10).
+	// Dual-stack clusters can set one IP address per IP family, comma-separated, in the same order as the service CIDRs.
+	// The webhook only checks that there is one IP address per IP family at most: the service CIDRs are defined on the Cluster,
+	// so the IP families are checked against them when the bootstrap data of the servers is generated.
 	//+optional
 	ClusterDNS string `json:"clusterDNS,omitempty"`
 
@@ -185,6 +320,61 @@ type RKE2ServerConfig struct {
 	// The config map must contain a key named cloud-config.
 	//+optional
 	CloudProviderConfigMap *corev1.ObjectReference `json:"cloudProviderConfigMap,omitempty"`
+
+	// SecretsEncryption configures the encryption at rest of the Kubernetes Secrets.
+	//+optional
+	SecretsEncryption *SecretsEncryption `json:"secretsEncryption,omitempty"`
+
+	// ChartValues are the values of the charts packaged with RKE2, keyed by chart name, one of rke2-canal, rke2-calico,
+	// rke2-cilium, rke2-flannel, rke2-multus, rke2-coredns, rke2-ingress-nginx, rke2-metrics-server, rke2-snapshot-controller,
+	// rke2-snapshot-validation-webhook. They are rendered into HelmChartConfig manifests on the first server and, once the
+	// control plane is initialized, applied to the workload cluster without rolling out the control plane machines.
+	// The HelmChartConfigs not generated from the chart values are not updated.
+	//+optional
+	ChartValues map[string]apiextensionsv1.JSON `json:"chartValues,omitempty"`
+}
+
+// PackagedCharts are the names of the charts packaged with RKE2 which can be configured with ChartValues.
+var PackagedCharts = []string{
+	"rke2-canal",
+	"rke2-calico",
+	"rke2-cilium",
+	"rke2-flannel",
+	"rke2-multus",
+	"rke2-coredns",
+	"rke2-ingress-nginx",
+	"rke2-metrics-server",
+	"rke2-snapshot-controller",
+	"rke2-snapshot-validation-webhook",
+}
+
+// SecretsEncryptionProvider is the provider used by RKE2 to encrypt the Kubernetes Secrets.
+type SecretsEncryptionProvider string
+
+const (
+	// AESCBCSecretsEncryptionProvider encrypts the Kubernetes Secrets with AES-CBC.
+	AESCBCSecretsEncryptionProvider SecretsEncryptionProvider = "aescbc"
+
+	// SecretBoxSecretsEncryptionProvider encrypts the Kubernetes Secrets with XSalsa20 and Poly1305.
+	SecretBoxSecretsEncryptionProvider SecretsEncryptionProvider = "secretbox"
+)
+
+// SecretsEncryption configures the encryption at rest of the Kubernetes Secrets.
+type SecretsEncryption struct {
+	// Enabled enables the encryption of the Kubernetes Secrets by RKE2 (default: true).
+	//+optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Provider is the provider used by RKE2 to encrypt the Kubernetes Secrets, one of aescbc, secretbox (default: aescbc).
+	// +kubebuilder:validation:Enum=aescbc;secretbox
+	//+optional
+	Provider SecretsEncryptionProvider `json:"provider,omitempty"`
+
+	// EncryptionConfigSecret is a reference to a Secret containing a custom EncryptionConfiguration for the API server.
+	// The secret must contain a key named encryption-config.yaml. The keys of a custom configuration are managed by the user:
+	// the encryption by RKE2 is disabled and the keys cannot be rotated with SecretsEncryptionKeyRotateAfter.
+	//+optional
+	EncryptionConfigSecret *corev1.ObjectReference `json:"encryptionConfigSecret,omitempty"`
 }
 
 // RKE2ControlPlaneStatus defines the observed state of RKE2ControlPlane.
@@ -235,6 +425,176 @@ type RKE2ControlPlaneStatus struct {
 	// AvailableServerIPs is a list of the Control Plane IP adds that can be used to register further nodes.
 	// +optional
 	AvailableServerIPs []string `json:"availableServerIPs,omitempty"`
+
+	// LastRemediation stores info about last remediation performed.
+	// +optional
+	LastRemediation *LastRemediationStatus `json:"lastRemediation,omitempty"`
+
+	// Rollout reports the progress of the rollout in progress, if any.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// EtcdMembers reports the database size and alarms of the etcd members, when EtcdMaintenance is enabled.
+	// +optional
+	EtcdMembers []EtcdMemberStatus `json:"etcdMembers,omitempty"`
+
+	// LastEtcdMaintenanceTime is the last time the etcd members were checked by the etcd maintenance.
+	// +optional
+	LastEtcdMaintenanceTime *metav1.Time `json:"lastEtcdMaintenanceTime,omitempty"`
+
+	// LastTokenRotationTime is the last time the server token of the cluster was rotated.
+	// +optional
+	LastTokenRotationTime *metav1.Time `json:"lastTokenRotationTime,omitempty"`
+
+	// LastAgentTokenRotationTime is the last time the agent token of the cluster was rotated. The control plane machines
+	// created before this time are rolled out.
+	// +optional
+	LastAgentTokenRotationTime *metav1.Time `json:"lastAgentTokenRotationTime,omitempty"`
+
+	// SecretsEncryptionKeyRotation reports the progress of the rotation of the secrets encryption keys, while it is running.
+	// +optional
+	SecretsEncryptionKeyRotation *SecretsEncryptionKeyRotationStatus `json:"secretsEncryptionKeyRotation,omitempty"`
+
+	// LastSecretsEncryptionKeyRotationTime is the last time the keys encrypting the Kubernetes Secrets were rotated.
+	// +optional
+	LastSecretsEncryptionKeyRotationTime *metav1.Time `json:"lastSecretsEncryptionKeyRotationTime,omitempty"`
+
+	// AppliedManifests are the objects of the manifests ConfigMap applied to the workload cluster, which are deleted
+	// once they are removed from the ConfigMap.
+	// +optional
+	AppliedManifests []ManifestObjectReference `json:"appliedManifests,omitempty"`
+}
+
+// ManifestSourceKind is the kind of a manifest source.
+type ManifestSourceKind string
+
+const (
+	// ConfigMapManifestSource is a manifest source backed by a ConfigMap.
+	ConfigMapManifestSource ManifestSourceKind = "ConfigMap"
+
+	// SecretManifestSource is a manifest source backed by a Secret, for the manifests holding credentials.
+	SecretManifestSource ManifestSourceKind = "Secret"
+)
+
+// ManifestSource references a ConfigMap or a Secret which contains Kubernetes manifests, one manifest file per data entry.
+type ManifestSource struct {
+	// Kind is the kind of the source, ConfigMap or Secret.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind ManifestSourceKind `json:"kind"`
+
+	// Name is the name of the ConfigMap or Secret, in the namespace of the RKE2ControlPlane.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Keys are the keys of the data entries to deploy. All the data entries are deployed when empty.
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+
+	// FilenamePrefix is prepended to the keys of the data entries to name the manifest files.
+	// +optional
+	FilenamePrefix string `json:"filenamePrefix,omitempty"`
+}
+
+// ManifestObjectReference identifies an object of the manifests applied to the workload cluster.
+type ManifestObjectReference struct {
+	// APIVersion is the API version of the object.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the kind of the object.
+	Kind string `json:"kind"`
+
+	// Namespace is the namespace of the object, empty for cluster-scoped objects.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the object.
+	Name string `json:"name"`
+}
+
+// EtcdMemberStatus reports the database size and alarms of an etcd member.
+type EtcdMemberStatus struct {
+	// NodeName is the name of the node the etcd member runs on.
+	NodeName string `json:"nodeName"`
+
+	// Leader indicates the member is the etcd leader.
+	// +optional
+	Leader bool `json:"leader,omitempty"`
+
+	// DBSize is the size of the member database.
+	DBSize resource.Quantity `json:"dbSize"`
+
+	// DBSizeInUse is the size of the member database actually in use, the rest can be reclaimed by defragmentation.
+	DBSizeInUse resource.Quantity `json:"dbSizeInUse"`
+
+	// Alarms are the alarms raised by the member, e.g. NOSPACE or CORRUPT.
+	// +optional
+	Alarms []string `json:"alarms,omitempty"`
+
+	// LastDefragmentTime is the last time the member was defragmented.
+	// +optional
+	LastDefragmentTime *metav1.Time `json:"lastDefragmentTime,omitempty"`
+}
+
+// SecretsEncryptionKeyRotationStage is a stage of the rotation of the secrets encryption keys.
+type SecretsEncryptionKeyRotationStage string
+
+const (
+	// SecretsEncryptionKeyRotationPrepareStage adds a new encryption key, not used to encrypt yet.
+	SecretsEncryptionKeyRotationPrepareStage SecretsEncryptionKeyRotationStage = "Prepare"
+
+	// SecretsEncryptionKeyRotationRotateStage makes the new encryption key the one used to encrypt.
+	SecretsEncryptionKeyRotationRotateStage SecretsEncryptionKeyRotationStage = "Rotate"
+
+	// SecretsEncryptionKeyRotationReencryptStage encrypts all the Secrets with the new key and removes the previous key.
+	SecretsEncryptionKeyRotationReencryptStage SecretsEncryptionKeyRotationStage = "Reencrypt"
+)
+
+// SecretsEncryptionKeyRotationStatus reports the progress of the rotation of the secrets encryption keys.
+type SecretsEncryptionKeyRotationStatus struct {
+	// Stage is the stage of the rotation currently run.
+	Stage SecretsEncryptionKeyRotationStage `json:"stage"`
+
+	// RestartedNodes are the names of the server nodes already restarted in the current stage.
+	// +optional
+	RestartedNodes []string `json:"restartedNodes,omitempty"`
+}
+
+// RolloutStatus reports the progress of a rollout of the control plane machines.
+type RolloutStatus struct {
+	// UpdatedMachines is the number of machines already replaced or upgraded.
+	UpdatedMachines int32 `json:"updatedMachines"`
+
+	// PendingMachines are the names of the machines still to be replaced or upgraded.
+	// +optional
+	PendingMachines []string `json:"pendingMachines,omitempty"`
+
+	// Paused indicates the rollout is waiting for an approval with the RolloutApprovedAnnotation.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// ApprovedMachines is the number of updated machines the rollout was last approved at.
+	// +optional
+	ApprovedMachines int32 `json:"approvedMachines,omitempty"`
+
+	// StartTime is the time at which the control plane machines started to be replaced or upgraded.
+	// It is not set while the rollout is deferred until the next maintenance window.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+}
+
+// LastRemediationStatus stores info about last remediation performed.
+// NOTE: if for any reason information about last remediation are lost, RetryCount is going to restart from 0 and thus
+// more remediations than expected might happen.
+type LastRemediationStatus struct {
+	// Machine is the machine name of the latest machine being remediated.
+	Machine string `json:"machine"`
+
+	// Timestamp is when last remediation happened. It is represented in RFC3339 form and is in UTC.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// RetryCount used to keep track of remediation retry for the last remediated machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails.
+	RetryCount int32 `json:"retryCount"`
 }
 
 // +kubebuilder:object:root=true
@@ -388,14 +748,44 @@ const (
 // RolloutStrategy describes how to replace existing machines
 // with new ones.
 type RolloutStrategy struct {
-	// Type of rollout. Currently the only supported strategy is "RollingUpdate".
+	// Type of rollout. Supported strategies are "RollingUpdate" and "InPlace".
 	// Default is RollingUpdate.
+	// +kubebuilder:validation:Enum=RollingUpdate;InPlace
 	// +optional
 	Type RolloutStrategyType `json:"type,omitempty"`
 
 	// Rolling update config params. Present only if RolloutStrategyType = RollingUpdate.
 	// +optional
 	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
+
+	// In-place upgrade config params. Present only if RolloutStrategyType = InPlace.
+	// +optional
+	InPlace *InPlaceUpgrade `json:"inPlace,omitempty"`
+
+	// PausePolicy pauses the rollout after a number of machines have been replaced or upgraded,
+	// until it is approved with the RolloutApprovedAnnotation.
+	// +optional
+	PausePolicy *RolloutPausePolicy `json:"pausePolicy,omitempty"`
+}
+
+// RolloutPausePolicy describes when a rollout waits for an approval before replacing more machines.
+type RolloutPausePolicy struct {
+	// PauseAfter is the list of numbers of replaced machines after which the rollout pauses.
+	// For example, [1] pauses the rollout once the first machine has been replaced,
+	// so that it can be validated before the remaining machines are replaced.
+	// +kubebuilder:validation:MinItems=1
+	PauseAfter []int32 `json:"pauseAfter"`
+}
+
+// InPlaceUpgrade is used to control the desired behavior of in-place upgrades.
+// Only Kubernetes/RKE2 version changes are applied in place, other changes
+// still require the machines to be replaced using a rolling update.
+type InPlaceUpgrade struct {
+	// Image is the image used to replace the RKE2 binaries on the nodes, following the
+	// system-upgrade-controller model. The image is tagged with the target RKE2 version.
+	// Defaults to rancher/rke2-upgrade.
+	// +optional
+	Image string `json:"image,omitempty"`
 }
 
 // RollingUpdate is used to control the desired behavior of rolling update.
@@ -410,6 +800,196 @@ type RollingUpdate struct {
 	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
 }
 
+// RolloutBefore describes when a rollout should be performed on the RCP machines.
+type RolloutBefore struct {
+	// CertificatesExpiryDays indicates a rollout needs to be performed if the
+	// certificates of the control plane will expire within the specified days.
+	// +kubebuilder:validation:Minimum=7
+	// +optional
+	CertificatesExpiryDays *int32 `json:"certificatesExpiryDays,omitempty"`
+}
+
+// MaintenanceWindow describes the time windows during which control plane machines can be replaced.
+type MaintenanceWindow struct {
+	// TimeZone is the IANA time zone in which the windows are defined, e.g. Europe/Berlin.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows is the list of time windows during which control plane machines can be replaced.
+	// +kubebuilder:validation:MinItems=1
+	Windows []TimeWindow `json:"windows"`
+}
+
+// EtcdMaintenance configures the periodic maintenance of the etcd cluster.
+type EtcdMaintenance struct {
+	// Interval is the interval at which the database size and alarms of the etcd members are checked.
+	// +kubebuilder:default="1h"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// DefragmentThresholdPercent is the percentage of the database size of a member that must be reclaimable
+	// for the member to be defragmented.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=50
+	// +optional
+	DefragmentThresholdPercent *int32 `json:"defragmentThresholdPercent,omitempty"`
+
+	// MinDefragmentDBSize is the database size under which a member is not defragmented, unless it raised
+	// a NOSPACE alarm.
+	// +kubebuilder:default="100Mi"
+	// +optional
+	MinDefragmentDBSize *resource.Quantity `json:"minDefragmentDBSize,omitempty"`
+}
+
+// RKE2ClusterImport describes an existing RKE2 cluster imported into the RKE2ControlPlane management.
+type RKE2ClusterImport struct {
+	// ServerAddresses are the addresses of the existing servers, used by the machines to join the cluster
+	// until some control plane machines are ready.
+	// +kubebuilder:validation:MinItems=1
+	ServerAddresses []string `json:"serverAddresses"`
+
+	// TokenSecretName is the name of a Secret, in the namespace of the RKE2ControlPlane, holding the server token
+	// of the existing cluster in the "value" key.
+	TokenSecretName string `json:"tokenSecretName"`
+
+	// CertificatesSecretPrefix is the name prefix of the Secrets, in the namespace of the RKE2ControlPlane, holding
+	// the certificate authorities of the existing cluster in the "tls.crt" and "tls.key" keys: <prefix>-ca holds
+	// the server CA, <prefix>-cca the client CA, <prefix>-etcd the etcd server CA and <prefix>-peer-etcd the etcd peer CA.
+	CertificatesSecretPrefix string `json:"certificatesSecretPrefix"`
+}
+
+// KubeVIP describes the virtual IP of the API server managed by kube-vip.
+type KubeVIP struct {
+	// Address is the virtual IP address of the API server.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// Interface is the network interface of the control plane nodes the virtual IP is bound to.
+	// +kubebuilder:validation:MinLength=1
+	Interface string `json:"interface"`
+
+	// Mode is the way the virtual IP is advertised: ARP elects a leader announcing the virtual IP,
+	// while BGP advertises it from every control plane node to a BGP peer. Defaults to ARP.
+	// +kubebuilder:validation:Enum=ARP;BGP
+	// +optional
+	Mode KubeVIPMode `json:"mode,omitempty"`
+
+	// BGP configures the BGP peering of the control plane nodes, required when the mode is BGP.
+	// +optional
+	BGP *KubeVIPBGP `json:"bgp,omitempty"`
+
+	// Image is the kube-vip image. Defaults to ghcr.io/kube-vip/kube-vip:v0.8.0.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// KubeVIPBGP describes the BGP peering of the control plane nodes.
+type KubeVIPBGP struct {
+	// AS is the AS number of the control plane nodes.
+	// +kubebuilder:validation:Minimum=1
+	AS uint32 `json:"as"`
+
+	// PeerAddress is the address of the BGP peer.
+	// +kubebuilder:validation:MinLength=1
+	PeerAddress string `json:"peerAddress"`
+
+	// PeerAS is the AS number of the BGP peer.
+	// +kubebuilder:validation:Minimum=1
+	PeerAS uint32 `json:"peerAS"`
+}
+
+// TimeWindow is a daily time range, optionally restricted to some days of the week.
+type TimeWindow struct {
+	// Days are the days of the week the window applies to. Defaults to every day.
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// Start is the start time of the window, in the HH:MM format.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End is the end time of the window, in the HH:MM format.
+	// An end time before the start time means the window ends on the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// Weekday is a day of the week.
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
+// RemediationStrategy allows to define how control plane machine remediation happens.
+type RemediationStrategy struct {
+	// MaxRetry is the Max number of retries while attempting to remediate an unhealthy machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails.
+	// For example, given a control plane with three machines M1, M2, M3:
+	//
+	//	M1 become unhealthy; remediation happens, and M1-1 is created as a replacement.
+	//	If M1-1 (replacement of M1) has problems while bootstrapping it will become unhealthy, and then be
+	//	remediated; such operation is considered a retry, remediation-retry #1.
+	//	If M1-2 (replacement of M1-1) becomes unhealthy, remediation-retry #2 will happen, etc.
+	//
+	// A retry could happen only after RetryPeriod from the previous retry.
+	// If a machine is marked as unhealthy after MinHealthyPeriod from the previous remediation expired,
+	// this is not considered a retry anymore because the new issue is assumed unrelated from the previous one.
+	//
+	// If not set, the remediation will be retried infinitely.
+	// +optional
+	MaxRetry *int32 `json:"maxRetry,omitempty"`
+
+	// RetryPeriod is the duration that RCP should wait before remediating a machine being created as a replacement
+	// for an unhealthy machine (a retry).
+	//
+	// If not set, a retry will happen immediately.
+	// +optional
+	RetryPeriod metav1.Duration `json:"retryPeriod,omitempty"`
+
+	// MinHealthyPeriod defines the duration after which RCP will consider any failure to a machine unrelated
+	// from the previous one. In this case the remediation is not considered a retry anymore, and thus the retry
+	// counter restarts from 0. For example, assuming MinHealthyPeriod is set to 1h (default)
+	//
+	//	M1 become unhealthy; remediation happens, and M1-1 is created as a replacement.
+	//	If M1-1 (replacement of M1) has problems within the 1hr after the creation, also
+	//	this machine will be remediated and this operation is considered a retry - a problem related
+	//	to the original issue happened to M1 -.
+	//
+	//	If instead the problem on M1-1 is happening after MinHealthyPeriod expired, e.g. four days after
+	//	m1-1 has been created as a remediation of M1, the problem on M1-1 is considered unrelated to
+	//	the original issue happened to M1.
+	//
+	// If not set, this value is defaulted to 1h.
+	// +optional
+	MinHealthyPeriod *metav1.Duration `json:"minHealthyPeriod,omitempty"`
+}
+
+// ScaleDownPolicy defines how a machine is selected for scale down.
+type ScaleDownPolicy string
+
+const (
+	// OldestScaleDownPolicy selects the oldest machine.
+	OldestScaleDownPolicy ScaleDownPolicy = "Oldest"
+
+	// NewestScaleDownPolicy selects the newest machine.
+	NewestScaleDownPolicy ScaleDownPolicy = "Newest"
+
+	// AvoidEtcdLeaderScaleDownPolicy selects the oldest machine which is not the etcd leader, so the
+	// leadership does not need to be moved before removing the machine.
+	AvoidEtcdLeaderScaleDownPolicy ScaleDownPolicy = "AvoidEtcdLeader"
+)
+
+// KubeVIPMode is the way the virtual IP of the API server is advertised.
+type KubeVIPMode string
+
+const (
+	// ARPKubeVIPMode advertises the virtual IP with ARP from the elected leader.
+	ARPKubeVIPMode KubeVIPMode = "ARP"
+
+	// BGPKubeVIPMode advertises the virtual IP with BGP from every control plane node.
+	BGPKubeVIPMode KubeVIPMode = "BGP"
+)
+
 // RolloutStrategyType defines the rollout strategies for a RKE2ControlPlane.
 type RolloutStrategyType string
 
@@ -417,6 +997,10 @@ const (
 	// RollingUpdateStrategyType replaces the old control planes by new one using rolling update
 	// i.e. gradually scale up or down the old control planes and scale up or down the new one.
 	RollingUpdateStrategyType RolloutStrategyType = "RollingUpdate"
+
+	// InPlaceStrategyType upgrades the RKE2 version of the existing control planes one at a time,
+	// without replacing the machines.
+	InPlaceStrategyType RolloutStrategyType = "InPlace"
 )
 
 func init() { //nolint:gochecknoinits
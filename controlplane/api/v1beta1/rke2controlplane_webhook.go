@@ -17,10 +17,16 @@ limitations under the License.
 package v1beta1
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -48,6 +54,7 @@ var _ webhook.Defaulter = &RKE2ControlPlane{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type.
 func (r *RKE2ControlPlane) Default() {
 	bootstrapv1.DefaultRKE2ConfigSpec(&r.Spec.RKE2ConfigSpec)
+	defaultKubeVIP(&r.Spec)
 }
 
 //+kubebuilder:webhook:path=/validate-controlplane-cluster-x-k8s-io-v1beta1-rke2controlplane,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes,verbs=create;update,versions=v1beta1,name=vrke2controlplane.kb.io,admissionReviewVersions=v1
@@ -63,12 +70,22 @@ func (r *RKE2ControlPlane) ValidateCreate() (admission.Warnings, error) {
 	allErrs = append(allErrs, bootstrapv1.ValidateRKE2ConfigSpec(r.Name, &r.Spec.RKE2ConfigSpec)...)
 	allErrs = append(allErrs, r.validateCNI()...)
 	allErrs = append(allErrs, r.validateRegistrationMethod()...)
+	allErrs = append(allErrs, validateRolloutStrategy(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMaintenanceWindow(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateKubeVIP(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateSecretsEncryption(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateClusterDNS(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateChartValues(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateManifestSources(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateAgentTokenRotation(&r.Spec, field.NewPath("spec"))...)
+
+	warnings := kubeVIPWarnings(&r.Spec, field.NewPath("spec"))
 
 	if len(allErrs) == 0 {
-		return nil, nil
+		return warnings, nil
 	}
 
-	return nil, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
@@ -84,18 +101,29 @@ func (r *RKE2ControlPlane) ValidateUpdate(old runtime.Object) (admission.Warning
 
 	allErrs = append(allErrs, bootstrapv1.ValidateRKE2ConfigSpec(r.Name, &r.Spec.RKE2ConfigSpec)...)
 	allErrs = append(allErrs, r.validateCNI()...)
-
-	if r.Spec.RegistrationMethod != oldControlplane.Spec.RegistrationMethod {
+	allErrs = append(allErrs, validateRolloutStrategy(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMaintenanceWindow(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateKubeVIP(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateSecretsEncryption(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateClusterDNS(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateChartValues(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateManifestSources(&r.Spec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateAgentTokenRotation(&r.Spec, field.NewPath("spec"))...)
+
+	if r.Spec.RegistrationMethod != oldControlplane.Spec.RegistrationMethod &&
+		!kubeVIPRegistrationDefaulted(&oldControlplane.Spec, &r.Spec) {
 		allErrs = append(allErrs,
 			field.Invalid(field.NewPath("spec", "registrationMethod"), r.Spec.RegistrationMethod, "field is immutable"),
 		)
 	}
 
+	warnings := kubeVIPWarnings(&r.Spec, field.NewPath("spec"))
+
 	if len(allErrs) == 0 {
-		return nil, nil
+		return warnings, nil
 	}
 
-	return nil, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
@@ -130,3 +158,274 @@ func (r *RKE2ControlPlane) validateRegistrationMethod() field.ErrorList {
 
 	return allErrs
 }
+
+func validateRolloutStrategy(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.RolloutStrategy == nil {
+		return allErrs
+	}
+
+	path := specPath.Child("rolloutStrategy")
+
+	if spec.RolloutStrategy.InPlace != nil && spec.RolloutStrategy.Type != InPlaceStrategyType {
+		allErrs = append(allErrs,
+			field.Invalid(path.Child("inPlace"), spec.RolloutStrategy.InPlace,
+				"can only be set when the rollout strategy type is InPlace"))
+	}
+
+	if spec.RolloutStrategy.PausePolicy != nil {
+		for i, pauseAfter := range spec.RolloutStrategy.PausePolicy.PauseAfter {
+			if pauseAfter < 1 {
+				allErrs = append(allErrs,
+					field.Invalid(path.Child("pausePolicy", "pauseAfter").Index(i), pauseAfter, "must be greater than 0"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateMaintenanceWindow(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.MaintenanceWindow == nil {
+		return allErrs
+	}
+
+	path := specPath.Child("maintenanceWindow")
+
+	if _, err := spec.MaintenanceWindow.Location(); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("timeZone"), spec.MaintenanceWindow.TimeZone, err.Error()))
+	}
+
+	for i, window := range spec.MaintenanceWindow.Windows {
+		if window.Start == window.End {
+			allErrs = append(allErrs,
+				field.Invalid(path.Child("windows").Index(i).Child("end"), window.End, "must be different from the start time"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateAgentTokenRotation rejects the rotation of the agent token of imported clusters, as the existing servers
+// would keep accepting the previous agent token.
+func validateAgentTokenRotation(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.AgentTokenRotateAfter != nil && spec.Import != nil {
+		allErrs = append(allErrs,
+			field.Forbidden(specPath.Child("agentTokenRotateAfter"), "agent token rotation is not supported for imported clusters"))
+	}
+
+	return allErrs
+}
+
+// defaultKubeVIP defaults the mode of the kube-vip virtual IP, and uses the virtual IP as the registration address
+// when no registration method is set.
+func defaultKubeVIP(spec *RKE2ControlPlaneSpec) {
+	if spec.KubeVIP == nil {
+		return
+	}
+
+	if spec.KubeVIP.Mode == "" {
+		spec.KubeVIP.Mode = ARPKubeVIPMode
+	}
+
+	if spec.RegistrationMethod == "" {
+		spec.RegistrationMethod = RegistrationMethodAddress
+		spec.RegistrationAddress = spec.KubeVIP.Address
+	}
+}
+
+// kubeVIPRegistrationDefaulted returns true if the registration method was unset and has been defaulted to the kube-vip
+// virtual IP, which is allowed on update as the registration method is otherwise immutable.
+func kubeVIPRegistrationDefaulted(oldSpec, spec *RKE2ControlPlaneSpec) bool {
+	return oldSpec.RegistrationMethod == "" &&
+		spec.KubeVIP != nil &&
+		spec.RegistrationMethod == RegistrationMethodAddress &&
+		spec.RegistrationAddress == spec.KubeVIP.Address
+}
+
+// kubeVIPWarnings warns when the kube-vip virtual IP is not used to register the nodes, as the registration method
+// was already set when kube-vip was configured.
+func kubeVIPWarnings(spec *RKE2ControlPlaneSpec, specPath *field.Path) admission.Warnings {
+	if spec.KubeVIP == nil || spec.RegistrationMethod == RegistrationMethodControlPlaneEndpoint ||
+		(spec.RegistrationMethod == RegistrationMethodAddress && spec.RegistrationAddress == spec.KubeVIP.Address) {
+		return nil
+	}
+
+	return admission.Warnings{fmt.Sprintf("%s: the kube-vip virtual IP %s is not used to register the nodes with the %q registration method",
+		specPath.Child("kubeVIP"), spec.KubeVIP.Address, spec.RegistrationMethod)}
+}
+
+func validateKubeVIP(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.KubeVIP == nil {
+		return allErrs
+	}
+
+	path := specPath.Child("kubeVIP")
+
+	if net.ParseIP(spec.KubeVIP.Address) == nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("address"), spec.KubeVIP.Address, "must be a valid IP address"))
+	}
+
+	if spec.KubeVIP.Mode == BGPKubeVIPMode && spec.KubeVIP.BGP == nil {
+		allErrs = append(allErrs, field.Required(path.Child("bgp"), "must be set when the mode is BGP"))
+	}
+
+	if spec.KubeVIP.Mode != BGPKubeVIPMode && spec.KubeVIP.BGP != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("bgp"), spec.KubeVIP.BGP, "can only be set when the mode is BGP"))
+	}
+
+	return allErrs
+}
+
+func validateSecretsEncryption(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	secretsEncryption := spec.ServerConfig.SecretsEncryption
+	if secretsEncryption == nil {
+		return allErrs
+	}
+
+	path := specPath.Child("serverConfig", "secretsEncryption")
+
+	if secretsEncryption.Enabled != nil && !*secretsEncryption.Enabled {
+		if secretsEncryption.Provider != "" {
+			allErrs = append(allErrs, field.Invalid(path.Child("provider"), secretsEncryption.Provider,
+				"cannot be set when secrets encryption is disabled"))
+		}
+
+		if spec.SecretsEncryptionKeyRotateAfter != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("secretsEncryptionKeyRotateAfter"), spec.SecretsEncryptionKeyRotateAfter,
+				"the keys cannot be rotated when secrets encryption is disabled"))
+		}
+	}
+
+	if secretsEncryption.EncryptionConfigSecret == nil {
+		return allErrs
+	}
+
+	if secretsEncryption.Provider != "" {
+		allErrs = append(allErrs, field.Invalid(path.Child("provider"), secretsEncryption.Provider,
+			"cannot be set with a custom encryption configuration"))
+	}
+
+	if spec.SecretsEncryptionKeyRotateAfter != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("secretsEncryptionKeyRotateAfter"), spec.SecretsEncryptionKeyRotateAfter,
+			"the keys of a custom encryption configuration cannot be rotated"))
+	}
+
+	return allErrs
+}
+
+// validateClusterDNS checks that the cluster DNS holds one IP address per IP family of the cluster, at most.
+// The IP families of the cluster DNS and of the Cluster CIDRs are compared when the RKE2 configuration is generated,
+// as the Cluster is not known when the control plane is admitted.
+func validateClusterDNS(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.ServerConfig.ClusterDNS == "" {
+		return allErrs
+	}
+
+	path := specPath.Child("serverConfig", "clusterDNS")
+	families := map[IPFamily]bool{}
+
+	for _, address := range strings.Split(spec.ServerConfig.ClusterDNS, ",") {
+		ip := net.ParseIP(strings.TrimSpace(address))
+		if ip == nil {
+			allErrs = append(allErrs, field.Invalid(path, spec.ServerConfig.ClusterDNS, "must be a comma-separated list of IP addresses"))
+
+			continue
+		}
+
+		family := IPv6IPFamily
+		if ip.To4() != nil {
+			family = IPv4IPFamily
+		}
+
+		if families[family] {
+			allErrs = append(allErrs, field.Invalid(path, spec.ServerConfig.ClusterDNS, "must hold one IP address per IP family at most"))
+		}
+
+		families[family] = true
+	}
+
+	return allErrs
+}
+
+// validateChartValues checks that the chart values are set for charts packaged with RKE2, as objects.
+func validateChartValues(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	path := specPath.Child("serverConfig", "chartValues")
+
+	for chart, values := range spec.ServerConfig.ChartValues {
+		if !slices.Contains(PackagedCharts, chart) {
+			allErrs = append(allErrs, field.NotSupported(path, chart, PackagedCharts))
+
+			continue
+		}
+
+		if err := json.Unmarshal(values.Raw, &map[string]interface{}{}); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Key(chart), string(values.Raw), "must be an object"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateManifestSources checks the keys and filename prefixes of the manifest sources, and that the manifest files
+// they define do not collide. Only the collisions between the files known at admission are detected, the other ones
+// are detected when the manifests are read from the sources.
+func validateManifestSources(spec *RKE2ControlPlaneSpec, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	path := specPath.Child("manifestSources")
+	unfiltered := map[string]bool{}
+	filenames := map[string]bool{}
+
+	for i, source := range spec.ManifestSources {
+		sourcePath := path.Index(i)
+
+		if strings.Contains(source.FilenamePrefix, "/") {
+			allErrs = append(allErrs, field.Invalid(sourcePath.Child("filenamePrefix"), source.FilenamePrefix, "must not contain '/'"))
+		}
+
+		// A source deploying all its data entries collides with any other source of the same files.
+		identity := string(source.Kind) + "/" + source.Name + "/" + source.FilenamePrefix
+		if len(source.Keys) == 0 {
+			if unfiltered[identity] {
+				allErrs = append(allErrs, field.Duplicate(sourcePath, source.Name))
+			}
+
+			unfiltered[identity] = true
+		}
+	}
+
+	for i, source := range spec.ManifestSources {
+		identity := string(source.Kind) + "/" + source.Name + "/" + source.FilenamePrefix
+
+		for j, key := range source.Keys {
+			keyPath := path.Index(i).Child("keys").Index(j)
+
+			for _, msg := range validation.IsConfigMapKey(key) {
+				allErrs = append(allErrs, field.Invalid(keyPath, key, msg))
+			}
+
+			filename := source.FilenamePrefix + key
+			if filenames[filename] || unfiltered[identity] {
+				allErrs = append(allErrs, field.Duplicate(keyPath, filename))
+			}
+
+			filenames[filename] = true
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,424 @@
+/*
+Copyright 2024 SUSE LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+)
+
+func TestDefaultKubeVIP(t *testing.T) {
+	tests := []struct {
+		name                    string
+		spec                    RKE2ControlPlaneSpec
+		wantMode                KubeVIPMode
+		wantRegistrationMethod  RegistrationMethod
+		wantRegistrationAddress string
+	}{
+		{
+			name:                    "defaults the mode and the registration address",
+			spec:                    RKE2ControlPlaneSpec{KubeVIP: &KubeVIP{Address: "192.168.1.100", Interface: "eth0"}},
+			wantMode:                ARPKubeVIPMode,
+			wantRegistrationMethod:  RegistrationMethodAddress,
+			wantRegistrationAddress: "192.168.1.100",
+		},
+		{
+			name: "keeps the registration method set by the user",
+			spec: RKE2ControlPlaneSpec{
+				KubeVIP:            &KubeVIP{Address: "192.168.1.100", Interface: "eth0", Mode: BGPKubeVIPMode},
+				RegistrationMethod: RegistrationMethodInternalIPs,
+			},
+			wantMode:               BGPKubeVIPMode,
+			wantRegistrationMethod: RegistrationMethodInternalIPs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			defaultKubeVIP(&tt.spec)
+			g.Expect(tt.spec.KubeVIP.Mode).To(Equal(tt.wantMode))
+			g.Expect(tt.spec.RegistrationMethod).To(Equal(tt.wantRegistrationMethod))
+			g.Expect(tt.spec.RegistrationAddress).To(Equal(tt.wantRegistrationAddress))
+		})
+	}
+}
+
+func TestKubeVIPRegistrationOnUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	oldRCP := &RKE2ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	// Adding kube-vip to a control plane without registration method defaults it to the virtual IP.
+	rcp := oldRCP.DeepCopy()
+	rcp.Spec.KubeVIP = &KubeVIP{Address: "192.168.1.100", Interface: "eth0"}
+	rcp.Default()
+
+	warnings, err := rcp.ValidateUpdate(oldRCP)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(warnings).To(BeEmpty())
+	g.Expect(rcp.Spec.RegistrationMethod).To(Equal(RegistrationMethodAddress))
+
+	// A registration method already set is kept, with a warning as the virtual IP is not used to register the nodes.
+	oldRCP.Spec.RegistrationMethod = RegistrationMethodFavourInternalIPs
+	rcp = oldRCP.DeepCopy()
+	rcp.Spec.KubeVIP = &KubeVIP{Address: "192.168.1.100", Interface: "eth0"}
+	rcp.Default()
+
+	warnings, err = rcp.ValidateUpdate(oldRCP)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(warnings).To(ConsistOf(ContainSubstring("192.168.1.100 is not used to register the nodes")))
+
+	// The registration method remains immutable otherwise.
+	rcp.Spec.RegistrationMethod = RegistrationMethodAddress
+	rcp.Spec.RegistrationAddress = "192.168.1.100"
+
+	_, err = rcp.ValidateUpdate(oldRCP)
+	g.Expect(err).To(MatchError(ContainSubstring("field is immutable")))
+}
+
+func TestValidateKubeVIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		kubeVIP  *KubeVIP
+		wantErrs int
+	}{
+		{
+			name: "no kube-vip",
+		},
+		{
+			name:    "valid ARP configuration",
+			kubeVIP: &KubeVIP{Address: "192.168.1.100", Interface: "eth0", Mode: ARPKubeVIPMode},
+		},
+		{
+			name: "valid BGP configuration",
+			kubeVIP: &KubeVIP{
+				Address:   "fd00::100",
+				Interface: "lo",
+				Mode:      BGPKubeVIPMode,
+				BGP:       &KubeVIPBGP{AS: 65000, PeerAddress: "fd00::1", PeerAS: 65001},
+			},
+		},
+		{
+			name:     "address is not an IP",
+			kubeVIP:  &KubeVIP{Address: "kube-vip.example.com", Interface: "eth0", Mode: ARPKubeVIPMode},
+			wantErrs: 1,
+		},
+		{
+			name:     "BGP mode without BGP configuration",
+			kubeVIP:  &KubeVIP{Address: "192.168.1.100", Interface: "eth0", Mode: BGPKubeVIPMode},
+			wantErrs: 1,
+		},
+		{
+			name: "BGP configuration in ARP mode",
+			kubeVIP: &KubeVIP{
+				Address:   "192.168.1.100",
+				Interface: "eth0",
+				Mode:      ARPKubeVIPMode,
+				BGP:       &KubeVIPBGP{AS: 65000, PeerAddress: "192.168.1.1", PeerAS: 65001},
+			},
+			wantErrs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateKubeVIP(&RKE2ControlPlaneSpec{KubeVIP: tt.kubeVIP}, field.NewPath("spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
+
+func TestValidateSecretsEncryption(t *testing.T) {
+	rotateAfter := &metav1.Time{Time: time.Now()}
+	encryptionConfigSecret := &corev1.ObjectReference{Name: "encryption-config", Namespace: "default"}
+
+	tests := []struct {
+		name              string
+		secretsEncryption *SecretsEncryption
+		rotateAfter       *metav1.Time
+		wantErrs          int
+	}{
+		{
+			name:        "no secrets encryption configuration",
+			rotateAfter: rotateAfter,
+		},
+		{
+			name:              "provider with key rotation",
+			secretsEncryption: &SecretsEncryption{Provider: SecretBoxSecretsEncryptionProvider},
+			rotateAfter:       rotateAfter,
+		},
+		{
+			name:              "disabled with a provider and key rotation",
+			secretsEncryption: &SecretsEncryption{Enabled: ptr.To(false), Provider: AESCBCSecretsEncryptionProvider},
+			rotateAfter:       rotateAfter,
+			wantErrs:          2,
+		},
+		{
+			name:              "custom encryption configuration",
+			secretsEncryption: &SecretsEncryption{EncryptionConfigSecret: encryptionConfigSecret},
+		},
+		{
+			name: "custom encryption configuration with a provider and key rotation",
+			secretsEncryption: &SecretsEncryption{
+				Provider:               AESCBCSecretsEncryptionProvider,
+				EncryptionConfigSecret: encryptionConfigSecret,
+			},
+			rotateAfter: rotateAfter,
+			wantErrs:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := &RKE2ControlPlaneSpec{
+				ServerConfig:                    RKE2ServerConfig{SecretsEncryption: tt.secretsEncryption},
+				SecretsEncryptionKeyRotateAfter: tt.rotateAfter,
+			}
+
+			errs := validateSecretsEncryption(spec, field.NewPath("spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
+
+func TestValidateClusterDNS(t *testing.T) {
+	tests := []struct {
+		name       string
+		clusterDNS string
+		wantErrs   int
+	}{
+		{
+			name: "no cluster DNS",
+		},
+		{
+			name:       "single-stack",
+			clusterDNS: "10.43.0.10",
+		},
+		{
+			name:       "dual-stack",
+			clusterDNS: "10.43.0.10,fd00:43::a",
+		},
+		{
+			name:       "not an IP address",
+			clusterDNS: "10.43.0.10,dns",
+			wantErrs:   1,
+		},
+		{
+			name:       "two addresses of the same family",
+			clusterDNS: "fd00:43::a,fd00:43::b",
+			wantErrs:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := &RKE2ControlPlaneSpec{ServerConfig: RKE2ServerConfig{ClusterDNS: tt.clusterDNS}}
+
+			errs := validateClusterDNS(spec, field.NewPath("spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
+
+func TestValidateChartValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		chartValues map[string]apiextensionsv1.JSON
+		wantErrs    int
+	}{
+		{
+			name: "no chart values",
+		},
+		{
+			name: "packaged charts",
+			chartValues: map[string]apiextensionsv1.JSON{
+				"rke2-coredns":       {Raw: []byte(`{"replicaCount":3}`)},
+				"rke2-ingress-nginx": {Raw: []byte(`{"controller":{"kind":"DaemonSet"}}`)},
+			},
+		},
+		{
+			name: "unknown chart",
+			chartValues: map[string]apiextensionsv1.JSON{
+				"rke2-coredns": {Raw: []byte(`{"replicaCount":3}`)},
+				"traefik":      {Raw: []byte(`{}`)},
+			},
+			wantErrs: 1,
+		},
+		{
+			name: "values which are not an object",
+			chartValues: map[string]apiextensionsv1.JSON{
+				"rke2-cilium": {Raw: []byte(`["kubeProxyReplacement"]`)},
+			},
+			wantErrs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := &RKE2ControlPlaneSpec{ServerConfig: RKE2ServerConfig{ChartValues: tt.chartValues}}
+
+			errs := validateChartValues(spec, field.NewPath("spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
+
+func TestValidateManifestSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  []ManifestSource
+		wantErrs int
+	}{
+		{
+			name: "no manifest sources",
+		},
+		{
+			name: "distinct sources",
+			sources: []ManifestSource{
+				{Kind: ConfigMapManifestSource, Name: "manifests"},
+				{Kind: SecretManifestSource, Name: "credentials", Keys: []string{"cloud.yaml"}, FilenamePrefix: "secret-"},
+				{Kind: ConfigMapManifestSource, Name: "manifests", FilenamePrefix: "copy-"},
+			},
+		},
+		{
+			name: "same file from two sources",
+			sources: []ManifestSource{
+				{Kind: ConfigMapManifestSource, Name: "manifests", Keys: []string{"app.yaml"}},
+				{Kind: SecretManifestSource, Name: "credentials", Keys: []string{"app.yaml"}},
+			},
+			wantErrs: 1,
+		},
+		{
+			name: "same source twice",
+			sources: []ManifestSource{
+				{Kind: ConfigMapManifestSource, Name: "manifests"},
+				{Kind: ConfigMapManifestSource, Name: "manifests"},
+				{Kind: ConfigMapManifestSource, Name: "manifests", Keys: []string{"app.yaml"}},
+			},
+			wantErrs: 2,
+		},
+		{
+			name: "invalid key and filename prefix",
+			sources: []ManifestSource{
+				{Kind: ConfigMapManifestSource, Name: "manifests", Keys: []string{"app:yaml"}, FilenamePrefix: "dir/"},
+			},
+			wantErrs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := &RKE2ControlPlaneSpec{ManifestSources: tt.sources}
+
+			errs := validateManifestSources(spec, field.NewPath("spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
+
+func TestValidateRolloutStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy *RolloutStrategy
+		wantErrs int
+	}{
+		{
+			name: "no rollout strategy",
+		},
+		{
+			name: "in-place rollout strategy",
+			strategy: &RolloutStrategy{
+				Type:        InPlaceStrategyType,
+				InPlace:     &InPlaceUpgrade{},
+				PausePolicy: &RolloutPausePolicy{PauseAfter: []int32{1, 2}},
+			},
+		},
+		{
+			name: "in-place upgrade with a rolling update and pause after no machine",
+			strategy: &RolloutStrategy{
+				Type:        RollingUpdateStrategyType,
+				InPlace:     &InPlaceUpgrade{},
+				PausePolicy: &RolloutPausePolicy{PauseAfter: []int32{0, 1}},
+			},
+			wantErrs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			spec := &RKE2ControlPlaneSpec{RolloutStrategy: tt.strategy}
+
+			errs := validateRolloutStrategy(spec, field.NewPath("spec", "template", "spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
+
+func TestValidateAgentTokenRotation(t *testing.T) {
+	rotateAfter := &metav1.Time{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	clusterImport := &RKE2ClusterImport{ServerAddresses: []string{"10.0.0.1"}, TokenSecretName: "imported-token"}
+
+	tests := []struct {
+		name     string
+		spec     RKE2ControlPlaneSpec
+		wantErrs int
+	}{
+		{
+			name: "no rotation",
+			spec: RKE2ControlPlaneSpec{Import: clusterImport},
+		},
+		{
+			name: "rotation",
+			spec: RKE2ControlPlaneSpec{AgentTokenRotateAfter: rotateAfter},
+		},
+		{
+			name:     "rotation of an imported cluster",
+			spec:     RKE2ControlPlaneSpec{AgentTokenRotateAfter: rotateAfter, Import: clusterImport},
+			wantErrs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			errs := validateAgentTokenRotation(&tt.spec, field.NewPath("spec"))
+			g.Expect(errs).To(HaveLen(tt.wantErrs))
+		})
+	}
+}
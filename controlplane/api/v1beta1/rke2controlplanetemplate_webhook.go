@@ -44,6 +44,7 @@ var _ webhook.Defaulter = &RKE2ControlPlaneTemplate{}
 // Default implements webhook.Defaulter so a webhook will be registered for the type.
 func (r *RKE2ControlPlaneTemplate) Default() {
 	bootstrapv1.DefaultRKE2ConfigSpec(&r.Spec.Template.Spec.RKE2ConfigSpec)
+	defaultKubeVIP(&r.Spec.Template.Spec)
 }
 
 //+kubebuilder:webhook:path=/validate-controlplane-cluster-x-k8s-io-v1beta1-rke2controlplanetemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanetemplates,verbs=create;update,versions=v1beta1,name=vrke2controlplanetemplate.kb.io,admissionReviewVersions=v1
@@ -58,13 +59,23 @@ func (r *RKE2ControlPlaneTemplate) ValidateCreate() (admission.Warnings, error)
 
 	allErrs = append(allErrs, bootstrapv1.ValidateRKE2ConfigSpec(r.Name, &r.Spec.Template.Spec.RKE2ConfigSpec)...)
 	allErrs = append(allErrs, r.validateCNI()...)
+	allErrs = append(allErrs, validateRolloutStrategy(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateMaintenanceWindow(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateKubeVIP(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateSecretsEncryption(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateClusterDNS(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateChartValues(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateManifestSources(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateAgentTokenRotation(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
 	allErrs = append(allErrs, r.validateRegistrationMethod()...)
 
+	warnings := kubeVIPWarnings(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))
+
 	if len(allErrs) == 0 {
-		return nil, nil
+		return warnings, nil
 	}
 
-	return nil, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
@@ -80,18 +91,29 @@ func (r *RKE2ControlPlaneTemplate) ValidateUpdate(old runtime.Object) (admission
 
 	allErrs = append(allErrs, bootstrapv1.ValidateRKE2ConfigSpec(r.Name, &r.Spec.Template.Spec.RKE2ConfigSpec)...)
 	allErrs = append(allErrs, r.validateCNI()...)
-
-	if r.Spec.Template.Spec.RegistrationMethod != oldControlplane.Spec.Template.Spec.RegistrationMethod {
+	allErrs = append(allErrs, validateRolloutStrategy(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateMaintenanceWindow(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateKubeVIP(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateSecretsEncryption(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateClusterDNS(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateChartValues(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateManifestSources(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateAgentTokenRotation(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))...)
+
+	if r.Spec.Template.Spec.RegistrationMethod != oldControlplane.Spec.Template.Spec.RegistrationMethod &&
+		!kubeVIPRegistrationDefaulted(&oldControlplane.Spec.Template.Spec, &r.Spec.Template.Spec) {
 		allErrs = append(allErrs,
 			field.Invalid(field.NewPath("spec", "registrationMethod"), r.Spec.Template.Spec.RegistrationMethod, "field is immutable"),
 		)
 	}
 
+	warnings := kubeVIPWarnings(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec"))
+
 	if len(allErrs) == 0 {
-		return nil, nil
+		return warnings, nil
 	}
 
-	return nil, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
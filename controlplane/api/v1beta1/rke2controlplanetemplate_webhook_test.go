@@ -43,6 +43,23 @@ func TestRKE2ControlPlaneTemplateValidateCreate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "don't allow RKE2ControlPlaneTemplate with an invalid rollout strategy",
+			inputTemplate: &RKE2ControlPlaneTemplate{
+				Spec: RKE2ControlPlaneTemplateSpec{
+					Template: RKE2ControlPlaneTemplateResource{
+						Spec: RKE2ControlPlaneSpec{
+							RolloutStrategy: &RolloutStrategy{
+								Type:        RollingUpdateStrategyType,
+								InPlace:     &InPlaceUpgrade{},
+								PausePolicy: &RolloutPausePolicy{PauseAfter: []int32{0}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, test := range tests {
 		tt := test
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 SUSE LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// RKE2EtcdSnapshotSpec defines the desired state of RKE2EtcdSnapshot.
+type RKE2EtcdSnapshotSpec struct {
+	// ClusterName is the name of the Cluster to snapshot, in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// MachineName is the name of the control plane Machine on which the snapshot is taken.
+	// If not set, the snapshot is taken on the oldest healthy control plane Machine.
+	// +optional
+	MachineName string `json:"machineName,omitempty"`
+
+	// SnapshotName is the base name of the snapshot. RKE2 appends the node name and a timestamp to it.
+	// Defaults to the name of the RKE2EtcdSnapshot.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+}
+
+// RKE2EtcdSnapshotPhase describes the state of a RKE2EtcdSnapshot.
+type RKE2EtcdSnapshotPhase string
+
+const (
+	// RKE2EtcdSnapshotPhasePending is the state of a snapshot that has not been started yet.
+	RKE2EtcdSnapshotPhasePending RKE2EtcdSnapshotPhase = "Pending"
+
+	// RKE2EtcdSnapshotPhaseRunning is the state of a snapshot being taken.
+	RKE2EtcdSnapshotPhaseRunning RKE2EtcdSnapshotPhase = "Running"
+
+	// RKE2EtcdSnapshotPhaseSucceeded is the state of a snapshot that was taken successfully.
+	RKE2EtcdSnapshotPhaseSucceeded RKE2EtcdSnapshotPhase = "Succeeded"
+
+	// RKE2EtcdSnapshotPhaseFailed is the state of a snapshot that could not be taken.
+	RKE2EtcdSnapshotPhaseFailed RKE2EtcdSnapshotPhase = "Failed"
+)
+
+// RKE2EtcdSnapshotStatus defines the observed state of RKE2EtcdSnapshot.
+type RKE2EtcdSnapshotStatus struct {
+	// Phase is the state of the snapshot.
+	// +optional
+	Phase RKE2EtcdSnapshotPhase `json:"phase,omitempty"`
+
+	// NodeName is the name of the node on which the snapshot is taken.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// SnapshotName is the full name of the snapshot file created by RKE2.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// Location is the location of the snapshot, either a file:// path on the node or a s3:// URL.
+	// +optional
+	Location string `json:"location,omitempty"`
+
+	// Size is the size of the snapshot.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// CompletionTime is the time at which the snapshot was completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Conditions defines current service state of the RKE2EtcdSnapshot.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:resource:path=rke2etcdsnapshots,scope=Namespaced,categories=cluster-api
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="Cluster"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Snapshot phase"
+// +kubebuilder:printcolumn:name="Snapshot",type="string",JSONPath=".status.snapshotName",description="Snapshot file name"
+// +kubebuilder:printcolumn:name="Size",type="string",JSONPath=".status.size",description="Snapshot size"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RKE2EtcdSnapshot is the Schema for the rke2etcdsnapshots API.
+type RKE2EtcdSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RKE2EtcdSnapshotSpec   `json:"spec,omitempty"`
+	Status RKE2EtcdSnapshotStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RKE2EtcdSnapshotList contains a list of RKE2EtcdSnapshot.
+type RKE2EtcdSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RKE2EtcdSnapshot `json:"items"`
+}
+
+// GetConditions returns the list of conditions for a RKE2EtcdSnapshot object.
+func (r *RKE2EtcdSnapshot) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+// SetConditions sets the list of conditions for a RKE2EtcdSnapshot object.
+func (r *RKE2EtcdSnapshot) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
+// GetSnapshotName returns the base name of the snapshot, defaulting to the name of the object.
+func (r *RKE2EtcdSnapshot) GetSnapshotName() string {
+	if r.Spec.SnapshotName != "" {
+		return r.Spec.SnapshotName
+	}
+
+	return r.Name
+}
+
+func init() { //nolint:gochecknoinits
+	objectTypes = append(objectTypes, &RKE2EtcdSnapshot{}, &RKE2EtcdSnapshotList{})
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 SUSE LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// RKE2EtcdSnapshotRestoreSpec defines the desired state of RKE2EtcdSnapshotRestore.
+type RKE2EtcdSnapshotRestoreSpec struct {
+	// ClusterName is the name of the Cluster to restore, in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	ClusterName string `json:"clusterName"`
+
+	// EtcdSnapshotName is the name of a successful RKE2EtcdSnapshot of the cluster, in the same namespace, to restore.
+	// Either EtcdSnapshotName or SnapshotName must be set.
+	// +optional
+	EtcdSnapshotName string `json:"etcdSnapshotName,omitempty"`
+
+	// SnapshotName is the name of the snapshot file to restore, as listed by `rke2 etcd-snapshot list`.
+	// Local snapshot files are looked up in the default snapshot directory, unless an absolute path is given.
+	// Either EtcdSnapshotName or SnapshotName must be set.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// S3 indicates the snapshot file referenced by SnapshotName is stored in the S3 bucket configured
+	// for the control plane, instead of on the nodes.
+	// +optional
+	S3 bool `json:"s3,omitempty"`
+
+	// MachineName is the name of the control plane Machine holding the local snapshot file referenced by SnapshotName.
+	// This Machine is kept while the other control plane Machines are replaced.
+	// If not set, the oldest healthy control plane Machine is used.
+	// +optional
+	MachineName string `json:"machineName,omitempty"`
+}
+
+// RKE2EtcdSnapshotRestorePhase describes the state of a RKE2EtcdSnapshotRestore.
+type RKE2EtcdSnapshotRestorePhase string
+
+const (
+	// RKE2EtcdSnapshotRestorePhasePending is the state of a restore that has not been started yet.
+	RKE2EtcdSnapshotRestorePhasePending RKE2EtcdSnapshotRestorePhase = "Pending"
+
+	// RKE2EtcdSnapshotRestorePhaseScalingDown is the state of a restore waiting for the control plane
+	// to be scaled down to a single machine.
+	RKE2EtcdSnapshotRestorePhaseScalingDown RKE2EtcdSnapshotRestorePhase = "ScalingDown"
+
+	// RKE2EtcdSnapshotRestorePhaseRestoring is the state of a restore running the cluster reset on the remaining machine.
+	RKE2EtcdSnapshotRestorePhaseRestoring RKE2EtcdSnapshotRestorePhase = "Restoring"
+
+	// RKE2EtcdSnapshotRestorePhaseRejoining is the state of a restore waiting for new control plane machines
+	// to join the restored etcd cluster.
+	RKE2EtcdSnapshotRestorePhaseRejoining RKE2EtcdSnapshotRestorePhase = "Rejoining"
+
+	// RKE2EtcdSnapshotRestorePhaseSucceeded is the state of a restore that completed successfully.
+	RKE2EtcdSnapshotRestorePhaseSucceeded RKE2EtcdSnapshotRestorePhase = "Succeeded"
+
+	// RKE2EtcdSnapshotRestorePhaseFailed is the state of a restore that could not be completed.
+	RKE2EtcdSnapshotRestorePhaseFailed RKE2EtcdSnapshotRestorePhase = "Failed"
+)
+
+// RKE2EtcdSnapshotRestoreStatus defines the observed state of RKE2EtcdSnapshotRestore.
+type RKE2EtcdSnapshotRestoreStatus struct {
+	// Phase is the state of the restore.
+	// +optional
+	Phase RKE2EtcdSnapshotRestorePhase `json:"phase,omitempty"`
+
+	// MachineName is the name of the control plane Machine on which the snapshot is restored.
+	// +optional
+	MachineName string `json:"machineName,omitempty"`
+
+	// NodeName is the name of the node on which the snapshot is restored.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// SnapshotName is the name or path of the snapshot file being restored.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// S3 indicates the snapshot file is restored from S3.
+	// +optional
+	S3 bool `json:"s3,omitempty"`
+
+	// RestoreStartTime is the time at which the cluster reset was started on the node.
+	// +optional
+	RestoreStartTime *metav1.Time `json:"restoreStartTime,omitempty"`
+
+	// CompletionTime is the time at which the restore was completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Conditions defines current service state of the RKE2EtcdSnapshotRestore.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:resource:path=rke2etcdsnapshotrestores,scope=Namespaced,categories=cluster-api
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="Cluster"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Restore phase"
+// +kubebuilder:printcolumn:name="Snapshot",type="string",JSONPath=".status.snapshotName",description="Restored snapshot"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RKE2EtcdSnapshotRestore is the Schema for the rke2etcdsnapshotrestores API.
+// While a restore is in progress, the RKE2ControlPlane of the cluster pauses its normal reconciliation,
+// scales the control plane down to a single machine, resets etcd on it from the snapshot
+// and then scales the control plane back up with fresh machines.
+type RKE2EtcdSnapshotRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RKE2EtcdSnapshotRestoreSpec   `json:"spec,omitempty"`
+	Status RKE2EtcdSnapshotRestoreStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RKE2EtcdSnapshotRestoreList contains a list of RKE2EtcdSnapshotRestore.
+type RKE2EtcdSnapshotRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RKE2EtcdSnapshotRestore `json:"items"`
+}
+
+// GetConditions returns the list of conditions for a RKE2EtcdSnapshotRestore object.
+func (r *RKE2EtcdSnapshotRestore) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+// SetConditions sets the list of conditions for a RKE2EtcdSnapshotRestore object.
+func (r *RKE2EtcdSnapshotRestore) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
+// IsFinished returns true if the restore has either succeeded or failed.
+func (r *RKE2EtcdSnapshotRestore) IsFinished() bool {
+	return r.Status.Phase == RKE2EtcdSnapshotRestorePhaseSucceeded || r.Status.Phase == RKE2EtcdSnapshotRestorePhaseFailed
+}
+
+func init() { //nolint:gochecknoinits
+	objectTypes = append(objectTypes, &RKE2EtcdSnapshotRestore{}, &RKE2EtcdSnapshotRestoreList{})
+}
@@ -37,3 +37,13 @@ var (
 	// Cluster is used for registration.
 	RegistrationMethodControlPlaneEndpoint = RegistrationMethod("control-plane-endpoint")
 )
+
+// IPFamily is the IP family of an address.
+type IPFamily string
+
+const (
+	// IPv4IPFamily is the IPv4 family.
+	IPv4IPFamily = IPFamily("IPv4")
+	// IPv6IPFamily is the IPv6 family.
+	IPv6IPFamily = IPFamily("IPv6")
+)
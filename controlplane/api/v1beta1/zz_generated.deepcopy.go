@@ -23,6 +23,7 @@ package v1beta1
 import (
 	apiv1beta1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -100,6 +101,62 @@ func (in *EtcdConfig) DeepCopy() *EtcdConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdMaintenance) DeepCopyInto(out *EtcdMaintenance) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DefragmentThresholdPercent != nil {
+		in, out := &in.DefragmentThresholdPercent, &out.DefragmentThresholdPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinDefragmentDBSize != nil {
+		in, out := &in.MinDefragmentDBSize, &out.MinDefragmentDBSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdMaintenance.
+func (in *EtcdMaintenance) DeepCopy() *EtcdMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdMemberStatus) DeepCopyInto(out *EtcdMemberStatus) {
+	*out = *in
+	out.DBSize = in.DBSize.DeepCopy()
+	out.DBSizeInUse = in.DBSizeInUse.DeepCopy()
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastDefragmentTime != nil {
+		in, out := &in.LastDefragmentTime, &out.LastDefragmentTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdMemberStatus.
+func (in *EtcdMemberStatus) DeepCopy() *EtcdMemberStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdMemberStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EtcdS3) DeepCopyInto(out *EtcdS3) {
 	*out = *in
@@ -121,6 +178,149 @@ func (in *EtcdS3) DeepCopy() *EtcdS3 {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InPlaceUpgrade) DeepCopyInto(out *InPlaceUpgrade) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InPlaceUpgrade.
+func (in *InPlaceUpgrade) DeepCopy() *InPlaceUpgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(InPlaceUpgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeVIP) DeepCopyInto(out *KubeVIP) {
+	*out = *in
+	if in.BGP != nil {
+		in, out := &in.BGP, &out.BGP
+		*out = new(KubeVIPBGP)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeVIP.
+func (in *KubeVIP) DeepCopy() *KubeVIP {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeVIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeVIPBGP) DeepCopyInto(out *KubeVIPBGP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeVIPBGP.
+func (in *KubeVIPBGP) DeepCopy() *KubeVIPBGP {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeVIPBGP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LastRemediationStatus) DeepCopyInto(out *LastRemediationStatus) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LastRemediationStatus.
+func (in *LastRemediationStatus) DeepCopy() *LastRemediationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LastRemediationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]TimeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManifestObjectReference) DeepCopyInto(out *ManifestObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManifestObjectReference.
+func (in *ManifestObjectReference) DeepCopy() *ManifestObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ManifestObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManifestSource) DeepCopyInto(out *ManifestSource) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManifestSource.
+func (in *ManifestSource) DeepCopy() *ManifestSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ManifestSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2ClusterImport) DeepCopyInto(out *RKE2ClusterImport) {
+	*out = *in
+	if in.ServerAddresses != nil {
+		in, out := &in.ServerAddresses, &out.ServerAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2ClusterImport.
+func (in *RKE2ClusterImport) DeepCopy() *RKE2ClusterImport {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2ClusterImport)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKE2ControlPlane) DeepCopyInto(out *RKE2ControlPlane) {
 	*out = *in
@@ -214,6 +414,13 @@ func (in *RKE2ControlPlaneSpec) DeepCopyInto(out *RKE2ControlPlaneSpec) {
 	in.MachineTemplate.DeepCopyInto(&out.MachineTemplate)
 	in.ServerConfig.DeepCopyInto(&out.ServerConfig)
 	out.ManifestsConfigMapReference = in.ManifestsConfigMapReference
+	if in.ManifestSources != nil {
+		in, out := &in.ManifestSources, &out.ManifestSources
+		*out = make([]ManifestSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.InfrastructureRef = in.InfrastructureRef
 	if in.NodeDrainTimeout != nil {
 		in, out := &in.NodeDrainTimeout, &out.NodeDrainTimeout
@@ -225,6 +432,52 @@ func (in *RKE2ControlPlaneSpec) DeepCopyInto(out *RKE2ControlPlaneSpec) {
 		*out = new(RolloutStrategy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RemediationStrategy != nil {
+		in, out := &in.RemediationStrategy, &out.RemediationStrategy
+		*out = new(RemediationStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RolloutBefore != nil {
+		in, out := &in.RolloutBefore, &out.RolloutBefore
+		*out = new(RolloutBefore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RolloutAfter != nil {
+		in, out := &in.RolloutAfter, &out.RolloutAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EtcdMaintenance != nil {
+		in, out := &in.EtcdMaintenance, &out.EtcdMaintenance
+		*out = new(EtcdMaintenance)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Import != nil {
+		in, out := &in.Import, &out.Import
+		*out = new(RKE2ClusterImport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeVIP != nil {
+		in, out := &in.KubeVIP, &out.KubeVIP
+		*out = new(KubeVIP)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenRotateAfter != nil {
+		in, out := &in.TokenRotateAfter, &out.TokenRotateAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.AgentTokenRotateAfter != nil {
+		in, out := &in.AgentTokenRotateAfter, &out.AgentTokenRotateAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.SecretsEncryptionKeyRotateAfter != nil {
+		in, out := &in.SecretsEncryptionKeyRotateAfter, &out.SecretsEncryptionKeyRotateAfter
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2ControlPlaneSpec.
@@ -262,6 +515,49 @@ func (in *RKE2ControlPlaneStatus) DeepCopyInto(out *RKE2ControlPlaneStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LastRemediation != nil {
+		in, out := &in.LastRemediation, &out.LastRemediation
+		*out = new(LastRemediationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EtcdMembers != nil {
+		in, out := &in.EtcdMembers, &out.EtcdMembers
+		*out = make([]EtcdMemberStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastEtcdMaintenanceTime != nil {
+		in, out := &in.LastEtcdMaintenanceTime, &out.LastEtcdMaintenanceTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastTokenRotationTime != nil {
+		in, out := &in.LastTokenRotationTime, &out.LastTokenRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAgentTokenRotationTime != nil {
+		in, out := &in.LastAgentTokenRotationTime, &out.LastAgentTokenRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SecretsEncryptionKeyRotation != nil {
+		in, out := &in.SecretsEncryptionKeyRotation, &out.SecretsEncryptionKeyRotation
+		*out = new(SecretsEncryptionKeyRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastSecretsEncryptionKeyRotationTime != nil {
+		in, out := &in.LastSecretsEncryptionKeyRotationTime, &out.LastSecretsEncryptionKeyRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AppliedManifests != nil {
+		in, out := &in.AppliedManifests, &out.AppliedManifests
+		*out = make([]ManifestObjectReference, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2ControlPlaneStatus.
@@ -365,6 +661,215 @@ func (in *RKE2ControlPlaneTemplateSpec) DeepCopy() *RKE2ControlPlaneTemplateSpec
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshot) DeepCopyInto(out *RKE2EtcdSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshot.
+func (in *RKE2EtcdSnapshot) DeepCopy() *RKE2EtcdSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RKE2EtcdSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotList) DeepCopyInto(out *RKE2EtcdSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RKE2EtcdSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotList.
+func (in *RKE2EtcdSnapshotList) DeepCopy() *RKE2EtcdSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RKE2EtcdSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotRestore) DeepCopyInto(out *RKE2EtcdSnapshotRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotRestore.
+func (in *RKE2EtcdSnapshotRestore) DeepCopy() *RKE2EtcdSnapshotRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RKE2EtcdSnapshotRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotRestoreList) DeepCopyInto(out *RKE2EtcdSnapshotRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RKE2EtcdSnapshotRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotRestoreList.
+func (in *RKE2EtcdSnapshotRestoreList) DeepCopy() *RKE2EtcdSnapshotRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RKE2EtcdSnapshotRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotRestoreSpec) DeepCopyInto(out *RKE2EtcdSnapshotRestoreSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotRestoreSpec.
+func (in *RKE2EtcdSnapshotRestoreSpec) DeepCopy() *RKE2EtcdSnapshotRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotRestoreStatus) DeepCopyInto(out *RKE2EtcdSnapshotRestoreStatus) {
+	*out = *in
+	if in.RestoreStartTime != nil {
+		in, out := &in.RestoreStartTime, &out.RestoreStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(cluster_apiapiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotRestoreStatus.
+func (in *RKE2EtcdSnapshotRestoreStatus) DeepCopy() *RKE2EtcdSnapshotRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotSpec) DeepCopyInto(out *RKE2EtcdSnapshotSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotSpec.
+func (in *RKE2EtcdSnapshotSpec) DeepCopy() *RKE2EtcdSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKE2EtcdSnapshotStatus) DeepCopyInto(out *RKE2EtcdSnapshotStatus) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(cluster_apiapiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2EtcdSnapshotStatus.
+func (in *RKE2EtcdSnapshotStatus) DeepCopy() *RKE2EtcdSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RKE2EtcdSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKE2ServerConfig) DeepCopyInto(out *RKE2ServerConfig) {
 	*out = *in
@@ -405,6 +910,18 @@ func (in *RKE2ServerConfig) DeepCopyInto(out *RKE2ServerConfig) {
 		*out = new(corev1.ObjectReference)
 		**out = **in
 	}
+	if in.SecretsEncryption != nil {
+		in, out := &in.SecretsEncryption, &out.SecretsEncryption
+		*out = new(SecretsEncryption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChartValues != nil {
+		in, out := &in.ChartValues, &out.ChartValues
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKE2ServerConfig.
@@ -417,6 +934,32 @@ func (in *RKE2ServerConfig) DeepCopy() *RKE2ServerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationStrategy) DeepCopyInto(out *RemediationStrategy) {
+	*out = *in
+	if in.MaxRetry != nil {
+		in, out := &in.MaxRetry, &out.MaxRetry
+		*out = new(int32)
+		**out = **in
+	}
+	out.RetryPeriod = in.RetryPeriod
+	if in.MinHealthyPeriod != nil {
+		in, out := &in.MinHealthyPeriod, &out.MinHealthyPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationStrategy.
+func (in *RemediationStrategy) DeepCopy() *RemediationStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingUpdate) DeepCopyInto(out *RollingUpdate) {
 	*out = *in
@@ -437,6 +980,70 @@ func (in *RollingUpdate) DeepCopy() *RollingUpdate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutBefore) DeepCopyInto(out *RolloutBefore) {
+	*out = *in
+	if in.CertificatesExpiryDays != nil {
+		in, out := &in.CertificatesExpiryDays, &out.CertificatesExpiryDays
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutBefore.
+func (in *RolloutBefore) DeepCopy() *RolloutBefore {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutBefore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutPausePolicy) DeepCopyInto(out *RolloutPausePolicy) {
+	*out = *in
+	if in.PauseAfter != nil {
+		in, out := &in.PauseAfter, &out.PauseAfter
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutPausePolicy.
+func (in *RolloutPausePolicy) DeepCopy() *RolloutPausePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutPausePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.PendingMachines != nil {
+		in, out := &in.PendingMachines, &out.PendingMachines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
 	*out = *in
@@ -445,6 +1052,16 @@ func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
 		*out = new(RollingUpdate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.InPlace != nil {
+		in, out := &in.InPlace, &out.InPlace
+		*out = new(InPlaceUpgrade)
+		**out = **in
+	}
+	if in.PausePolicy != nil {
+		in, out := &in.PausePolicy, &out.PausePolicy
+		*out = new(RolloutPausePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStrategy.
@@ -456,3 +1073,68 @@ func (in *RolloutStrategy) DeepCopy() *RolloutStrategy {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretsEncryption) DeepCopyInto(out *SecretsEncryption) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EncryptionConfigSecret != nil {
+		in, out := &in.EncryptionConfigSecret, &out.EncryptionConfigSecret
+		*out = new(corev1.ObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsEncryption.
+func (in *SecretsEncryption) DeepCopy() *SecretsEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretsEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretsEncryptionKeyRotationStatus) DeepCopyInto(out *SecretsEncryptionKeyRotationStatus) {
+	*out = *in
+	if in.RestartedNodes != nil {
+		in, out := &in.RestartedNodes, &out.RestartedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsEncryptionKeyRotationStatus.
+func (in *SecretsEncryptionKeyRotationStatus) DeepCopy() *SecretsEncryptionKeyRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretsEncryptionKeyRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TimeWindow) DeepCopyInto(out *TimeWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TimeWindow.
+func (in *TimeWindow) DeepCopy() *TimeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(TimeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
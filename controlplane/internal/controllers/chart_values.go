@@ -0,0 +1,53 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// reconcileChartValues applies the values of the RKE2 packaged charts to the workload cluster, so that changes of
+// the chart values do not require to roll out the control plane machines. The HelmChartConfigs not generated from the
+// chart values are not taken over: they are only reported, so that they do not block the other operations on the control plane.
+func (r *RKE2ControlPlaneReconciler) reconcileChartValues(ctx context.Context, controlPlane *rke2.ControlPlane) error {
+	// Return if RCP is not yet initialized, the first server deploys the chart values of its bootstrap data.
+	if !controlPlane.RCP.Status.Initialized || controlPlane.Machines.Len() == 0 {
+		return nil
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		return errors.Wrap(err, "failed to reconcile chart values: cannot get remote client to workload cluster")
+	}
+
+	err = workloadCluster.ReconcileChartValues(ctx, controlPlane.RCP.Spec.ServerConfig.ChartValues)
+	if errors.Is(err, rke2.ErrUnmanagedHelmChartConfig) {
+		ctrl.LoggerFrom(ctx).Info("Skipping the chart values of HelmChartConfigs not generated from the chart values", "reason", err.Error())
+		r.recorder.Eventf(controlPlane.RCP, corev1.EventTypeWarning, "UnmanagedHelmChartConfig",
+			"Chart values not applied: %s", err)
+
+		return nil
+	}
+
+	return err
+}
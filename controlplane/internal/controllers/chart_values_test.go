@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakeChartValuesWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	chartValues map[string]apiextensionsv1.JSON
+	calls       int
+	err         error
+}
+
+func (f *fakeChartValuesWorkloadCluster) ReconcileChartValues(_ context.Context, chartValues map[string]apiextensionsv1.JSON) error {
+	f.chartValues = chartValues
+	f.calls++
+
+	return f.err
+}
+
+var _ = Describe("Chart values", func() {
+	var (
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeChartValuesWorkloadCluster
+		recorder *record.FakeRecorder
+		rcp      *controlplanev1.RKE2ControlPlane
+		cp       *rke2.ControlPlane
+	)
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				ServerConfig: controlplanev1.RKE2ServerConfig{
+					ChartValues: map[string]apiextensionsv1.JSON{
+						"rke2-coredns": {Raw: []byte(`{"replicaCount":3}`)},
+					},
+				},
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		workload = &fakeChartValuesWorkloadCluster{}
+		recorder = record.NewFakeRecorder(32)
+		r = &RKE2ControlPlaneReconciler{
+			managementCluster: &fakeManagementCluster{workload: workload},
+			recorder:          recorder,
+		}
+		cp = &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(restoreTestMachine("m1", 1)),
+		}
+	})
+
+	It("should apply the chart values to the workload cluster", func() {
+		Expect(r.reconcileChartValues(ctx, cp)).To(Succeed())
+		Expect(workload.calls).To(Equal(1))
+		Expect(workload.chartValues).To(HaveKey("rke2-coredns"))
+	})
+
+	It("should report the HelmChartConfigs not generated from the chart values without failing", func() {
+		workload.err = fmt.Errorf("charts rke2-coredns: %w", rke2.ErrUnmanagedHelmChartConfig)
+
+		Expect(r.reconcileChartValues(ctx, cp)).To(Succeed())
+		Expect(recorder.Events).To(Receive(ContainSubstring("UnmanagedHelmChartConfig")))
+	})
+
+	It("should fail on other errors", func() {
+		workload.err = errors.New("boom")
+
+		Expect(r.reconcileChartValues(ctx, cp)).ToNot(Succeed())
+	})
+
+	It("should wait for the control plane to be initialized", func() {
+		rcp.Status.Initialized = false
+
+		Expect(r.reconcileChartValues(ctx, cp)).To(Succeed())
+		Expect(workload.calls).To(BeZero())
+	})
+})
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/secret"
+	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+// controlPlaneCertificates returns the certificates of the control plane. The certificate authorities of an imported
+// cluster are read from the Secrets provided by the user, instead of being generated.
+func (r *RKE2ControlPlaneReconciler) controlPlaneCertificates(rcp *controlplanev1.RKE2ControlPlane) secret.Certificates {
+	if rcp.Spec.Import == nil {
+		return secret.NewCertificatesForInitialControlPlane()
+	}
+
+	return secret.NewCertificatesForImportedControlPlane(r.Client, client.ObjectKey{
+		Namespace: rcp.Namespace,
+		Name:      rcp.Spec.Import.CertificatesSecretPrefix,
+	})
+}
+
+// reconcileImportedToken stores the server token of an imported cluster, provided by the user, in the token Secret
+// of the cluster, so the machines join the existing servers with it instead of a token generated by the RKE2Config controller.
+func (r *RKE2ControlPlaneReconciler) reconcileImportedToken(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	rcp *controlplanev1.RKE2ControlPlane,
+) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	importedSecret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: rcp.Namespace, Name: rcp.Spec.Import.TokenSecretName}, importedSecret); err != nil {
+		return errors.Wrapf(err, "failed to get the server token Secret %s of the imported cluster", rcp.Spec.Import.TokenSecretName)
+	}
+
+	token := importedSecret.Data["value"]
+	if len(token) == 0 {
+		return errors.Errorf("the server token Secret %s of the imported cluster has no value", rcp.Spec.Import.TokenSecretName)
+	}
+
+	tokenSecret := &corev1.Secret{}
+
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: bsutil.TokenName(cluster.Name)}, tokenSecret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get the server token Secret")
+	}
+
+	if apierrors.IsNotFound(err) {
+		tokenSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bsutil.TokenName(cluster.Name),
+				Namespace: cluster.Namespace,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel: cluster.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       cluster.Name,
+						UID:        cluster.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Data: map[string][]byte{
+				"value": token,
+			},
+			Type: clusterv1.ClusterSecretType,
+		}
+
+		if err := r.Client.Create(ctx, tokenSecret); err != nil {
+			return errors.Wrap(err, "failed to create the server token Secret")
+		}
+
+		logger.Info("Stored the server token of the imported cluster", "secret", tokenSecret.Name)
+
+		return nil
+	}
+
+	if bytes.Equal(tokenSecret.Data["value"], token) {
+		return nil
+	}
+
+	if tokenSecret.Data == nil {
+		tokenSecret.Data = map[string][]byte{}
+	}
+
+	tokenSecret.Data["value"] = token
+
+	if err := r.Client.Update(ctx, tokenSecret); err != nil {
+		return errors.Wrap(err, "failed to update the server token Secret")
+	}
+
+	logger.Info("Updated the server token with the one of the imported cluster", "secret", tokenSecret.Name)
+
+	return nil
+}
+
+// importedServerAddresses returns the addresses of the existing servers of an imported cluster, if any.
+func importedServerAddresses(rcp *controlplanev1.RKE2ControlPlane) []string {
+	if rcp.Spec.Import == nil {
+		return nil
+	}
+
+	return rcp.Spec.Import.ServerAddresses
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/blang/semver/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/certs"
+	"sigs.k8s.io/cluster-api/util/collections"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/secret"
+)
+
+type fakeImportWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	controlPlaneNodes []string
+	keptNodes         []string
+}
+
+func (f *fakeImportWorkloadCluster) ControlPlaneNodeNames(_ context.Context) ([]string, error) {
+	return f.controlPlaneNodes, nil
+}
+
+func (f *fakeImportWorkloadCluster) ReconcileEtcdMembers(_ context.Context, nodeNames []string, _ semver.Version) ([]string, error) {
+	f.keptNodes = nodeNames
+
+	return nil, nil
+}
+
+var _ = Describe("Cluster import", func() {
+	var (
+		cl      client.Client
+		r       *RKE2ControlPlaneReconciler
+		cluster *clusterv1.Cluster
+		rcp     *controlplanev1.RKE2ControlPlane
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		cluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "cluster-uid"}}
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Import: &controlplanev1.RKE2ClusterImport{
+					ServerAddresses:          []string{"10.0.0.1"},
+					TokenSecretName:          "imported-token",
+					CertificatesSecretPrefix: "imported",
+				},
+			},
+		}
+
+		objs := []client.Object{
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "imported-token", Namespace: "default"},
+				Data:       map[string][]byte{"value": []byte("imported-token-value")},
+			},
+		}
+
+		for _, purpose := range []secret.Purpose{secret.ClusterCA, secret.ClientClusterCA, secret.EtcdCA, secret.EtcdServerCA} {
+			objs = append(objs, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: secret.Name("imported", purpose), Namespace: "default"},
+				Data: map[string][]byte{
+					secret.TLSCrtDataName: []byte(string(purpose) + "-crt"),
+					secret.TLSKeyDataName: []byte(string(purpose) + "-key"),
+				},
+			})
+		}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		r = &RKE2ControlPlaneReconciler{Client: cl}
+	})
+
+	It("should store the certificate authorities of the imported cluster", func() {
+		certificates := r.controlPlaneCertificates(rcp)
+		Expect(certificates.LookupOrGenerate(ctx, cl, client.ObjectKeyFromObject(cluster), metav1.OwnerReference{})).To(Succeed())
+
+		for _, purpose := range []secret.Purpose{secret.ClusterCA, secret.ClientClusterCA, secret.EtcdCA, secret.EtcdServerCA} {
+			Expect(certificates.GetByPurpose(purpose).GetKeyPair()).To(Equal(&certs.KeyPair{
+				Cert: []byte(string(purpose) + "-crt"),
+				Key:  []byte(string(purpose) + "-key"),
+			}))
+
+			stored, err := secret.GetFromNamespacedName(ctx, cl, client.ObjectKeyFromObject(cluster), purpose)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored.Data[secret.TLSCrtDataName]).To(Equal([]byte(string(purpose) + "-crt")))
+			Expect(stored.Labels).To(HaveKeyWithValue(secret.ExternalPurposeLabel, string(purpose)))
+		}
+	})
+
+	It("should fail when a certificate authority of the imported cluster is missing", func() {
+		Expect(cl.Delete(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secret.Name("imported", secret.EtcdServerCA), Namespace: "default"},
+		})).To(Succeed())
+
+		certificates := r.controlPlaneCertificates(rcp)
+		Expect(certificates.LookupOrGenerate(ctx, cl, client.ObjectKeyFromObject(cluster), metav1.OwnerReference{})).ToNot(Succeed())
+	})
+
+	It("should store the server token of the imported cluster", func() {
+		Expect(r.reconcileImportedToken(ctx, cluster, rcp)).To(Succeed())
+
+		token := &corev1.Secret{}
+		Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-token"}, token)).To(Succeed())
+		Expect(token.Data).To(HaveKeyWithValue("value", []byte("imported-token-value")))
+		Expect(metav1.GetControllerOf(token).UID).To(Equal(cluster.UID))
+
+		token.Data["value"] = []byte("generated-token-value")
+		Expect(cl.Update(ctx, token)).To(Succeed())
+
+		Expect(r.reconcileImportedToken(ctx, cluster, rcp)).To(Succeed())
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(token), token)).To(Succeed())
+		Expect(token.Data).To(HaveKeyWithValue("value", []byte("imported-token-value")))
+	})
+
+	It("should use the existing servers until control plane machines are ready", func() {
+		Expect(importedServerAddresses(rcp)).To(Equal([]string{"10.0.0.1"}))
+
+		rcp.Spec.Import = nil
+		Expect(importedServerAddresses(rcp)).To(BeNil())
+	})
+
+	It("should keep the etcd members of the existing servers of an imported cluster", func() {
+		workload := &fakeImportWorkloadCluster{controlPlaneNodes: []string{"node-m1", "imported-1"}}
+		r.managementCluster = &fakeManagementCluster{workload: workload}
+		rcp.Spec.Version = "v1.29.3+rke2r1"
+
+		controlPlane := &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  cluster,
+			Machines: collections.FromMachines(restoreTestMachine("m1", 2), restoreTestMachine("m2", 1)),
+		}
+
+		Expect(r.reconcileEtcdMembers(ctx, controlPlane)).To(Succeed())
+		Expect(workload.keptNodes).To(ConsistOf("node-m1", "node-m2", "imported-1"))
+	})
+
+	It("should remove the etcd members of the servers removed from an imported cluster", func() {
+		workload := &fakeImportWorkloadCluster{controlPlaneNodes: []string{"node-m1"}}
+		r.managementCluster = &fakeManagementCluster{workload: workload}
+		rcp.Spec.Version = "v1.29.3+rke2r1"
+
+		controlPlane := &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  cluster,
+			Machines: collections.FromMachines(restoreTestMachine("m1", 1)),
+		}
+
+		Expect(r.reconcileEtcdMembers(ctx, controlPlane)).To(Succeed())
+		Expect(workload.keptNodes).To(ConsistOf("node-m1"))
+	})
+})
@@ -26,4 +26,8 @@ const (
 	// preflightFailedRequeueAfter is how long to wait before trying to scale
 	// up/down if some preflight check for those operation has failed.
 	preflightFailedRequeueAfter = 15 * time.Second
+
+	// inPlaceUpgradeRequeueAfter is how long to wait before checking again the
+	// progress of an in-place upgrade of a control plane machine.
+	inPlaceUpgradeRequeueAfter = 20 * time.Second
 )
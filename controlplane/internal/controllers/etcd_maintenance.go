@@ -0,0 +1,419 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/etcd"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+const (
+	// defaultEtcdMaintenanceInterval is the interval between two checks of the etcd members, when not set in the spec.
+	defaultEtcdMaintenanceInterval = time.Hour
+
+	// defaultEtcdDefragmentThresholdPercent is the percentage of reclaimable database size above which
+	// a member is defragmented, when not set in the spec.
+	defaultEtcdDefragmentThresholdPercent = 50
+
+	// defaultEtcdMinDefragmentDBSize is the database size under which a member is not defragmented, when not set in the spec.
+	defaultEtcdMinDefragmentDBSize = 100 * 1024 * 1024
+
+	// etcdMaintenanceRequeueAfter is how long to wait before checking the etcd members again while maintenance is in progress,
+	// giving time to a defragmented member to catch up with the cluster.
+	etcdMaintenanceRequeueAfter = 30 * time.Second
+)
+
+// reconcileEtcdMaintenance periodically checks the database size and the alarms of the etcd members and maintains them:
+// fragmented members are defragmented one at a time, followers first, and NOSPACE alarms are disarmed once space
+// has been reclaimed by compaction and defragmentation. CORRUPT alarms are only surfaced, as the member must be remediated.
+// It returns a non-zero result when etcd was acted on, so that the control plane is not changed any further until
+// the etcd members are checked again.
+func (r *RKE2ControlPlaneReconciler) reconcileEtcdMaintenance(ctx context.Context, controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+
+	if rcp.Spec.EtcdMaintenance == nil {
+		rcp.Status.EtcdMembers = nil
+		rcp.Status.LastEtcdMaintenanceTime = nil
+		conditions.Delete(rcp, controlplanev1.EtcdMaintenanceCondition)
+
+		return ctrl.Result{}, nil
+	}
+
+	// Return if RCP is not yet initialized (no etcd cluster to maintain).
+	if controlPlane.Machines.Len() == 0 || !rcp.Status.Initialized {
+		return ctrl.Result{}, nil
+	}
+
+	nodeNames := []string{}
+
+	for _, machine := range controlPlane.Machines {
+		// Do not maintain etcd while members are joining or leaving the cluster.
+		if machine.Status.NodeRef == nil || !machine.DeletionTimestamp.IsZero() {
+			return ctrl.Result{}, nil
+		}
+
+		nodeNames = append(nodeNames, machine.Status.NodeRef.Name)
+	}
+
+	sort.Strings(nodeNames)
+
+	now := time.Now()
+	if !etcdMaintenanceDue(rcp, now) {
+		return ctrl.Result{}, nil
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "cannot get remote client to workload cluster")
+	}
+
+	rcp.Status.LastEtcdMaintenanceTime = &metav1.Time{Time: now}
+
+	members, err := workloadCluster.EtcdMemberDBStatuses(ctx, nodeNames)
+	if err != nil {
+		logger.Info("Failed to check etcd members, skipping etcd maintenance", "error", err.Error())
+		conditions.MarkFalse(rcp,
+			controlplanev1.EtcdMaintenanceCondition,
+			controlplanev1.EtcdMaintenanceFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"Failed to check etcd members: %v", err)
+
+		return ctrl.Result{}, nil
+	}
+
+	updateEtcdMembersStatus(rcp, members)
+
+	result, err := r.maintainEtcd(ctx, controlPlane, workloadCluster, members)
+	if err != nil {
+		conditions.MarkFalse(rcp,
+			controlplanev1.EtcdMaintenanceCondition,
+			controlplanev1.EtcdMaintenanceFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			err.Error())
+
+		return ctrl.Result{}, errors.Wrap(err, "failed to maintain etcd")
+	}
+
+	return result, nil
+}
+
+func (r *RKE2ControlPlaneReconciler) maintainEtcd(
+	ctx context.Context,
+	controlPlane *rke2.ControlPlane,
+	workloadCluster rke2.WorkloadCluster,
+	members []rke2.EtcdMemberDBStatus,
+) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+
+	// Defragmentation and compaction do not help with a corrupted member, which must be replaced.
+	for _, member := range members {
+		if member.HasAlarm(etcd.AlarmCorrupt) {
+			conditions.MarkFalse(rcp,
+				controlplanev1.EtcdMaintenanceCondition,
+				controlplanev1.EtcdCorruptAlarmReason,
+				clusterv1.ConditionSeverityError,
+				"Etcd member on node %s raised a CORRUPT alarm, its machine must be remediated", member.NodeName)
+			r.recorder.Eventf(rcp, corev1.EventTypeWarning, "EtcdCorruptAlarm",
+				"Etcd member on node %s raised a CORRUPT alarm", member.NodeName)
+
+			return ctrl.Result{}, nil
+		}
+	}
+
+	noSpace := []rke2.EtcdMemberDBStatus{}
+
+	for _, member := range members {
+		if member.HasAlarm(etcd.AlarmNoSpace) {
+			noSpace = append(noSpace, member)
+		}
+	}
+
+	// Compaction releases the history of the keys, so that defragmentation can reclaim the space it used.
+	// The members are checked again, as compaction changes the size in use of their database.
+	if len(noSpace) > 0 {
+		revision := int64(0)
+
+		for _, member := range members {
+			if member.Revision > revision {
+				revision = member.Revision
+			}
+		}
+
+		logger.Info("Compacting etcd after NOSPACE alarm", "revision", revision)
+
+		if err := workloadCluster.CompactEtcd(ctx, noSpace[0].NodeName, revision); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		nodeNames := make([]string, 0, len(members))
+		for _, member := range members {
+			nodeNames = append(nodeNames, member.NodeName)
+		}
+
+		var err error
+
+		members, err = workloadCluster.EtcdMemberDBStatuses(ctx, nodeNames)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		updateEtcdMembersStatus(rcp, members)
+	}
+
+	var (
+		leader    *rke2.EtcdMemberDBStatus
+		followers []rke2.EtcdMemberDBStatus
+		toDefrag  []rke2.EtcdMemberDBStatus
+	)
+
+	for i := range members {
+		member := members[i]
+
+		if member.Leader {
+			leader = &members[i]
+		} else {
+			followers = append(followers, member)
+		}
+
+		if etcdMemberNeedsDefragment(rcp.Spec.EtcdMaintenance, member.DBSize, member.DBSizeInUse, len(noSpace) > 0) {
+			toDefrag = append(toDefrag, member)
+		}
+	}
+
+	if len(toDefrag) > 0 {
+		// Followers are defragmented first, the leader is defragmented last, after its leadership has been
+		// moved to an already defragmented follower.
+		sort.SliceStable(toDefrag, func(i, j int) bool { return !toDefrag[i].Leader && toDefrag[j].Leader })
+		member := toDefrag[0]
+
+		if member.Leader && len(followers) > 0 {
+			return r.forwardEtcdLeadershipForDefragment(ctx, controlPlane, workloadCluster, leader, followers[0])
+		}
+
+		conditions.MarkFalse(rcp,
+			controlplanev1.EtcdMaintenanceCondition,
+			controlplanev1.EtcdDefragmentingReason,
+			clusterv1.ConditionSeverityInfo,
+			"Defragmenting etcd member on node %s (%d of %d members to defragment)", member.NodeName, len(toDefrag), len(members))
+
+		if err := workloadCluster.DefragmentEtcdMember(ctx, member.NodeName); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		for i := range rcp.Status.EtcdMembers {
+			if rcp.Status.EtcdMembers[i].NodeName == member.NodeName {
+				rcp.Status.EtcdMembers[i].LastDefragmentTime = &metav1.Time{Time: time.Now()}
+			}
+		}
+
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "EtcdMemberDefragmented",
+			"Defragmented etcd member on node %s, reclaiming %s", member.NodeName,
+			resource.NewQuantity(member.DBSize-member.DBSizeInUse, resource.BinarySI))
+
+		return ctrl.Result{RequeueAfter: etcdMaintenanceRequeueAfter}, nil
+	}
+
+	if len(noSpace) > 0 {
+		for _, member := range noSpace {
+			if err := workloadCluster.DisarmEtcdAlarm(ctx, member.NodeName, etcd.AlarmNoSpace); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			r.recorder.Eventf(rcp, corev1.EventTypeNormal, "EtcdAlarmDisarmed",
+				"Disarmed NOSPACE alarm of etcd member on node %s", member.NodeName)
+		}
+
+		conditions.MarkFalse(rcp,
+			controlplanev1.EtcdMaintenanceCondition,
+			controlplanev1.EtcdNoSpaceAlarmReason,
+			clusterv1.ConditionSeverityWarning,
+			"Disarmed NOSPACE alarm of %d etcd members, waiting to check them again", len(noSpace))
+
+		return ctrl.Result{RequeueAfter: etcdMaintenanceRequeueAfter}, nil
+	}
+
+	conditions.MarkTrue(rcp, controlplanev1.EtcdMaintenanceCondition)
+
+	return ctrl.Result{}, nil
+}
+
+// forwardEtcdLeadershipForDefragment moves the etcd leadership away from the leader, so that it can be defragmented
+// without blocking the cluster.
+func (r *RKE2ControlPlaneReconciler) forwardEtcdLeadershipForDefragment(
+	ctx context.Context,
+	controlPlane *rke2.ControlPlane,
+	workloadCluster rke2.WorkloadCluster,
+	leader *rke2.EtcdMemberDBStatus,
+	candidate rke2.EtcdMemberDBStatus,
+) (ctrl.Result, error) {
+	var leaderMachine, candidateMachine *clusterv1.Machine
+
+	for _, machine := range controlPlane.Machines {
+		switch machine.Status.NodeRef.Name {
+		case leader.NodeName:
+			leaderMachine = machine
+		case candidate.NodeName:
+			candidateMachine = machine
+		}
+	}
+
+	if leaderMachine == nil || candidateMachine == nil {
+		return ctrl.Result{}, errors.Errorf("failed to find the machines of etcd members on nodes %s and %s", leader.NodeName, candidate.NodeName)
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Moving etcd leadership before defragmenting the leader",
+		"leader", leader.NodeName, "candidate", candidate.NodeName)
+
+	if err := workloadCluster.ForwardEtcdLeadership(ctx, leaderMachine, candidateMachine); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to move etcd leadership before defragmentation")
+	}
+
+	conditions.MarkFalse(controlPlane.RCP,
+		controlplanev1.EtcdMaintenanceCondition,
+		controlplanev1.EtcdDefragmentingReason,
+		clusterv1.ConditionSeverityInfo,
+		"Moved etcd leadership from node %s to node %s to defragment it", leader.NodeName, candidate.NodeName)
+
+	return ctrl.Result{RequeueAfter: etcdMaintenanceRequeueAfter}, nil
+}
+
+// updateEtcdMembersStatus reports the etcd members database size and alarms in the status.
+func updateEtcdMembersStatus(rcp *controlplanev1.RKE2ControlPlane, members []rke2.EtcdMemberDBStatus) {
+	lastDefragmentTimes := map[string]*metav1.Time{}
+	for _, member := range rcp.Status.EtcdMembers {
+		lastDefragmentTimes[member.NodeName] = member.LastDefragmentTime
+	}
+
+	rcp.Status.EtcdMembers = make([]controlplanev1.EtcdMemberStatus, 0, len(members))
+
+	for _, member := range members {
+		status := controlplanev1.EtcdMemberStatus{
+			NodeName:           member.NodeName,
+			Leader:             member.Leader,
+			DBSize:             *resource.NewQuantity(member.DBSize, resource.BinarySI),
+			DBSizeInUse:        *resource.NewQuantity(member.DBSizeInUse, resource.BinarySI),
+			LastDefragmentTime: lastDefragmentTimes[member.NodeName],
+		}
+
+		for _, alarm := range member.Alarms {
+			status.Alarms = append(status.Alarms, etcd.AlarmTypeName[alarm])
+		}
+
+		rcp.Status.EtcdMembers = append(rcp.Status.EtcdMembers, status)
+	}
+}
+
+// etcdMaintenanceDue returns true if the etcd members must be checked: once per interval, or more often
+// while the last check found members to defragment or alarms to handle.
+func etcdMaintenanceDue(rcp *controlplanev1.RKE2ControlPlane, now time.Time) bool {
+	last := rcp.Status.LastEtcdMaintenanceTime
+	if last == nil {
+		return true
+	}
+
+	if etcdMaintenancePending(rcp) {
+		return !now.Before(last.Add(etcdMaintenanceRequeueAfter))
+	}
+
+	return !now.Before(last.Add(etcdMaintenanceInterval(rcp.Spec.EtcdMaintenance)))
+}
+
+// nextEtcdMaintenance returns how long to wait before the etcd members must be checked again, if etcd maintenance is enabled.
+func nextEtcdMaintenance(rcp *controlplanev1.RKE2ControlPlane, now time.Time) time.Duration {
+	if rcp.Spec.EtcdMaintenance == nil || rcp.Status.LastEtcdMaintenanceTime == nil {
+		return 0
+	}
+
+	wait := etcdMaintenanceInterval(rcp.Spec.EtcdMaintenance)
+	if etcdMaintenancePending(rcp) {
+		wait = etcdMaintenanceRequeueAfter
+	}
+
+	if next := rcp.Status.LastEtcdMaintenanceTime.Add(wait).Sub(now); next > 0 {
+		return next
+	}
+
+	return etcdMaintenanceRequeueAfter
+}
+
+// etcdMaintenancePending returns true if the last check of the etcd members found members to defragment or alarms to handle.
+func etcdMaintenancePending(rcp *controlplanev1.RKE2ControlPlane) bool {
+	alarmed, noSpace := false, false
+
+	for _, member := range rcp.Status.EtcdMembers {
+		for _, alarm := range member.Alarms {
+			alarmed = true
+			noSpace = noSpace || alarm == etcd.AlarmTypeName[etcd.AlarmNoSpace]
+		}
+	}
+
+	for _, member := range rcp.Status.EtcdMembers {
+		if etcdMemberNeedsDefragment(rcp.Spec.EtcdMaintenance, member.DBSize.Value(), member.DBSizeInUse.Value(), noSpace) {
+			return true
+		}
+	}
+
+	return alarmed
+}
+
+// etcdMemberNeedsDefragment returns true if enough of the member database can be reclaimed by defragmentation.
+// The minimum database size is ignored while a NOSPACE alarm is raised.
+func etcdMemberNeedsDefragment(maintenance *controlplanev1.EtcdMaintenance, dbSize, dbSizeInUse int64, noSpace bool) bool {
+	if dbSize <= 0 {
+		return false
+	}
+
+	thresholdPercent := int64(defaultEtcdDefragmentThresholdPercent)
+	if maintenance.DefragmentThresholdPercent != nil {
+		thresholdPercent = int64(*maintenance.DefragmentThresholdPercent)
+	}
+
+	minDBSize := int64(defaultEtcdMinDefragmentDBSize)
+	if maintenance.MinDefragmentDBSize != nil {
+		minDBSize = maintenance.MinDefragmentDBSize.Value()
+	}
+
+	if !noSpace && dbSize < minDBSize {
+		return false
+	}
+
+	return (dbSize-dbSizeInUse)*100/dbSize >= thresholdPercent //nolint:gomnd
+}
+
+func etcdMaintenanceInterval(maintenance *controlplanev1.EtcdMaintenance) time.Duration {
+	if maintenance.Interval == nil || maintenance.Interval.Duration <= 0 {
+		return defaultEtcdMaintenanceInterval
+	}
+
+	return maintenance.Interval.Duration
+}
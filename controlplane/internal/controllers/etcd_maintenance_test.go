@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/etcd"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+const mebibyte = 1024 * 1024
+
+type fakeMaintenanceWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	members    map[string]*rke2.EtcdMemberDBStatus
+	operations []string
+	compacted  bool
+}
+
+func (f *fakeMaintenanceWorkloadCluster) EtcdMemberDBStatuses(_ context.Context, nodeNames []string) ([]rke2.EtcdMemberDBStatus, error) {
+	statuses := []rke2.EtcdMemberDBStatus{}
+	for _, nodeName := range nodeNames {
+		statuses = append(statuses, *f.members[nodeName])
+	}
+
+	return statuses, nil
+}
+
+func (f *fakeMaintenanceWorkloadCluster) CompactEtcd(_ context.Context, _ string, _ int64) error {
+	f.operations = append(f.operations, "compact")
+
+	if !f.compacted {
+		for _, member := range f.members {
+			member.DBSizeInUse /= 4
+		}
+	}
+
+	f.compacted = true
+
+	return nil
+}
+
+func (f *fakeMaintenanceWorkloadCluster) DefragmentEtcdMember(_ context.Context, nodeName string) error {
+	f.operations = append(f.operations, "defragment "+nodeName)
+	f.members[nodeName].DBSize = f.members[nodeName].DBSizeInUse
+
+	return nil
+}
+
+func (f *fakeMaintenanceWorkloadCluster) DisarmEtcdAlarm(_ context.Context, nodeName string, _ etcd.AlarmType) error {
+	f.operations = append(f.operations, "disarm "+nodeName)
+	f.members[nodeName].Alarms = nil
+
+	return nil
+}
+
+func (f *fakeMaintenanceWorkloadCluster) ForwardEtcdLeadership(_ context.Context, machine, leaderCandidate *clusterv1.Machine) error {
+	f.operations = append(f.operations, "move leader to "+leaderCandidate.Status.NodeRef.Name)
+	f.members[machine.Status.NodeRef.Name].Leader = false
+	f.members[leaderCandidate.Status.NodeRef.Name].Leader = true
+
+	return nil
+}
+
+var _ = Describe("Etcd maintenance", func() {
+	var (
+		r            *RKE2ControlPlaneReconciler
+		rcp          *controlplanev1.RKE2ControlPlane
+		workload     *fakeMaintenanceWorkloadCluster
+		controlPlane *rke2.ControlPlane
+	)
+
+	member := func(nodeName string, leader bool, dbSize, dbSizeInUse int64) *rke2.EtcdMemberDBStatus {
+		return &rke2.EtcdMemberDBStatus{NodeName: nodeName, Leader: leader, DBSize: dbSize, DBSizeInUse: dbSizeInUse}
+	}
+
+	// reconcile runs the etcd maintenance as if the interval to check the members again had elapsed.
+	reconcile := func() ctrl.Result {
+		rcp.Status.LastEtcdMaintenanceTime = nil
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+
+		return result
+	}
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Replicas:        ptr.To[int32](3),
+				EtcdMaintenance: &controlplanev1.EtcdMaintenance{},
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		workload = &fakeMaintenanceWorkloadCluster{members: map[string]*rke2.EtcdMemberDBStatus{
+			"node-m1": member("node-m1", true, 400*mebibyte, 100*mebibyte),
+			"node-m2": member("node-m2", false, 400*mebibyte, 100*mebibyte),
+			"node-m3": member("node-m3", false, 400*mebibyte, 300*mebibyte),
+		}}
+		r = &RKE2ControlPlaneReconciler{
+			managementCluster: &fakeManagementCluster{workload: workload},
+			recorder:          record.NewFakeRecorder(32),
+		}
+		controlPlane = &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(restoreTestMachine("m1", 3), restoreTestMachine("m2", 2), restoreTestMachine("m3", 1)),
+		}
+	})
+
+	It("should defragment fragmented members one at a time, the leader last", func() {
+		Expect(reconcile().RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		Expect(workload.operations).To(Equal([]string{"defragment node-m2"}))
+		Expect(conditions.GetReason(rcp, controlplanev1.EtcdMaintenanceCondition)).To(Equal(controlplanev1.EtcdDefragmentingReason))
+		Expect(rcp.Status.EtcdMembers).To(HaveLen(3))
+		Expect(rcp.Status.EtcdMembers[1].LastDefragmentTime).ToNot(BeNil())
+
+		Expect(reconcile().RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		Expect(workload.operations).To(Equal([]string{"defragment node-m2", "move leader to node-m2"}))
+
+		Expect(reconcile().RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+		Expect(workload.operations).To(Equal([]string{"defragment node-m2", "move leader to node-m2", "defragment node-m1"}))
+		Expect(conditions.IsTrue(rcp, controlplanev1.EtcdMaintenanceCondition)).To(BeTrue())
+		Expect(rcp.Status.EtcdMembers[0].DBSize.Value()).To(Equal(int64(100 * mebibyte)))
+		Expect(rcp.Status.EtcdMembers[1].Leader).To(BeTrue())
+	})
+
+	It("should compact, defragment and disarm a NOSPACE alarm", func() {
+		workload.members["node-m1"] = member("node-m1", true, 50*mebibyte, 40*mebibyte)
+		workload.members["node-m2"] = member("node-m2", false, 50*mebibyte, 40*mebibyte)
+		workload.members["node-m2"].Alarms = []etcd.AlarmType{etcd.AlarmNoSpace}
+		workload.members["node-m3"] = member("node-m3", false, 50*mebibyte, 40*mebibyte)
+
+		Expect(reconcile().RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		Expect(workload.operations).To(Equal([]string{"compact", "defragment node-m2"}))
+		Expect(rcp.Status.EtcdMembers[1].Alarms).To(Equal([]string{"NOSPACE"}))
+
+		for i := 0; i < 4; i++ {
+			Expect(reconcile().RequeueAfter).To(Equal(etcdMaintenanceRequeueAfter))
+		}
+
+		Expect(workload.operations).To(Equal([]string{
+			"compact", "defragment node-m2",
+			"compact", "defragment node-m3",
+			"compact", "move leader to node-m2",
+			"compact", "defragment node-m1",
+			"compact", "disarm node-m2",
+		}))
+		Expect(conditions.GetReason(rcp, controlplanev1.EtcdMaintenanceCondition)).To(Equal(controlplanev1.EtcdNoSpaceAlarmReason))
+
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+		Expect(conditions.IsTrue(rcp, controlplanev1.EtcdMaintenanceCondition)).To(BeTrue())
+	})
+
+	It("should only surface a CORRUPT alarm", func() {
+		workload.members["node-m3"].Alarms = []etcd.AlarmType{etcd.AlarmCorrupt}
+
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+		Expect(workload.operations).To(BeEmpty())
+		Expect(conditions.GetReason(rcp, controlplanev1.EtcdMaintenanceCondition)).To(Equal(controlplanev1.EtcdCorruptAlarmReason))
+		Expect(conditions.GetSeverity(rcp, controlplanev1.EtcdMaintenanceCondition)).To(Equal(ptr.To(clusterv1.ConditionSeverityError)))
+	})
+
+	It("should check the members once per interval", func() {
+		workload.members["node-m1"].DBSize = 100 * mebibyte
+		workload.members["node-m2"].DBSize = 100 * mebibyte
+
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+		Expect(conditions.IsTrue(rcp, controlplanev1.EtcdMaintenanceCondition)).To(BeTrue())
+
+		now := time.Now()
+		Expect(nextEtcdMaintenance(rcp, now)).To(BeNumerically("~", time.Hour, time.Minute))
+
+		workload.members["node-m1"].DBSize = 400 * mebibyte
+
+		result, err := r.reconcileEtcdMaintenance(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(workload.operations).To(BeEmpty())
+	})
+
+	It("should not maintain etcd while a machine is provisioning", func() {
+		controlPlane.Machines["m3"].Status.NodeRef = nil
+
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+		Expect(rcp.Status.EtcdMembers).To(BeEmpty())
+	})
+
+	It("should clear the status when etcd maintenance is disabled", func() {
+		reconcile()
+		Expect(rcp.Status.EtcdMembers).ToNot(BeEmpty())
+
+		rcp.Spec.EtcdMaintenance = nil
+
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+		Expect(rcp.Status.EtcdMembers).To(BeNil())
+		Expect(conditions.Has(rcp, controlplanev1.EtcdMaintenanceCondition)).To(BeFalse())
+	})
+})
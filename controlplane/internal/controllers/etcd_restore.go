@@ -0,0 +1,448 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+const (
+	// etcdRestoreRequeueAfter is how long to wait before checking again the progress of an etcd snapshot restore.
+	etcdRestoreRequeueAfter = 20 * time.Second
+
+	// etcdRestoreTimeout is how long to wait for the cluster reset to complete on the node before failing the restore.
+	etcdRestoreTimeout = 30 * time.Minute
+
+	localSnapshotLocationPrefix = "file://"
+	s3SnapshotLocationPrefix    = "s3://"
+)
+
+// reconcileEtcdSnapshotRestore drives the restore of an etcd snapshot requested for the cluster through a RKE2EtcdSnapshotRestore:
+// the control plane is scaled down to a single machine, etcd is reset from the snapshot on it, and fresh machines
+// are joined to the restored etcd cluster by the normal reconciliation.
+// It returns a non-zero result as long as the normal reconciliation of the control plane must be paused.
+func (r *RKE2ControlPlaneReconciler) reconcileEtcdSnapshotRestore(ctx context.Context, controlPlane *rke2.ControlPlane) (res ctrl.Result, reterr error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	restore, err := r.getActiveEtcdSnapshotRestore(ctx, controlPlane.Cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if restore == nil {
+		return ctrl.Result{}, nil
+	}
+
+	logger = logger.WithValues("RKE2EtcdSnapshotRestore", klog.KObj(restore))
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	patchHelper, err := patch.NewHelper(restore, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to create patch helper")
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, restore, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			controlplanev1.EtcdSnapshotRestoredCondition,
+		}}); err != nil {
+			logger.Error(err, "Failed to patch RKE2EtcdSnapshotRestore")
+			reterr = kerrors.NewAggregate([]error{reterr, err})
+		}
+	}()
+
+	restore.OwnerReferences = util.EnsureOwnerRef(restore.OwnerReferences, metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       controlPlane.Cluster.Name,
+		UID:        controlPlane.Cluster.UID,
+	})
+
+	switch restore.Status.Phase {
+	case "", controlplanev1.RKE2EtcdSnapshotRestorePhasePending:
+		return r.startEtcdSnapshotRestore(ctx, controlPlane, restore)
+	case controlplanev1.RKE2EtcdSnapshotRestorePhaseScalingDown:
+		return r.scaleDownForEtcdSnapshotRestore(ctx, controlPlane, restore)
+	case controlplanev1.RKE2EtcdSnapshotRestorePhaseRestoring:
+		return r.resetEtcdFromSnapshot(ctx, controlPlane, restore)
+	case controlplanev1.RKE2EtcdSnapshotRestorePhaseRejoining:
+		return r.rejoinMachinesAfterEtcdSnapshotRestore(ctx, controlPlane, restore)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getActiveEtcdSnapshotRestore returns the oldest unfinished RKE2EtcdSnapshotRestore of the cluster, if any.
+func (r *RKE2ControlPlaneReconciler) getActiveEtcdSnapshotRestore(
+	ctx context.Context, cluster *clusterv1.Cluster,
+) (*controlplanev1.RKE2EtcdSnapshotRestore, error) {
+	restoreList := &controlplanev1.RKE2EtcdSnapshotRestoreList{}
+	if err := r.Client.List(ctx, restoreList, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "failed to list etcd snapshot restores")
+	}
+
+	restores := []*controlplanev1.RKE2EtcdSnapshotRestore{}
+
+	for i := range restoreList.Items {
+		restore := &restoreList.Items[i]
+		if restore.Spec.ClusterName == cluster.Name && !restore.IsFinished() && restore.DeletionTimestamp.IsZero() {
+			restores = append(restores, restore)
+		}
+	}
+
+	if len(restores) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(restores, func(i, j int) bool {
+		return restores[i].CreationTimestamp.Before(&restores[j].CreationTimestamp)
+	})
+
+	return restores[0], nil
+}
+
+// startEtcdSnapshotRestore resolves the snapshot to restore and the control plane machine to restore it on.
+func (r *RKE2ControlPlaneReconciler) startEtcdSnapshotRestore(
+	ctx context.Context, controlPlane *rke2.ControlPlane, restore *controlplanev1.RKE2EtcdSnapshotRestore,
+) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	restore.Status.Phase = controlplanev1.RKE2EtcdSnapshotRestorePhasePending
+
+	if !controlPlane.RCP.Status.Initialized {
+		conditions.MarkFalse(restore, controlplanev1.EtcdSnapshotRestoredCondition,
+			controlplanev1.WaitingForControlPlaneReason, clusterv1.ConditionSeverityInfo, "")
+
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	snapshotName, s3, machineName := restore.Spec.SnapshotName, restore.Spec.S3, restore.Spec.MachineName
+
+	if restore.Spec.EtcdSnapshotName != "" {
+		snapshot := &controlplanev1.RKE2EtcdSnapshot{}
+
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.EtcdSnapshotName}, snapshot)
+		if apierrors.IsNotFound(err) {
+			r.failEtcdSnapshotRestore(restore, "RKE2EtcdSnapshot %s not found", restore.Spec.EtcdSnapshotName)
+
+			return ctrl.Result{}, nil
+		} else if err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to get RKE2EtcdSnapshot %s", restore.Spec.EtcdSnapshotName)
+		}
+
+		if snapshot.Spec.ClusterName != restore.Spec.ClusterName || snapshot.Status.Phase != controlplanev1.RKE2EtcdSnapshotPhaseSucceeded {
+			r.failEtcdSnapshotRestore(restore, "RKE2EtcdSnapshot %s is not a successful snapshot of cluster %s",
+				snapshot.Name, restore.Spec.ClusterName)
+
+			return ctrl.Result{}, nil
+		}
+
+		snapshotName = snapshot.Status.SnapshotName
+		s3 = strings.HasPrefix(snapshot.Status.Location, s3SnapshotLocationPrefix)
+
+		// Local snapshots are only available on the node they were taken on.
+		if !s3 {
+			if strings.HasPrefix(snapshot.Status.Location, localSnapshotLocationPrefix) {
+				snapshotName = strings.TrimPrefix(snapshot.Status.Location, localSnapshotLocationPrefix)
+			}
+
+			machine := controlPlane.Machines.Filter(func(machine *clusterv1.Machine) bool {
+				return machine.Status.NodeRef != nil && machine.Status.NodeRef.Name == snapshot.Status.NodeName
+			}).Oldest()
+			if machine == nil {
+				r.failEtcdSnapshotRestore(restore, "Node %s holding the snapshot is no longer part of the control plane", snapshot.Status.NodeName)
+
+				return ctrl.Result{}, nil
+			}
+
+			machineName = machine.Name
+		}
+	}
+
+	if snapshotName == "" {
+		r.failEtcdSnapshotRestore(restore, "Either etcdSnapshotName or snapshotName must be set")
+
+		return ctrl.Result{}, nil
+	}
+
+	var machine *clusterv1.Machine
+
+	if machineName != "" {
+		machine = controlPlane.Machines[machineName]
+		if machine == nil || machine.Status.NodeRef == nil {
+			r.failEtcdSnapshotRestore(restore, "Machine %s is not a control plane machine with a node", machineName)
+
+			return ctrl.Result{}, nil
+		}
+	} else {
+		machine = controlPlane.Machines.Filter(collections.HasNode(), collections.IsReady()).Oldest()
+		if machine == nil {
+			logger.Info("Waiting for a healthy control plane machine to restore the etcd snapshot on")
+			conditions.MarkFalse(restore, controlplanev1.EtcdSnapshotRestoredCondition,
+				controlplanev1.WaitingForControlPlaneReason, clusterv1.ConditionSeverityInfo, "No control plane machine available")
+
+			return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+		}
+	}
+
+	restore.Status.SnapshotName = snapshotName
+	restore.Status.S3 = s3
+	restore.Status.MachineName = machine.Name
+	restore.Status.NodeName = machine.Status.NodeRef.Name
+	restore.Status.Phase = controlplanev1.RKE2EtcdSnapshotRestorePhaseScalingDown
+	conditions.MarkFalse(restore, controlplanev1.EtcdSnapshotRestoredCondition, controlplanev1.EtcdRestoreScalingDownReason,
+		clusterv1.ConditionSeverityInfo, "Scaling down control plane to Machine %s", machine.Name)
+
+	logger.Info("Starting etcd snapshot restore", "snapshot", snapshotName, "machine", machine.Name)
+	r.recorder.Eventf(restore, corev1.EventTypeNormal, "RestoreStarted",
+		"Restoring etcd snapshot %s on control plane Machine %s", snapshotName, machine.Name)
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// scaleDownForEtcdSnapshotRestore deletes, one at a time, the control plane machines other than the one the snapshot is restored on.
+// Etcd members are removed on a best effort basis, as a restore is often needed because etcd lost quorum, once the
+// machines are drained when they carry the pre-terminate hook.
+func (r *RKE2ControlPlaneReconciler) scaleDownForEtcdSnapshotRestore(
+	ctx context.Context, controlPlane *rke2.ControlPlane, restore *controlplanev1.RKE2EtcdSnapshotRestore,
+) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	machine := controlPlane.Machines[restore.Status.MachineName]
+	if machine == nil || !machine.DeletionTimestamp.IsZero() {
+		r.failEtcdSnapshotRestore(restore, "Machine %s was deleted while restoring the etcd snapshot", restore.Status.MachineName)
+
+		return ctrl.Result{}, nil
+	}
+
+	otherMachines := controlPlane.Machines.Filter(func(m *clusterv1.Machine) bool {
+		return m.Name != machine.Name
+	})
+
+	if otherMachines.Len() == 0 {
+		restore.Status.Phase = controlplanev1.RKE2EtcdSnapshotRestorePhaseRestoring
+		conditions.MarkFalse(restore, controlplanev1.EtcdSnapshotRestoredCondition, controlplanev1.EtcdRestoreInProgressReason,
+			clusterv1.ConditionSeverityInfo, "Restoring snapshot on node %s", restore.Status.NodeName)
+
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if controlPlane.HasDeletingMachine() {
+		// The pre-terminate hook is not reconciled while the restore pauses the normal reconciliation, so the etcd member
+		// of a drained machine is removed here before removing its hook, which lets the machine deletion proceed.
+		for _, deletingMachine := range otherMachines.Filter(collections.HasDeletionTimestamp) {
+			if !isWaitingForPreTerminateHook(deletingMachine) {
+				continue
+			}
+
+			r.removeEtcdMemberForEtcdSnapshotRestore(ctx, controlPlane, deletingMachine, machine)
+
+			if err := r.removePreTerminateHook(ctx, deletingMachine); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		logger.Info("Waiting for machines to be deleted before restoring the etcd snapshot",
+			"machines", strings.Join(otherMachines.Filter(collections.HasDeletionTimestamp).Names(), ", "))
+
+		return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+	}
+
+	machineToDelete := otherMachines.Newest()
+
+	// The etcd member of a machine with the pre-terminate hook is removed once the machine is drained.
+	if _, ok := machineToDelete.Annotations[controlplanev1.PreTerminateHookCleanupAnnotation]; !ok {
+		r.removeEtcdMemberForEtcdSnapshotRestore(ctx, controlPlane, machineToDelete, machine)
+	}
+
+	logger.Info("Deleting control plane machine before restoring the etcd snapshot", "machine", machineToDelete.Name)
+
+	if err := r.Client.Delete(ctx, machineToDelete); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to delete control plane Machine %s", machineToDelete.Name)
+	}
+
+	return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+}
+
+// removeEtcdMemberForEtcdSnapshotRestore forwards the etcd leadership to the restored machine and removes the etcd member
+// of a machine deleted for the restore, on a best effort basis.
+func (r *RKE2ControlPlaneReconciler) removeEtcdMemberForEtcdSnapshotRestore(
+	ctx context.Context, controlPlane *rke2.ControlPlane, machineToDelete, restoredMachine *clusterv1.Machine,
+) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		logger.Error(err, "Failed to get workload cluster, deleting machine without removing its etcd member")
+
+		return
+	}
+
+	if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToDelete, restoredMachine); err != nil {
+		logger.Error(err, "Failed to move etcd leadership to the restored machine")
+	}
+
+	if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToDelete); err != nil {
+		logger.Error(err, "Failed to remove etcd member for machine", "machine", machineToDelete.Name)
+	}
+}
+
+// resetEtcdFromSnapshot runs the cluster reset from the snapshot on the remaining control plane machine
+// and waits for etcd to come back as a single member cluster.
+func (r *RKE2ControlPlaneReconciler) resetEtcdFromSnapshot(
+	ctx context.Context, controlPlane *rke2.ControlPlane, restore *controlplanev1.RKE2EtcdSnapshotRestore,
+) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		// The API server is not available while RKE2 restarts from the restored snapshot.
+		logger.Info("Waiting for the workload cluster to be reachable", "error", err.Error())
+
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	if restore.Status.RestoreStartTime == nil {
+		if err := workloadCluster.StartEtcdSnapshotRestore(ctx, string(restore.UID), restore.Status.NodeName, restore.Status.SnapshotName,
+			restore.Status.S3, upgradeImage(controlPlane.RCP)); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		restore.Status.RestoreStartTime = ptr.To(metav1.Now())
+
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	phase, message, err := workloadCluster.GetEtcdSnapshotRestorePhase(ctx, string(restore.UID))
+	if err != nil {
+		logger.Info("Waiting for the etcd snapshot restore to complete", "error", err.Error())
+
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	switch phase {
+	case rke2.EtcdSnapshotFailed:
+		r.failEtcdSnapshotRestore(restore, "Cluster reset on node %s failed: %s", restore.Status.NodeName, message)
+
+		return ctrl.Result{}, nil
+	case rke2.EtcdSnapshotRunning:
+		if time.Since(restore.Status.RestoreStartTime.Time) > etcdRestoreTimeout {
+			r.failEtcdSnapshotRestore(restore, "Cluster reset on node %s did not complete within %s", restore.Status.NodeName, etcdRestoreTimeout)
+
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	// Verify through etcd that the cluster was reset to a single member before joining new machines.
+	members, err := workloadCluster.EtcdMembers(ctx)
+	if err != nil || len(members) != 1 || !strings.Contains(members[0], restore.Status.NodeName) {
+		logger.Info("Waiting for etcd to report a single member after the restore", "members", members)
+
+		return ctrl.Result{RequeueAfter: etcdRestoreRequeueAfter}, nil
+	}
+
+	if err := workloadCluster.RemoveStaleControlPlaneNodes(ctx, []string{restore.Status.NodeName}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	restore.Status.Phase = controlplanev1.RKE2EtcdSnapshotRestorePhaseRejoining
+	conditions.MarkFalse(restore, controlplanev1.EtcdSnapshotRestoredCondition, controlplanev1.EtcdRestoreRejoiningMachinesReason,
+		clusterv1.ConditionSeverityInfo, "Scaling up control plane to %d replicas", *controlPlane.RCP.Spec.Replicas)
+	r.recorder.Eventf(restore, corev1.EventTypeNormal, "SnapshotRestored",
+		"Etcd snapshot %s restored on node %s", restore.Status.SnapshotName, restore.Status.NodeName)
+
+	return ctrl.Result{}, nil
+}
+
+// rejoinMachinesAfterEtcdSnapshotRestore lets the normal reconciliation scale up the control plane, and completes the restore
+// once every control plane machine is an etcd member.
+func (r *RKE2ControlPlaneReconciler) rejoinMachinesAfterEtcdSnapshotRestore(
+	ctx context.Context, controlPlane *rke2.ControlPlane, restore *controlplanev1.RKE2EtcdSnapshotRestore,
+) (ctrl.Result, error) {
+	machines := controlPlane.Machines.Filter(collections.HasNode(), collections.IsReady())
+	if machines.Len() != controlPlane.Machines.Len() || int32(machines.Len()) != *controlPlane.RCP.Spec.Replicas {
+		return ctrl.Result{}, nil
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, nil //nolint:nilerr
+	}
+
+	members, err := workloadCluster.EtcdMembers(ctx)
+	if err != nil || len(members) != machines.Len() {
+		return ctrl.Result{}, nil //nolint:nilerr
+	}
+
+	for _, machine := range machines {
+		if !hasEtcdMember(members, machine.Status.NodeRef.Name) {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	restore.Status.Phase = controlplanev1.RKE2EtcdSnapshotRestorePhaseSucceeded
+	restore.Status.CompletionTime = ptr.To(metav1.Now())
+	conditions.MarkTrue(restore, controlplanev1.EtcdSnapshotRestoredCondition)
+	r.recorder.Eventf(restore, corev1.EventTypeNormal, "RestoreCompleted",
+		"Etcd snapshot %s restored, %d control plane machines joined", restore.Status.SnapshotName, machines.Len())
+
+	return ctrl.Result{}, nil
+}
+
+// failEtcdSnapshotRestore marks the restore as failed. The normal reconciliation of the control plane resumes.
+func (r *RKE2ControlPlaneReconciler) failEtcdSnapshotRestore(
+	restore *controlplanev1.RKE2EtcdSnapshotRestore, messageFormat string, messageArgs ...interface{},
+) {
+	restore.Status.Phase = controlplanev1.RKE2EtcdSnapshotRestorePhaseFailed
+	conditions.MarkFalse(restore, controlplanev1.EtcdSnapshotRestoredCondition, controlplanev1.EtcdRestoreFailedReason,
+		clusterv1.ConditionSeverityError, messageFormat, messageArgs...)
+	r.recorder.Event(restore, corev1.EventTypeWarning, "RestoreFailed", fmt.Sprintf(messageFormat, messageArgs...))
+}
+
+func hasEtcdMember(members []string, nodeName string) bool {
+	for _, member := range members {
+		if strings.Contains(member, nodeName) {
+			return true
+		}
+	}
+
+	return false
+}
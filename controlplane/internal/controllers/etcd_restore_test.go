@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakeManagementCluster struct {
+	rke2.ManagementCluster
+
+	workload rke2.WorkloadCluster
+}
+
+func (f *fakeManagementCluster) GetWorkloadCluster(_ context.Context, _ client.ObjectKey) (rke2.WorkloadCluster, error) {
+	return f.workload, nil
+}
+
+type fakeRestoreWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	restoreStarted bool
+	restorePhase   rke2.EtcdSnapshotPhase
+	keptNodes      []string
+	removedMembers []string
+}
+
+func (f *fakeRestoreWorkloadCluster) ForwardEtcdLeadership(_ context.Context, _, _ *clusterv1.Machine) error {
+	return nil
+}
+
+func (f *fakeRestoreWorkloadCluster) RemoveEtcdMemberForMachine(_ context.Context, machine *clusterv1.Machine) error {
+	f.removedMembers = append(f.removedMembers, machine.Name)
+
+	return nil
+}
+
+func (f *fakeRestoreWorkloadCluster) StartEtcdSnapshotRestore(_ context.Context, _, _, _ string, _ bool, _ string) error {
+	f.restoreStarted = true
+
+	return nil
+}
+
+func (f *fakeRestoreWorkloadCluster) GetEtcdSnapshotRestorePhase(_ context.Context, _ string) (rke2.EtcdSnapshotPhase, string, error) {
+	return f.restorePhase, "", nil
+}
+
+func (f *fakeRestoreWorkloadCluster) RemoveStaleControlPlaneNodes(_ context.Context, nodeNames []string) error {
+	f.keptNodes = nodeNames
+
+	return nil
+}
+
+func restoreTestMachine(name string, age int) *clusterv1.Machine {
+	m := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.Unix(int64(1000-age), 0),
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "node-" + name},
+		},
+	}
+	conditions.MarkTrue(m, clusterv1.ReadyCondition)
+
+	return m
+}
+
+var _ = Describe("Etcd snapshot restore", func() {
+	var (
+		cl       client.Client
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeRestoreWorkloadCluster
+		cluster  *clusterv1.Cluster
+		rcp      *controlplanev1.RKE2ControlPlane
+		restore  *controlplanev1.RKE2EtcdSnapshotRestore
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		cluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Replicas: ptr.To[int32](3),
+				Version:  "v1.29.3+rke2r1",
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		restore = &controlplanev1.RKE2EtcdSnapshotRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default", UID: "1234"},
+			Spec: controlplanev1.RKE2EtcdSnapshotRestoreSpec{
+				ClusterName:  "test",
+				SnapshotName: "before-upgrade-node-m1-1700000000",
+				S3:           true,
+			},
+		}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(restore, restoreTestMachine("m1", 3), restoreTestMachine("m2", 2), restoreTestMachine("m3", 1)).
+			WithStatusSubresource(restore).
+			Build()
+
+		workload = &fakeRestoreWorkloadCluster{restorePhase: rke2.EtcdSnapshotRunning}
+		r = &RKE2ControlPlaneReconciler{
+			Client:            cl,
+			managementCluster: &fakeManagementCluster{workload: workload},
+			recorder:          record.NewFakeRecorder(32),
+		}
+	})
+
+	reconcile := func() (bool, *controlplanev1.RKE2EtcdSnapshotRestore) {
+		machines := &clusterv1.MachineList{}
+		Expect(cl.List(ctx, machines)).To(Succeed())
+
+		controlPlane := &rke2.ControlPlane{RCP: rcp, Cluster: cluster, Machines: collections.FromMachineList(machines)}
+
+		result, err := r.reconcileEtcdSnapshotRestore(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+
+		updated := &controlplanev1.RKE2EtcdSnapshotRestore{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(restore), updated)).To(Succeed())
+
+		return !result.IsZero(), updated
+	}
+
+	It("should scale down, restore the snapshot and complete once new machines joined", func() {
+		paused, updated := reconcile()
+		Expect(paused).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseScalingDown))
+		Expect(updated.Status.MachineName).To(Equal("m1"))
+		Expect(updated.Status.NodeName).To(Equal("node-m1"))
+		Expect(updated.OwnerReferences).To(HaveLen(1))
+
+		// Machines other than the restored one are deleted one at a time.
+		for _, remaining := range []int{2, 1} {
+			paused, _ = reconcile()
+			Expect(paused).To(BeTrue())
+
+			machines := &clusterv1.MachineList{}
+			Expect(cl.List(ctx, machines)).To(Succeed())
+			Expect(machines.Items).To(HaveLen(remaining))
+		}
+
+		paused, updated = reconcile()
+		Expect(paused).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseRestoring))
+
+		paused, updated = reconcile()
+		Expect(paused).To(BeTrue())
+		Expect(workload.restoreStarted).To(BeTrue())
+		Expect(updated.Status.RestoreStartTime).ToNot(BeNil())
+		Expect(conditions.GetReason(updated, controlplanev1.EtcdSnapshotRestoredCondition)).To(Equal(controlplanev1.EtcdRestoreInProgressReason))
+
+		paused, updated = reconcile()
+		Expect(paused).To(BeTrue())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseRestoring))
+
+		// The restore completes once etcd is reset to a single member.
+		workload.restorePhase = rke2.EtcdSnapshotSucceeded
+		workload.etcdMembers = []string{"node-m1-1a2b3c4d"}
+
+		paused, updated = reconcile()
+		Expect(paused).To(BeFalse())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseRejoining))
+		Expect(workload.keptNodes).To(ConsistOf("node-m1"))
+
+		paused, updated = reconcile()
+		Expect(paused).To(BeFalse())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseRejoining))
+
+		Expect(cl.Create(ctx, restoreTestMachine("m4", 0))).To(Succeed())
+		Expect(cl.Create(ctx, restoreTestMachine("m5", 0))).To(Succeed())
+		workload.etcdMembers = []string{"node-m1-1a2b3c4d", "node-m4-2b3c4d5e", "node-m5-3c4d5e6f"}
+
+		paused, updated = reconcile()
+		Expect(paused).To(BeFalse())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseSucceeded))
+		Expect(updated.Status.CompletionTime).ToNot(BeNil())
+		Expect(conditions.IsTrue(updated, controlplanev1.EtcdSnapshotRestoredCondition)).To(BeTrue())
+
+		// Finished restores no longer pause the control plane.
+		paused, _ = reconcile()
+		Expect(paused).To(BeFalse())
+	})
+
+	It("should remove the pre-terminate hook of the machines deleted for the restore", func() {
+		machines := &clusterv1.MachineList{}
+		Expect(cl.List(ctx, machines)).To(Succeed())
+
+		for i := range machines.Items {
+			m := &machines.Items[i]
+			m.Annotations = map[string]string{controlplanev1.PreTerminateHookCleanupAnnotation: ""}
+			m.Finalizers = []string{clusterv1.MachineFinalizer}
+			Expect(cl.Update(ctx, m)).To(Succeed())
+		}
+
+		// The machine controller drains the deleting machines and waits for their pre-terminate hook to be removed.
+		machineController := func() {
+			machines := &clusterv1.MachineList{}
+			Expect(cl.List(ctx, machines)).To(Succeed())
+
+			for i := range machines.Items {
+				m := &machines.Items[i]
+				if m.DeletionTimestamp.IsZero() {
+					continue
+				}
+
+				if _, ok := m.Annotations[controlplanev1.PreTerminateHookCleanupAnnotation]; ok {
+					conditions.MarkFalse(m, clusterv1.PreTerminateDeleteHookSucceededCondition,
+						clusterv1.WaitingExternalHookReason, clusterv1.ConditionSeverityInfo, "")
+				} else {
+					m.Finalizers = nil
+				}
+
+				Expect(cl.Update(ctx, m)).To(Succeed())
+			}
+		}
+
+		_, updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseScalingDown))
+
+		for i := 0; i < 6; i++ {
+			_, updated = reconcile()
+			machineController()
+		}
+
+		Expect(cl.List(ctx, machines)).To(Succeed())
+		Expect(machines.Items).To(HaveLen(1))
+		Expect(machines.Items[0].Name).To(Equal("m1"))
+		Expect(workload.removedMembers).To(ConsistOf("m2", "m3"))
+
+		_, updated = reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseRestoring))
+	})
+
+	It("should fail when the referenced snapshot does not exist", func() {
+		restore.Spec.EtcdSnapshotName = "missing"
+		Expect(cl.Update(ctx, restore)).To(Succeed())
+
+		paused, updated := reconcile()
+		Expect(paused).To(BeFalse())
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseFailed))
+		Expect(conditions.GetReason(updated, controlplanev1.EtcdSnapshotRestoredCondition)).To(Equal(controlplanev1.EtcdRestoreFailedReason))
+	})
+
+	It("should restore a local snapshot on the node it was taken on", func() {
+		snapshot := &controlplanev1.RKE2EtcdSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "before-upgrade", Namespace: "default"},
+			Spec:       controlplanev1.RKE2EtcdSnapshotSpec{ClusterName: "test"},
+			Status: controlplanev1.RKE2EtcdSnapshotStatus{
+				Phase:        controlplanev1.RKE2EtcdSnapshotPhaseSucceeded,
+				NodeName:     "node-m2",
+				SnapshotName: "before-upgrade-node-m2-1700000000",
+				Location:     "file:///var/lib/rancher/rke2/server/db/snapshots/before-upgrade-node-m2-1700000000",
+			},
+		}
+		Expect(cl.Create(ctx, snapshot)).To(Succeed())
+
+		restore.Spec = controlplanev1.RKE2EtcdSnapshotRestoreSpec{ClusterName: "test", EtcdSnapshotName: "before-upgrade"}
+		Expect(cl.Update(ctx, restore)).To(Succeed())
+
+		_, updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotRestorePhaseScalingDown))
+		Expect(updated.Status.MachineName).To(Equal("m2"))
+		Expect(updated.Status.S3).To(BeFalse())
+		Expect(updated.Status.SnapshotName).To(Equal("/var/lib/rancher/rke2/server/db/snapshots/before-upgrade-node-m2-1700000000"))
+	})
+})
@@ -0,0 +1,237 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// upgradeControlPlaneInPlace upgrades the RKE2 version of the control plane machines one at a time, without replacing them.
+// For each machine the node is cordoned and drained, the RKE2 binaries are replaced and the RKE2 service restarted;
+// once the node is healthy again at the new version, the node is uncordoned and the Machine is updated.
+// Machines needing a rollout for any other reason than a version change are replaced using a rolling update first,
+// including a machine whose configuration changed while it was being upgraded in place.
+func (r *RKE2ControlPlaneReconciler) upgradeControlPlaneInPlace(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	rcp *controlplanev1.RKE2ControlPlane,
+	controlPlane *rke2.ControlPlane,
+	workloadCluster rke2.WorkloadCluster,
+	machinesRequireUpgrade collections.Machines,
+) (ctrl.Result, error) {
+	logger := controlPlane.Logger()
+	desiredVersion := rcp.GetDesiredVersion()
+
+	needReplacement := controlPlane.MachinesNeedingReplacement()
+
+	machine := controlPlane.Machines.Filter(collections.HasAnnotationKey(controlplanev1.InPlaceUpgradeAnnotation)).Oldest()
+	if machine != nil {
+		// The configuration of the control plane changed while the machine was being upgraded in place,
+		// so it can't be considered up to date once upgraded; replace it instead.
+		if _, ok := needReplacement[machine.Name]; ok {
+			logger.Info("Machine being upgraded in place can't be upgraded in place anymore, replacing it", "machine", machine.Name)
+
+			return r.rollingUpdateControlPlane(ctx, cluster, rcp, controlPlane, collections.FromMachines(machine))
+		}
+	} else {
+		if needReplacement.Len() > 0 {
+			logger.Info("Machines can't be upgraded in place, replacing them", "machines", needReplacement.Names())
+
+			return r.rollingUpdateControlPlane(ctx, cluster, rcp, controlPlane, needReplacement)
+		}
+
+		// Run preflight checks ensuring the control plane is stable before starting the upgrade of the next machine.
+		if result := r.preflightChecks(ctx, controlPlane); !result.IsZero() {
+			return result, nil
+		}
+
+		machine = machinesRequireUpgrade.Filter(collections.HasNode()).Oldest()
+		if machine == nil {
+			logger.Info("Waiting for control plane machines to have a node before upgrading them in place")
+
+			return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, nil
+		}
+
+		if err := setInPlaceUpgradeAnnotation(ctx, r.Client, machine, desiredVersion); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "InPlaceUpgradeStarted",
+			"Upgrading control plane Machine %s in place to version %s", machine.Name, desiredVersion)
+	}
+
+	logger = logger.WithValues("machine", machine.Name, "version", desiredVersion)
+
+	if machine.Status.NodeRef == nil {
+		logger.Info("Waiting for machine to have a node before upgrading it in place")
+
+		return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, nil
+	}
+
+	nodeName := machine.Status.NodeRef.Name
+
+	// If etcd leadership is on the machine that is about to be restarted, move it to the newest healthy member.
+	if etcdLeaderCandidate := controlPlane.HealthyMachines().Filter(
+		collections.Not(collections.HasAnnotationKey(controlplanev1.InPlaceUpgradeAnnotation)),
+	).Newest(); etcdLeaderCandidate != nil {
+		if err := workloadCluster.ForwardEtcdLeadership(ctx, machine, etcdLeaderCandidate); err != nil {
+			logger.Error(err, "Failed to move leadership to candidate machine", "candidate", etcdLeaderCandidate.Name)
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	drained, err := workloadCluster.CordonAndDrainNode(ctx, nodeName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !drained {
+		logger.Info("Waiting for node to be drained before upgrading it in place", "node", nodeName)
+
+		return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, nil
+	}
+
+	upgraded, err := workloadCluster.UpgradeNodeInPlace(ctx, nodeName, normalizeVersion(desiredVersion), upgradeImage(rcp))
+	if err != nil {
+		r.recorder.Eventf(rcp, corev1.EventTypeWarning, "InPlaceUpgradeFailed",
+			"Failed to upgrade control plane Machine %s in place to version %s: %v", machine.Name, desiredVersion, err)
+
+		return ctrl.Result{}, err
+	}
+
+	if !upgraded {
+		logger.Info("Waiting for node to be upgraded in place", "node", nodeName)
+
+		return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, nil
+	}
+
+	// Verify the health of the upgraded machine through the agent and etcd conditions before moving on.
+	for _, condition := range []clusterv1.ConditionType{
+		controlplanev1.MachineAgentHealthyCondition,
+		controlplanev1.MachineEtcdMemberHealthyCondition,
+	} {
+		if err := preflightCheckCondition("machine", machine, condition); err != nil {
+			logger.Info("Waiting for upgraded machine to be healthy", "reason", err.Error())
+
+			return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, nil
+		}
+	}
+
+	if err := workloadCluster.UncordonNode(ctx, nodeName); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := completeInPlaceUpgrade(ctx, r.Client, rcp, machine); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.recorder.Eventf(rcp, corev1.EventTypeNormal, "InPlaceUpgradeCompleted",
+		"Control plane Machine %s upgraded in place to version %s", machine.Name, desiredVersion)
+
+	// Requeue the control plane, in case there are other machines to upgrade.
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// setInPlaceUpgradeAnnotation marks the machine as being upgraded in place to the given version.
+func setInPlaceUpgradeAnnotation(ctx context.Context, c client.Client, machine *clusterv1.Machine, version string) error {
+	patchHelper, err := patch.NewHelper(machine, c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create patch helper for Machine/%s", machine.Name)
+	}
+
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[controlplanev1.InPlaceUpgradeAnnotation] = version
+	machine.SetAnnotations(annotations)
+
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return errors.Wrapf(err, "failed to mark Machine/%s for in-place upgrade", machine.Name)
+	}
+
+	return nil
+}
+
+// completeInPlaceUpgrade updates the version and the server configuration of the upgraded machine,
+// so that it is considered up to date, and removes the in-place upgrade annotation.
+// NOTE: the machine must not need a replacement, so that the server configuration of the control plane
+// is the one the machine has been bootstrapped with.
+func completeInPlaceUpgrade(ctx context.Context, c client.Client, rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine) error {
+	serverConfig, err := json.Marshal(rcp.Spec.ServerConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster configuration")
+	}
+
+	patchHelper, err := patch.NewHelper(machine, c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create patch helper for Machine/%s", machine.Name)
+	}
+
+	version := rcp.GetDesiredVersion()
+	machine.Spec.Version = &version
+
+	annotations := machine.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[controlplanev1.RKE2ServerConfigurationAnnotation] = string(serverConfig)
+	delete(annotations, controlplanev1.InPlaceUpgradeAnnotation)
+	machine.SetAnnotations(annotations)
+
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return errors.Wrapf(err, "failed to complete in-place upgrade of Machine/%s", machine.Name)
+	}
+
+	return nil
+}
+
+// upgradeImage returns the RKE2 upgrade image configured for the control plane. Besides in-place upgrades,
+// the image is used to run rke2 commands, like etcd snapshots, on the control plane nodes.
+func upgradeImage(rcp *controlplanev1.RKE2ControlPlane) string {
+	if rcp.Spec.RolloutStrategy != nil && rcp.Spec.RolloutStrategy.InPlace != nil && rcp.Spec.RolloutStrategy.InPlace.Image != "" {
+		return rcp.Spec.RolloutStrategy.InPlace.Image
+	}
+
+	return controlplanev1.DefaultInPlaceUpgradeImage
+}
+
+// normalizeVersion returns the version prefixed with "v", as reported by the kubelet.
+func normalizeVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+
+	return "v" + version
+}
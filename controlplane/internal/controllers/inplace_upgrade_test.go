@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakeInPlaceUpgradeWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	drained    bool
+	upgraded   bool
+	upgradeErr error
+	operations []string
+}
+
+func (f *fakeInPlaceUpgradeWorkloadCluster) ForwardEtcdLeadership(_ context.Context, machine, leaderCandidate *clusterv1.Machine) error {
+	f.operations = append(f.operations, "forward "+machine.Name+" to "+leaderCandidate.Name)
+
+	return nil
+}
+
+func (f *fakeInPlaceUpgradeWorkloadCluster) CordonAndDrainNode(_ context.Context, nodeName string) (bool, error) {
+	f.operations = append(f.operations, "drain "+nodeName)
+
+	return f.drained, nil
+}
+
+func (f *fakeInPlaceUpgradeWorkloadCluster) UpgradeNodeInPlace(_ context.Context, nodeName, version, _ string) (bool, error) {
+	f.operations = append(f.operations, "upgrade "+nodeName+" to "+version)
+
+	return f.upgraded, f.upgradeErr
+}
+
+func (f *fakeInPlaceUpgradeWorkloadCluster) UncordonNode(_ context.Context, nodeName string) error {
+	f.operations = append(f.operations, "uncordon "+nodeName)
+
+	return nil
+}
+
+// inPlaceUpgradeTestCase is a step of the in-place upgrade of a control plane of three machines m1, m2 and m3,
+// m1 being the oldest one.
+type inPlaceUpgradeTestCase struct {
+	replicas   int32
+	setup      func(m1, m2, m3 *clusterv1.Machine, workload *fakeInPlaceUpgradeWorkloadCluster)
+	result     ctrl.Result
+	err        bool
+	operations []string
+	deleted    []string
+	upgrading  []string
+	upgraded   []string
+	event      string
+}
+
+var _ = Describe("In-place upgrade", func() {
+	var (
+		cl      client.Client
+		rcp     *controlplanev1.RKE2ControlPlane
+		machine *clusterv1.Machine
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+
+		rcp = &controlplanev1.RKE2ControlPlane{
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Version: "v1.29.3+rke2r1",
+				ServerConfig: controlplanev1.RKE2ServerConfig{
+					CNI: controlplanev1.Calico,
+				},
+			},
+		}
+		machine = &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "m1",
+				Namespace: "default",
+			},
+			Spec: clusterv1.MachineSpec{
+				ClusterName: "test",
+				Version:     ptr.To("v1.28.8+rke2r1"),
+			},
+		}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(machine).Build()
+	})
+
+	It("should track and complete the in-place upgrade of a machine", func() {
+		Expect(setInPlaceUpgradeAnnotation(ctx, cl, machine, rcp.Spec.Version)).To(Succeed())
+
+		updated := &clusterv1.Machine{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), updated)).To(Succeed())
+		Expect(updated.Annotations).To(HaveKeyWithValue(controlplanev1.InPlaceUpgradeAnnotation, "v1.29.3+rke2r1"))
+
+		Expect(completeInPlaceUpgrade(ctx, cl, rcp, updated)).To(Succeed())
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), updated)).To(Succeed())
+		Expect(updated.Annotations).ToNot(HaveKey(controlplanev1.InPlaceUpgradeAnnotation))
+		Expect(updated.Annotations).To(HaveKeyWithValue(controlplanev1.RKE2ServerConfigurationAnnotation, ContainSubstring(`"cni":"calico"`)))
+		Expect(*updated.Spec.Version).To(Equal("v1.29.3+rke2r1"))
+	})
+
+	It("should normalize versions as reported by the kubelet", func() {
+		Expect(normalizeVersion("1.29.3+rke2r1")).To(Equal("v1.29.3+rke2r1"))
+		Expect(normalizeVersion("v1.29.3+rke2r1")).To(Equal("v1.29.3+rke2r1"))
+	})
+
+	DescribeTable("upgradeControlPlaneInPlace",
+		func(tc inPlaceUpgradeTestCase) {
+			scheme := runtime.NewScheme()
+			Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+
+			rcp.Name = "test-control-plane"
+			rcp.Namespace = "default"
+			rcp.Spec.Replicas = ptr.To[int32](3)
+			if tc.replicas != 0 {
+				rcp.Spec.Replicas = ptr.To(tc.replicas)
+			}
+			rcp.Spec.RolloutStrategy = &controlplanev1.RolloutStrategy{Type: controlplanev1.InPlaceStrategyType}
+
+			m1, m2, m3 := restoreTestMachine("m1", 3), restoreTestMachine("m2", 2), restoreTestMachine("m3", 1)
+			for _, m := range []*clusterv1.Machine{m1, m2, m3} {
+				m.Spec.ClusterName = "test"
+				m.Spec.Version = ptr.To("v1.28.8+rke2r1")
+				conditions.MarkTrue(m, controlplanev1.MachineAgentHealthyCondition)
+				conditions.MarkTrue(m, controlplanev1.MachineEtcdMemberHealthyCondition)
+			}
+
+			workload := &fakeInPlaceUpgradeWorkloadCluster{}
+			if tc.setup != nil {
+				tc.setup(m1, m2, m3, workload)
+			}
+
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(m1, m2, m3).Build()
+			for _, m := range []*clusterv1.Machine{m1, m2, m3} {
+				Expect(cl.Get(ctx, client.ObjectKeyFromObject(m), m)).To(Succeed())
+			}
+
+			recorder := record.NewFakeRecorder(32)
+			r := &RKE2ControlPlaneReconciler{Client: cl, recorder: recorder}
+			controlPlane := &rke2.ControlPlane{
+				RCP:      rcp,
+				Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+				Machines: collections.FromMachines(m1, m2, m3),
+			}
+
+			result, err := r.upgradeControlPlaneInPlace(ctx, controlPlane.Cluster, rcp, controlPlane, workload, controlPlane.Machines)
+			if tc.err {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(result).To(Equal(tc.result))
+			Expect(workload.operations).To(Equal(tc.operations))
+
+			if tc.event != "" {
+				Expect(recorder.Events).To(Receive(ContainSubstring(tc.event)))
+			}
+
+			for _, name := range tc.deleted {
+				err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, &clusterv1.Machine{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "machine %s should be deleted", name)
+			}
+
+			for _, name := range []string{"m1", "m2", "m3"} {
+				machine := &clusterv1.Machine{}
+				if err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, machine); apierrors.IsNotFound(err) {
+					continue
+				}
+
+				switch {
+				case slices.Contains(tc.upgrading, name):
+					Expect(machine.Annotations).To(HaveKeyWithValue(controlplanev1.InPlaceUpgradeAnnotation, "v1.29.3+rke2r1"), name)
+				case slices.Contains(tc.upgraded, name):
+					Expect(machine.Annotations).ToNot(HaveKey(controlplanev1.InPlaceUpgradeAnnotation), name)
+					Expect(*machine.Spec.Version).To(Equal("v1.29.3+rke2r1"), name)
+				default:
+					Expect(machine.Annotations).ToNot(HaveKey(controlplanev1.InPlaceUpgradeAnnotation), name)
+					Expect(*machine.Spec.Version).To(Equal("v1.28.8+rke2r1"), name)
+				}
+			}
+		},
+		Entry("should replace the machines which can't be upgraded in place first", inPlaceUpgradeTestCase{
+			replicas: 2,
+			setup: func(m1, _, _ *clusterv1.Machine, _ *fakeInPlaceUpgradeWorkloadCluster) {
+				m1.SetAnnotations(map[string]string{controlplanev1.RKE2ServerConfigurationAnnotation: `{"cni":"canal"}`})
+			},
+			result:  ctrl.Result{Requeue: true},
+			deleted: []string{"m1"},
+		}),
+		Entry("should replace a machine whose configuration changed while it was upgraded in place", inPlaceUpgradeTestCase{
+			replicas: 2,
+			setup: func(_, m2, _ *clusterv1.Machine, _ *fakeInPlaceUpgradeWorkloadCluster) {
+				m2.SetAnnotations(map[string]string{
+					controlplanev1.InPlaceUpgradeAnnotation:          "v1.29.3+rke2r1",
+					controlplanev1.RKE2ServerConfigurationAnnotation: `{"cni":"canal"}`,
+				})
+			},
+			result:  ctrl.Result{Requeue: true},
+			deleted: []string{"m2"},
+		}),
+		Entry("should not start the upgrade of a machine while the control plane is not healthy", inPlaceUpgradeTestCase{
+			setup: func(_, _, m3 *clusterv1.Machine, _ *fakeInPlaceUpgradeWorkloadCluster) {
+				conditions.MarkFalse(m3, controlplanev1.MachineAgentHealthyCondition, "NodeNotReady", clusterv1.ConditionSeverityError, "")
+			},
+			result: ctrl.Result{RequeueAfter: preflightFailedRequeueAfter},
+			event:  "ControlPlaneUnhealthy",
+		}),
+		Entry("should wait for the node of the oldest machine to be drained", inPlaceUpgradeTestCase{
+			result:     ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter},
+			operations: []string{"forward m1 to m3", "drain node-m1"},
+			upgrading:  []string{"m1"},
+			event:      "InPlaceUpgradeStarted",
+		}),
+		Entry("should report a failed upgrade job", inPlaceUpgradeTestCase{
+			setup: func(m1, _, _ *clusterv1.Machine, workload *fakeInPlaceUpgradeWorkloadCluster) {
+				m1.SetAnnotations(map[string]string{controlplanev1.InPlaceUpgradeAnnotation: "v1.29.3+rke2r1"})
+				workload.drained = true
+				workload.upgradeErr = errors.New("upgrade job failed")
+			},
+			err:        true,
+			operations: []string{"forward m1 to m3", "drain node-m1", "upgrade node-m1 to v1.29.3+rke2r1"},
+			upgrading:  []string{"m1"},
+			event:      "InPlaceUpgradeFailed",
+		}),
+		Entry("should wait for the upgraded machine to be healthy", inPlaceUpgradeTestCase{
+			setup: func(m1, _, _ *clusterv1.Machine, workload *fakeInPlaceUpgradeWorkloadCluster) {
+				m1.SetAnnotations(map[string]string{controlplanev1.InPlaceUpgradeAnnotation: "v1.29.3+rke2r1"})
+				conditions.MarkFalse(m1, controlplanev1.MachineEtcdMemberHealthyCondition, "MemberUnhealthy", clusterv1.ConditionSeverityError, "")
+				workload.drained = true
+				workload.upgraded = true
+			},
+			result:     ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter},
+			operations: []string{"forward m1 to m3", "drain node-m1", "upgrade node-m1 to v1.29.3+rke2r1"},
+			upgrading:  []string{"m1"},
+		}),
+		Entry("should uncordon the node and complete the upgrade of the machine", inPlaceUpgradeTestCase{
+			setup: func(m1, _, _ *clusterv1.Machine, workload *fakeInPlaceUpgradeWorkloadCluster) {
+				m1.SetAnnotations(map[string]string{controlplanev1.InPlaceUpgradeAnnotation: "v1.29.3+rke2r1"})
+				workload.drained = true
+				workload.upgraded = true
+			},
+			result: ctrl.Result{Requeue: true},
+			operations: []string{
+				"forward m1 to m3", "drain node-m1", "upgrade node-m1 to v1.29.3+rke2r1", "uncordon node-m1",
+			},
+			upgraded: []string{"m1"},
+			event:    "InPlaceUpgradeCompleted",
+		}),
+	)
+})
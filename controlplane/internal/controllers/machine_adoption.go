@@ -0,0 +1,197 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/blang/semver/v4"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+// adoptMachines adopts the control plane machines of the cluster which are not controlled by any object, e.g. after they
+// have been orphaned by a failed move or a manual recovery. The RKE2Config and the infrastructure object of each machine
+// are adopted first, so an adoption failing midway is retried, as the machine remains adoptable.
+func (r *RKE2ControlPlaneReconciler) adoptMachines(
+	ctx context.Context,
+	rcp *controlplanev1.RKE2ControlPlane,
+	machines collections.Machines,
+) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	// We do an uncached full quorum read against the RCP to avoid re-adopting Machines the garbage collector just intentionally orphaned
+	// See https://github.com/kubernetes/kubernetes/issues/42639
+	uncached := controlplanev1.RKE2ControlPlane{}
+	if err := r.managementClusterUncached.Get(ctx, client.ObjectKeyFromObject(rcp), &uncached); err != nil {
+		return errors.Wrapf(err, "failed to check whether %s/%s was deleted before adoption", rcp.Namespace, rcp.Name)
+	}
+
+	if !uncached.DeletionTimestamp.IsZero() {
+		return errors.Errorf("%s/%s has just been deleted at %v", rcp.Namespace, rcp.Name, rcp.GetDeletionTimestamp())
+	}
+
+	rcpVersion, err := semver.ParseTolerant(rcp.GetDesiredVersion())
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse RKE2ControlPlane version %q", rcp.GetDesiredVersion())
+	}
+
+	for _, m := range machines {
+		ref := m.Spec.Bootstrap.ConfigRef
+		if ref == nil || ref.Kind != "RKE2Config" {
+			return errors.Errorf("unable to adopt Machine %s/%s: expected a ConfigRef of kind RKE2Config but instead found %v",
+				m.Namespace, m.Name, ref)
+		}
+
+		if ref.Namespace != "" && ref.Namespace != rcp.Namespace {
+			return errors.Errorf("unable to adopt RKE2Config %s/%s: cannot adopt across namespaces", ref.Namespace, ref.Name)
+		}
+
+		if m.Spec.Version == nil {
+			// If the machine's version is not immediately apparent, assume the operator knows what they're doing.
+			continue
+		}
+
+		machineVersion, err := semver.ParseTolerant(*m.Spec.Version)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse version %q of Machine %s/%s", *m.Spec.Version, m.Namespace, m.Name)
+		}
+
+		if !util.IsSupportedVersionSkew(rcpVersion, machineVersion) {
+			logger.Info("Not adopting control plane machine with an incompatible version", "machine", m.Name, "version", *m.Spec.Version)
+			r.recorder.Eventf(rcp, corev1.EventTypeWarning, "AdoptionFailed",
+				"Could not adopt Machine %s/%s: its version (%q) is outside supported +/- one minor version skew from RCP's (%q)",
+				m.Namespace, m.Name, *m.Spec.Version, rcp.GetDesiredVersion())
+
+			// Avoid returning an error here so we don't cause the RCP controller to spin until the operator clarifies their intent.
+			return nil
+		}
+	}
+
+	serverConfig, err := json.Marshal(rcp.Spec.ServerConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster configuration")
+	}
+
+	// The RKE2Config and the infrastructure object are owned by the RKE2ControlPlane without a controller
+	// reference, as the owning controller is the machine controller.
+	owner := metav1.OwnerReference{
+		APIVersion: controlplanev1.GroupVersion.String(),
+		Kind:       "RKE2ControlPlane",
+		Name:       rcp.Name,
+		UID:        rcp.UID,
+	}
+
+	for _, m := range machines {
+		if err := r.adoptRKE2Config(ctx, m, owner); err != nil {
+			return err
+		}
+
+		if err := r.adoptInfrastructure(ctx, m, owner); err != nil {
+			return err
+		}
+
+		patchHelper, err := patch.NewHelper(m, r.Client)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create patch helper for Machine/%s", m.Name)
+		}
+
+		if err := controllerutil.SetControllerReference(rcp, m, r.Client.Scheme()); err != nil {
+			return errors.Wrapf(err, "failed to set controller reference on Machine/%s", m.Name)
+		}
+
+		// Without the annotation, the machine filters can't tell whether the server configuration of the machine
+		// matches the control plane, so assume the machine was created with the current configuration.
+		annotations := m.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		if _, ok := annotations[controlplanev1.RKE2ServerConfigurationAnnotation]; !ok {
+			annotations[controlplanev1.RKE2ServerConfigurationAnnotation] = string(serverConfig)
+		}
+
+		m.SetAnnotations(annotations)
+
+		// Note that ValidateOwnerReferences() will reject this patch if another
+		// OwnerReference exists with controller=true.
+		if err := patchHelper.Patch(ctx, m); err != nil {
+			return errors.Wrapf(err, "failed to adopt Machine/%s", m.Name)
+		}
+
+		logger.Info("Adopted control plane machine", "machine", m.Name)
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "AdoptedMachine", "Adopted control plane Machine %s", m.Name)
+	}
+
+	return nil
+}
+
+// adoptRKE2Config adds an owner reference to the RKE2ControlPlane on the RKE2Config of the machine.
+func (r *RKE2ControlPlaneReconciler) adoptRKE2Config(ctx context.Context, machine *clusterv1.Machine, owner metav1.OwnerReference) error {
+	config := &bootstrapv1.RKE2Config{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machine.Spec.Bootstrap.ConfigRef.Name}, config); err != nil {
+		return errors.Wrapf(err, "failed to get RKE2Config of Machine/%s", machine.Name)
+	}
+
+	patchHelper, err := patch.NewHelper(config, r.Client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create patch helper for RKE2Config/%s", config.Name)
+	}
+
+	config.SetOwnerReferences(util.EnsureOwnerRef(config.GetOwnerReferences(), owner))
+
+	if err := patchHelper.Patch(ctx, config); err != nil {
+		return errors.Wrapf(err, "failed to adopt RKE2Config/%s", config.Name)
+	}
+
+	return nil
+}
+
+// adoptInfrastructure adds an owner reference to the RKE2ControlPlane on the infrastructure object of the machine.
+func (r *RKE2ControlPlaneReconciler) adoptInfrastructure(ctx context.Context, machine *clusterv1.Machine, owner metav1.OwnerReference) error {
+	infraObj, err := external.Get(ctx, r.Client, &machine.Spec.InfrastructureRef, machine.Namespace)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get infrastructure object of Machine/%s", machine.Name)
+	}
+
+	patchHelper, err := patch.NewHelper(infraObj, r.Client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create patch helper for %s/%s", infraObj.GetKind(), infraObj.GetName())
+	}
+
+	infraObj.SetOwnerReferences(util.EnsureOwnerRef(infraObj.GetOwnerReferences(), owner))
+
+	if err := patchHelper.Patch(ctx, infraObj); err != nil {
+		return errors.Wrapf(err, "failed to adopt %s/%s", infraObj.GetKind(), infraObj.GetName())
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("Machine adoption", func() {
+	var (
+		cl       client.Client
+		r        *RKE2ControlPlaneReconciler
+		recorder *record.FakeRecorder
+		rcp      *controlplanev1.RKE2ControlPlane
+		machine  *clusterv1.Machine
+		config   *bootstrapv1.RKE2Config
+		infra    *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default", UID: "rcp-uid"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Version:      "v1.29.3+rke2r1",
+				ServerConfig: controlplanev1.RKE2ServerConfig{CNI: controlplanev1.Calico},
+			},
+		}
+		config = &bootstrapv1.RKE2Config{ObjectMeta: metav1.ObjectMeta{Name: "config-m1", Namespace: "default"}}
+		infra = &unstructured.Unstructured{}
+		infra.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1beta1")
+		infra.SetKind("GenericInfrastructureMachine")
+		infra.SetNamespace("default")
+		infra.SetName("infra-m1")
+		machine = &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "m1",
+				Namespace: "default",
+				Labels:    rke2.ControlPlaneLabelsForCluster("test"),
+			},
+			Spec: clusterv1.MachineSpec{
+				ClusterName: "test",
+				Version:     ptr.To("v1.28.8+rke2r1"),
+				Bootstrap: clusterv1.Bootstrap{
+					ConfigRef: &corev1.ObjectReference{
+						APIVersion: bootstrapv1.GroupVersion.String(),
+						Kind:       "RKE2Config",
+						Name:       config.Name,
+					},
+				},
+				InfrastructureRef: corev1.ObjectReference{
+					APIVersion: infra.GetAPIVersion(),
+					Kind:       infra.GetKind(),
+					Name:       infra.GetName(),
+				},
+			},
+		}
+	})
+
+	adopt := func() error {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(rcp, machine, config, infra).Build()
+		recorder = record.NewFakeRecorder(32)
+		r = &RKE2ControlPlaneReconciler{
+			Client:                    cl,
+			managementClusterUncached: &rke2.Management{Client: cl},
+			recorder:                  recorder,
+		}
+
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(rcp), rcp)).To(Succeed())
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+
+		return r.adoptMachines(ctx, rcp, collections.FromMachines(machine))
+	}
+
+	It("should adopt the machine, its RKE2Config and its infrastructure object", func() {
+		Expect(adopt()).To(Succeed())
+
+		adopted := &clusterv1.Machine{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), adopted)).To(Succeed())
+		Expect(metav1.GetControllerOf(adopted)).ToNot(BeNil())
+		Expect(metav1.GetControllerOf(adopted).Name).To(Equal(rcp.Name))
+
+		serverConfig, err := json.Marshal(rcp.Spec.ServerConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adopted.Annotations).To(HaveKeyWithValue(controlplanev1.RKE2ServerConfigurationAnnotation, string(serverConfig)))
+
+		adoptedConfig := &bootstrapv1.RKE2Config{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(config), adoptedConfig)).To(Succeed())
+		Expect(adoptedConfig.OwnerReferences).To(ConsistOf(HaveField("UID", rcp.UID)))
+
+		adoptedInfra := infra.DeepCopy()
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(infra), adoptedInfra)).To(Succeed())
+		Expect(adoptedInfra.GetOwnerReferences()).To(ConsistOf(HaveField("UID", rcp.UID)))
+
+		Expect(recorder.Events).To(Receive(ContainSubstring("AdoptedMachine")))
+	})
+
+	It("should keep the existing server configuration annotation", func() {
+		machine.Annotations = map[string]string{controlplanev1.RKE2ServerConfigurationAnnotation: `{"cni":"cilium"}`}
+
+		Expect(adopt()).To(Succeed())
+
+		adopted := &clusterv1.Machine{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), adopted)).To(Succeed())
+		Expect(adopted.Annotations).To(HaveKeyWithValue(controlplanev1.RKE2ServerConfigurationAnnotation, `{"cni":"cilium"}`))
+	})
+
+	It("should not adopt a machine with an incompatible version", func() {
+		machine.Spec.Version = ptr.To("v1.26.15+rke2r1")
+
+		Expect(adopt()).To(Succeed())
+
+		notAdopted := &clusterv1.Machine{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), notAdopted)).To(Succeed())
+		Expect(notAdopted.OwnerReferences).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(ContainSubstring("AdoptionFailed")))
+	})
+
+	It("should not adopt a machine once the control plane is deleted, even if the cache is not updated yet", func() {
+		Expect(adopt()).To(Succeed())
+
+		deleting := rcp.DeepCopy()
+		deleting.ResourceVersion = ""
+		deleting.Finalizers = []string{controlplanev1.RKE2ControlPlaneFinalizer}
+		deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		scheme := runtime.NewScheme()
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		apiServer := fake.NewClientBuilder().WithScheme(scheme).WithObjects(deleting).Build()
+		r.managementClusterUncached = &rke2.Management{Client: &uncachedClient{Client: cl, reader: apiServer}}
+
+		Expect(r.adoptMachines(ctx, rcp, collections.FromMachines(machine))).To(MatchError(ContainSubstring("has just been deleted")))
+	})
+
+	It("should not adopt a machine without a RKE2Config", func() {
+		machine.Spec.Bootstrap.ConfigRef.Kind = "KubeadmConfig"
+
+		Expect(adopt()).ToNot(Succeed())
+
+		notAdopted := &clusterv1.Machine{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), notAdopted)).To(Succeed())
+		Expect(notAdopted.OwnerReferences).To(BeEmpty())
+	})
+})
@@ -0,0 +1,177 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// manifestsSyncRetryAfter is how long to wait before applying the manifests again after a failure.
+const manifestsSyncRetryAfter = time.Minute
+
+// reconcileManifests applies the manifests of the ManifestsConfigMapReference and the ManifestSources to the workload
+// cluster, and deletes the objects removed from them. A failure is reported in the ManifestsSynced condition
+// without blocking the other operations on the control plane, and the duration to wait before retrying is returned.
+func (r *RKE2ControlPlaneReconciler) reconcileManifests(ctx context.Context, controlPlane *rke2.ControlPlane) (time.Duration, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+
+	// Return if RCP is not yet initialized, the manifests are applied once the workload cluster is reachable.
+	if !rcp.Status.Initialized || controlPlane.Machines.Len() == 0 {
+		return 0, nil
+	}
+
+	if !hasManifestSources(rcp) && len(rcp.Status.AppliedManifests) == 0 {
+		conditions.Delete(rcp, controlplanev1.ManifestsSyncedCondition)
+
+		return 0, nil
+	}
+
+	files, err := rke2.GetManifestFiles(ctx, r.Client, rcp)
+	if err != nil {
+		if !apierrors.IsNotFound(err) && !errors.Is(err, rke2.ErrInvalidManifestSources) {
+			return 0, errors.Wrap(err, "failed to get manifests")
+		}
+
+		// The objects are not pruned while a source is missing or invalid, they are applied again once it is fixed.
+		conditions.MarkFalse(rcp,
+			controlplanev1.ManifestsSyncedCondition,
+			controlplanev1.ManifestsSyncFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"Failed to get the manifests: %s", err)
+
+		return 0, nil
+	}
+
+	objects, err := rke2.ParseManifests(files)
+	if err != nil {
+		conditions.MarkFalse(rcp,
+			controlplanev1.ManifestsSyncedCondition,
+			controlplanev1.ManifestsSyncFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"Invalid manifests: %s", err)
+
+		return 0, nil
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to reconcile manifests: cannot get remote client to workload cluster")
+	}
+
+	applied, err := workloadCluster.ApplyManifests(ctx, objects, rcp.Status.AppliedManifests)
+	rcp.Status.AppliedManifests = applied
+
+	if err != nil {
+		logger.Error(err, "Failed to sync the manifests to the workload cluster")
+		conditions.MarkFalse(rcp,
+			controlplanev1.ManifestsSyncedCondition,
+			controlplanev1.ManifestsSyncFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"Failed to sync the manifests: %s", err)
+		r.recorder.Eventf(rcp, corev1.EventTypeWarning, "ManifestsSyncFailed", "Failed to sync the manifests: %s", err)
+
+		return manifestsSyncRetryAfter, nil
+	}
+
+	if !hasManifestSources(rcp) {
+		conditions.Delete(rcp, controlplanev1.ManifestsSyncedCondition)
+
+		return 0, nil
+	}
+
+	conditions.MarkTrue(rcp, controlplanev1.ManifestsSyncedCondition)
+
+	return 0, nil
+}
+
+// hasManifestSources returns true if manifests are deployed on the cluster.
+func hasManifestSources(rcp *controlplanev1.RKE2ControlPlane) bool {
+	return rcp.Spec.ManifestsConfigMapReference.Name != "" || len(rcp.Spec.ManifestSources) > 0
+}
+
+// ConfigMapToRKE2ControlPlane is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
+// for RKE2ControlPlane based on updates to the ConfigMaps referenced by ManifestsConfigMapReference or ManifestSources.
+func (r *RKE2ControlPlaneReconciler) ConfigMapToRKE2ControlPlane(ctx context.Context) handler.MapFunc {
+	return r.manifestSourceToRKE2ControlPlane(ctx, controlplanev1.ConfigMapManifestSource)
+}
+
+// SecretToRKE2ControlPlane is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
+// for RKE2ControlPlane based on updates to the Secrets referenced by ManifestSources.
+func (r *RKE2ControlPlaneReconciler) SecretToRKE2ControlPlane(ctx context.Context) handler.MapFunc {
+	return r.manifestSourceToRKE2ControlPlane(ctx, controlplanev1.SecretManifestSource)
+}
+
+func (r *RKE2ControlPlaneReconciler) manifestSourceToRKE2ControlPlane(ctx context.Context, kind controlplanev1.ManifestSourceKind) handler.MapFunc {
+	log := log.FromContext(ctx)
+
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		rcps := &controlplanev1.RKE2ControlPlaneList{}
+		if err := r.Client.List(ctx, rcps); err != nil {
+			log.Error(err, fmt.Sprintf("Failed to list RKE2ControlPlanes for %s %s", kind, client.ObjectKeyFromObject(o)))
+
+			return nil
+		}
+
+		requests := []ctrl.Request{}
+
+		for i := range rcps.Items {
+			rcp := &rcps.Items[i]
+			if referencesManifestSource(rcp, kind, o) {
+				requests = append(requests, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(rcp)})
+			}
+		}
+
+		return requests
+	}
+}
+
+// referencesManifestSource returns true if the object is a manifest source of the RKE2ControlPlane.
+func referencesManifestSource(rcp *controlplanev1.RKE2ControlPlane, kind controlplanev1.ManifestSourceKind, o client.Object) bool {
+	reference := rcp.Spec.ManifestsConfigMapReference
+	if kind == controlplanev1.ConfigMapManifestSource && reference.Name == o.GetName() && reference.Namespace == o.GetNamespace() {
+		return true
+	}
+
+	if rcp.Namespace != o.GetNamespace() {
+		return false
+	}
+
+	for _, source := range rcp.Spec.ManifestSources {
+		if source.Kind == kind && source.Name == o.GetName() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakeManifestsWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	objects []*unstructured.Unstructured
+	applied []controlplanev1.ManifestObjectReference
+	err     error
+	calls   int
+}
+
+func (f *fakeManifestsWorkloadCluster) ApplyManifests(
+	_ context.Context, objects []*unstructured.Unstructured, applied []controlplanev1.ManifestObjectReference,
+) ([]controlplanev1.ManifestObjectReference, error) {
+	f.objects = objects
+	f.applied = applied
+	f.calls++
+
+	if f.err != nil {
+		return applied, f.err
+	}
+
+	managed := []controlplanev1.ManifestObjectReference{}
+	for _, object := range objects {
+		managed = append(managed, controlplanev1.ManifestObjectReference{
+			APIVersion: object.GetAPIVersion(),
+			Kind:       object.GetKind(),
+			Namespace:  object.GetNamespace(),
+			Name:       object.GetName(),
+		})
+	}
+
+	return managed, nil
+}
+
+var _ = Describe("Manifests", func() {
+	var (
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeManifestsWorkloadCluster
+		rcp      *controlplanev1.RKE2ControlPlane
+		cp       *rke2.ControlPlane
+	)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "manifests", Namespace: "default"},
+		Data: map[string]string{
+			"manifests.yaml": "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: test\n",
+		},
+	}
+
+	previous := controlplanev1.ManifestObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "previous"}
+
+	controlPlane := func(objs ...client.Object) *rke2.ControlPlane {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		r = &RKE2ControlPlaneReconciler{
+			Client:            fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+			managementCluster: &fakeManagementCluster{workload: workload},
+			recorder:          record.NewFakeRecorder(32),
+		}
+
+		return &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(restoreTestMachine("m1", 1)),
+		}
+	}
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				ManifestsConfigMapReference: corev1.ObjectReference{Name: "manifests", Namespace: "default"},
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{
+				Initialized:      true,
+				AppliedManifests: []controlplanev1.ManifestObjectReference{previous},
+			},
+		}
+		workload = &fakeManifestsWorkloadCluster{}
+	})
+
+	It("should apply the manifests to the workload cluster", func() {
+		cp = controlPlane(configMap.DeepCopy(), rcp)
+
+		requeueAfter, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeueAfter).To(BeZero())
+		Expect(workload.calls).To(Equal(1))
+		Expect(workload.objects).To(HaveLen(1))
+		Expect(workload.applied).To(ConsistOf(previous))
+		Expect(rcp.Status.AppliedManifests).To(ConsistOf(
+			controlplanev1.ManifestObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "test"},
+		))
+		Expect(conditions.IsTrue(rcp, controlplanev1.ManifestsSyncedCondition)).To(BeTrue())
+	})
+
+	It("should not prune the objects while the ConfigMap is missing", func() {
+		cp = controlPlane(rcp)
+
+		_, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workload.calls).To(BeZero())
+		Expect(rcp.Status.AppliedManifests).To(ConsistOf(previous))
+		Expect(conditions.IsFalse(rcp, controlplanev1.ManifestsSyncedCondition)).To(BeTrue())
+	})
+
+	It("should not apply invalid manifests", func() {
+		invalid := configMap.DeepCopy()
+		invalid.Data["invalid.yaml"] = "apiVersion: v1\nkind: Namespace\n"
+		cp = controlPlane(invalid, rcp)
+
+		_, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workload.calls).To(BeZero())
+		Expect(conditions.GetReason(rcp, controlplanev1.ManifestsSyncedCondition)).To(Equal(controlplanev1.ManifestsSyncFailedReason))
+	})
+
+	It("should apply the manifests of the Secret sources", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+			Data: map[string][]byte{
+				"secret.yaml": []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: cloud-config\n"),
+			},
+		}
+		rcp.Spec.ManifestSources = []controlplanev1.ManifestSource{
+			{Kind: controlplanev1.SecretManifestSource, Name: "credentials"},
+		}
+		cp = controlPlane(configMap.DeepCopy(), secret, rcp)
+
+		_, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rcp.Status.AppliedManifests).To(ConsistOf(
+			controlplanev1.ManifestObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "test"},
+			controlplanev1.ManifestObjectReference{APIVersion: "v1", Kind: "Secret", Name: "cloud-config"},
+		))
+		Expect(conditions.IsTrue(rcp, controlplanev1.ManifestsSyncedCondition)).To(BeTrue())
+	})
+
+	It("should not prune the objects when several sources define the same file", func() {
+		rcp.Spec.ManifestSources = []controlplanev1.ManifestSource{
+			{Kind: controlplanev1.ConfigMapManifestSource, Name: "manifests"},
+		}
+		cp = controlPlane(configMap.DeepCopy(), rcp)
+
+		_, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workload.calls).To(BeZero())
+		Expect(rcp.Status.AppliedManifests).To(ConsistOf(previous))
+		Expect(conditions.IsFalse(rcp, controlplanev1.ManifestsSyncedCondition)).To(BeTrue())
+	})
+
+	It("should retry when the manifests cannot be applied", func() {
+		workload.err = errors.New("apply failed")
+		cp = controlPlane(configMap.DeepCopy(), rcp)
+
+		requeueAfter, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeueAfter).To(Equal(manifestsSyncRetryAfter))
+		Expect(rcp.Status.AppliedManifests).To(ConsistOf(previous))
+		Expect(conditions.IsFalse(rcp, controlplanev1.ManifestsSyncedCondition)).To(BeTrue())
+	})
+
+	It("should delete the applied objects when the reference is removed", func() {
+		rcp.Spec.ManifestsConfigMapReference = corev1.ObjectReference{}
+		cp = controlPlane(rcp)
+
+		_, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workload.calls).To(Equal(1))
+		Expect(workload.objects).To(BeEmpty())
+		Expect(rcp.Status.AppliedManifests).To(BeEmpty())
+		Expect(conditions.Has(rcp, controlplanev1.ManifestsSyncedCondition)).To(BeFalse())
+	})
+
+	It("should wait for the control plane to be initialized", func() {
+		rcp.Status.Initialized = false
+		cp = controlPlane(configMap.DeepCopy(), rcp)
+
+		_, err := r.reconcileManifests(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workload.calls).To(BeZero())
+	})
+
+	It("should map the ConfigMap to the control planes referencing it", func() {
+		other := rcp.DeepCopy()
+		other.Name = "other-control-plane"
+		other.Spec.ManifestsConfigMapReference.Name = "other"
+		cp = controlPlane(rcp, other)
+
+		requests := r.ConfigMapToRKE2ControlPlane(ctx)(ctx, configMap)
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal(rcp.Name))
+	})
+
+	It("should map the Secret to the control planes referencing it", func() {
+		rcp.Spec.ManifestSources = []controlplanev1.ManifestSource{
+			{Kind: controlplanev1.SecretManifestSource, Name: "credentials"},
+		}
+		cp = controlPlane(rcp)
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"}}
+		Expect(r.SecretToRKE2ControlPlane(ctx)(ctx, secret)).To(HaveLen(1))
+
+		secret.Namespace = "other"
+		Expect(r.SecretToRKE2ControlPlane(ctx)(ctx, secret)).To(BeEmpty())
+		Expect(r.ConfigMapToRKE2ControlPlane(ctx)(ctx, &corev1.ConfigMap{ObjectMeta: secret.ObjectMeta})).To(BeEmpty())
+	})
+})
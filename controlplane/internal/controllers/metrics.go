@@ -0,0 +1,160 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+const (
+	metricsNamespace = "capi_rke2"
+	metricsSubsystem = "controlplane"
+
+	// preflightMachineDeletingReason is the preflight check failure reason used while control plane machines are being deleted.
+	preflightMachineDeletingReason = "MachineDeleting"
+)
+
+// controlPlaneMetricsLabels are the labels identifying the RKE2ControlPlane of a metric.
+var controlPlaneMetricsLabels = []string{"namespace", "name", "cluster"}
+
+var (
+	desiredReplicasGauge = newControlPlaneGauge("desired_replicas",
+		"Number of desired control plane replicas.")
+	replicasGauge = newControlPlaneGauge("replicas",
+		"Number of control plane machines.")
+	readyReplicasGauge = newControlPlaneGauge("ready_replicas",
+		"Number of ready control plane machines.")
+	updatedReplicasGauge = newControlPlaneGauge("updated_replicas",
+		"Number of control plane machines with the desired spec.")
+	unavailableReplicasGauge = newControlPlaneGauge("unavailable_replicas",
+		"Number of unavailable control plane machines.")
+	rolloutStartTimeGauge = newControlPlaneGauge("rollout_start_time_seconds",
+		"Unix time at which the last rollout of the control plane machines started.")
+	rolloutCompletionTimeGauge = newControlPlaneGauge("rollout_completion_time_seconds",
+		"Unix time at which the last rollout of the control plane machines completed.")
+	etcdMembersGauge = newControlPlaneGauge("etcd_members",
+		"Number of control plane machines reporting the health of their etcd member.")
+	etcdHealthyMembersGauge = newControlPlaneGauge("etcd_healthy_members",
+		"Number of control plane machines with a healthy etcd member.")
+
+	preflightCheckFailuresCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "preflight_check_failures_total",
+		Help:      "Number of preflight checks that failed before an operation on the control plane machines, by reason.",
+	}, append([]string{"reason"}, controlPlaneMetricsLabels...))
+
+	controlPlaneGauges = []*prometheus.GaugeVec{
+		desiredReplicasGauge,
+		replicasGauge,
+		readyReplicasGauge,
+		updatedReplicasGauge,
+		unavailableReplicasGauge,
+		rolloutStartTimeGauge,
+		rolloutCompletionTimeGauge,
+		etcdMembersGauge,
+		etcdHealthyMembersGauge,
+	}
+)
+
+func init() {
+	for _, gauge := range controlPlaneGauges {
+		metrics.Registry.MustRegister(gauge)
+	}
+
+	metrics.Registry.MustRegister(preflightCheckFailuresCounter)
+}
+
+func newControlPlaneGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      name,
+		Help:      help,
+	}, controlPlaneMetricsLabels)
+}
+
+func controlPlaneMetricsLabelValues(rcp *controlplanev1.RKE2ControlPlane, clusterName string) prometheus.Labels {
+	return prometheus.Labels{
+		"namespace": rcp.Namespace,
+		"name":      rcp.Name,
+		"cluster":   clusterName,
+	}
+}
+
+// recordControlPlaneMetrics records the metrics of the control plane from the status computed by updateStatus,
+// and from the conditions of the control plane machines. The rollout times are recorded by the rollout itself. The metrics of a deleted control plane are removed.
+func recordControlPlaneMetrics(rcp *controlplanev1.RKE2ControlPlane, clusterName string, machines collections.Machines) {
+	labels := controlPlaneMetricsLabelValues(rcp, clusterName)
+
+	if !rcp.DeletionTimestamp.IsZero() && !controllerutil.ContainsFinalizer(rcp, controlplanev1.RKE2ControlPlaneFinalizer) {
+		for _, gauge := range controlPlaneGauges {
+			gauge.Delete(labels)
+		}
+
+		preflightCheckFailuresCounter.DeletePartialMatch(labels)
+
+		return
+	}
+
+	if rcp.Spec.Replicas != nil {
+		desiredReplicasGauge.With(labels).Set(float64(*rcp.Spec.Replicas))
+	}
+
+	replicasGauge.With(labels).Set(float64(rcp.Status.Replicas))
+	readyReplicasGauge.With(labels).Set(float64(rcp.Status.ReadyReplicas))
+	updatedReplicasGauge.With(labels).Set(float64(rcp.Status.UpdatedReplicas))
+	unavailableReplicasGauge.With(labels).Set(float64(rcp.Status.UnavailableReplicas))
+
+	etcdMembers := machines.Filter(func(machine *clusterv1.Machine) bool {
+		return conditions.Has(machine, controlplanev1.MachineEtcdMemberHealthyCondition)
+	})
+	etcdHealthyMembers := etcdMembers.Filter(func(machine *clusterv1.Machine) bool {
+		return conditions.IsTrue(machine, controlplanev1.MachineEtcdMemberHealthyCondition)
+	})
+
+	etcdMembersGauge.With(labels).Set(float64(etcdMembers.Len()))
+	etcdHealthyMembersGauge.With(labels).Set(float64(etcdHealthyMembers.Len()))
+}
+
+// recordRolloutStart records the time at which the rollout of the control plane machines started.
+func recordRolloutStart(rcp *controlplanev1.RKE2ControlPlane, clusterName string, startTime time.Time) {
+	rolloutStartTimeGauge.With(controlPlaneMetricsLabelValues(rcp, clusterName)).Set(float64(startTime.Unix()))
+}
+
+// recordRolloutCompletion records the time at which the rollout of the control plane machines completed.
+func recordRolloutCompletion(rcp *controlplanev1.RKE2ControlPlane, clusterName string, completionTime time.Time) {
+	rolloutCompletionTimeGauge.With(controlPlaneMetricsLabelValues(rcp, clusterName)).Set(float64(completionTime.Unix()))
+}
+
+// recordPreflightCheckFailure records a preflight check failure of the control plane.
+func recordPreflightCheckFailure(rcp *controlplanev1.RKE2ControlPlane, clusterName, reason string) {
+	labels := controlPlaneMetricsLabelValues(rcp, clusterName)
+	labels["reason"] = reason
+
+	preflightCheckFailuresCounter.With(labels).Inc()
+}
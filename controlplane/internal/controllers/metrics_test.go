@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("Control plane metrics", func() {
+	var (
+		rcp    *controlplanev1.RKE2ControlPlane
+		labels []string
+	)
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "metrics-control-plane",
+				Namespace:  "default",
+				Finalizers: []string{controlplanev1.RKE2ControlPlaneFinalizer},
+			},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{Replicas: ptr.To[int32](3)},
+			Status: controlplanev1.RKE2ControlPlaneStatus{
+				Replicas:            3,
+				ReadyReplicas:       2,
+				UpdatedReplicas:     1,
+				UnavailableReplicas: 1,
+			},
+		}
+		labels = []string{"default", "metrics-control-plane", "test"}
+	})
+
+	AfterEach(func() {
+		rcp.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		rcp.Finalizers = nil
+		recordControlPlaneMetrics(rcp, "test", collections.New())
+	})
+
+	It("should record the replicas and the etcd members of the control plane", func() {
+		m1, m2, m3 := restoreTestMachine("m1", 3), restoreTestMachine("m2", 2), restoreTestMachine("m3", 1)
+		conditions.MarkTrue(m1, controlplanev1.MachineEtcdMemberHealthyCondition)
+		conditions.MarkFalse(m2, controlplanev1.MachineEtcdMemberHealthyCondition, "MemberUnhealthy",
+			clusterv1.ConditionSeverityError, "")
+
+		recordControlPlaneMetrics(rcp, "test", collections.FromMachines(m1, m2, m3))
+
+		Expect(testutil.ToFloat64(desiredReplicasGauge.WithLabelValues(labels...))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(replicasGauge.WithLabelValues(labels...))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(readyReplicasGauge.WithLabelValues(labels...))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(updatedReplicasGauge.WithLabelValues(labels...))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(unavailableReplicasGauge.WithLabelValues(labels...))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(etcdMembersGauge.WithLabelValues(labels...))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(etcdHealthyMembersGauge.WithLabelValues(labels...))).To(Equal(1.0))
+	})
+
+	It("should record the start and completion times of rollouts", func() {
+		start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		recordRolloutStart(rcp, "test", start)
+
+		Expect(testutil.ToFloat64(rolloutStartTimeGauge.WithLabelValues(labels...))).To(Equal(float64(start.Unix())))
+		Expect(rolloutCompletionTimeGauge.DeleteLabelValues(labels...)).To(BeFalse())
+
+		completion := start.Add(time.Hour)
+		recordRolloutCompletion(rcp, "test", completion)
+
+		// The rollout times are not derived from the conditions of the control plane.
+		conditions.MarkTrue(rcp, controlplanev1.MachinesSpecUpToDateCondition)
+		recordControlPlaneMetrics(rcp, "test", collections.New())
+
+		Expect(testutil.ToFloat64(rolloutStartTimeGauge.WithLabelValues(labels...))).To(Equal(float64(start.Unix())))
+		Expect(testutil.ToFloat64(rolloutCompletionTimeGauge.WithLabelValues(labels...))).To(Equal(float64(completion.Unix())))
+	})
+
+	It("should record preflight check failures by reason", func() {
+		m1, m2 := restoreTestMachine("m1", 2), restoreTestMachine("m2", 1)
+		conditions.MarkTrue(m1, controlplanev1.MachineAgentHealthyCondition)
+		conditions.MarkTrue(m1, controlplanev1.MachineEtcdMemberHealthyCondition)
+		conditions.MarkTrue(m2, controlplanev1.MachineAgentHealthyCondition)
+
+		r := &RKE2ControlPlaneReconciler{recorder: record.NewFakeRecorder(32)}
+		controlPlane := &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(m1, m2),
+		}
+
+		result := r.preflightChecks(ctx, controlPlane)
+		Expect(result.IsZero()).To(BeFalse())
+
+		Expect(testutil.ToFloat64(preflightCheckFailuresCounter.WithLabelValues(
+			append([]string{string(controlplanev1.MachineEtcdMemberHealthyCondition)}, labels...)...))).To(Equal(1.0))
+		Expect(preflightCheckFailuresCounter.DeleteLabelValues(
+			append([]string{string(controlplanev1.MachineAgentHealthyCondition)}, labels...)...)).To(BeFalse())
+	})
+
+	It("should remove the metrics of a deleted control plane", func() {
+		recordControlPlaneMetrics(rcp, "test", collections.New())
+		recordPreflightCheckFailure(rcp, "test", preflightMachineDeletingReason)
+
+		rcp.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		rcp.Finalizers = nil
+
+		recordControlPlaneMetrics(rcp, "test", collections.New())
+		Expect(replicasGauge.DeleteLabelValues(labels...)).To(BeFalse())
+		Expect(preflightCheckFailuresCounter.DeleteLabelValues(
+			append([]string{preflightMachineDeletingReason}, labels...)...)).To(BeFalse())
+	})
+})
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// ensurePreTerminateHook sets the pre-terminate hook on the control plane machines which don't have it yet,
+// e.g. the machines created before the hook was introduced.
+// The code paths deleting control plane machines must either let reconcilePreTerminateHook run on the following
+// reconciliations, like the scale down and the remediation, or remove the hook themselves, like the deletion of the
+// control plane and the etcd snapshot restore, which pause the normal reconciliation.
+func (r *RKE2ControlPlaneReconciler) ensurePreTerminateHook(ctx context.Context, controlPlane *rke2.ControlPlane) error {
+	machines := controlPlane.Machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		collections.Not(collections.HasAnnotationKey(controlplanev1.PreTerminateHookCleanupAnnotation)),
+	)
+
+	for _, machine := range machines {
+		patchHelper, err := patch.NewHelper(machine, r.Client)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create patch helper for Machine/%s", machine.Name)
+		}
+
+		annotations := machine.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[controlplanev1.PreTerminateHookCleanupAnnotation] = ""
+		machine.SetAnnotations(annotations)
+
+		if err := patchHelper.Patch(ctx, machine); err != nil {
+			return errors.Wrapf(err, "failed to add pre-terminate hook to Machine/%s", machine.Name)
+		}
+	}
+
+	return nil
+}
+
+// reconcilePreTerminateHook forwards the etcd leadership and removes the etcd member of a deleting control plane
+// machine once the machine controller waits for its pre-terminate hook, i.e. once the node has been drained,
+// and then removes the hook so the deletion of the machine proceeds.
+// Deleting machines are processed one at a time, and the reconciliation is requeued while any machine is deleting.
+func (r *RKE2ControlPlaneReconciler) reconcilePreTerminateHook(ctx context.Context, controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+	deletingMachines := controlPlane.Machines.Filter(collections.HasDeletionTimestamp)
+	if deletingMachines.Len() == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	logger := ctrl.LoggerFrom(ctx)
+
+	// Wait for the deleting machines without the pre-terminate hook to go away before running the hook on other machines.
+	for _, machine := range deletingMachines {
+		if _, ok := machine.Annotations[controlplanev1.PreTerminateHookCleanupAnnotation]; !ok {
+			return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+		}
+	}
+
+	// Pick the machine with the oldest deletion timestamp, so the hook is removed from one machine at a time.
+	sortedMachines := deletingMachines.UnsortedList()
+	sort.Slice(sortedMachines, func(i, j int) bool {
+		return sortedMachines[i].DeletionTimestamp.Before(sortedMachines[j].DeletionTimestamp)
+	})
+
+	deletingMachine := sortedMachines[0]
+	logger = logger.WithValues("machine", klog.KObj(deletingMachine))
+
+	// The other pre-terminate hooks run first, so the node is still fully working while they run.
+	if machineHasOtherPreTerminateHooks(deletingMachine) {
+		logger.Info("Waiting for other pre-terminate hooks of the machine to be removed")
+
+		return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+	}
+
+	if !isWaitingForPreTerminateHook(deletingMachine) {
+		return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+	}
+
+	// The last etcd member can't be removed, but the existing servers of an imported cluster are members too.
+	if controlPlane.Machines.Len() > 1 || controlPlane.RCP.Spec.Import != nil {
+		workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to create client to workload cluster")
+		}
+
+		// The machine may have become the etcd leader again since it was selected for deletion,
+		// or may have been deleted outside of the controller, e.g. by a user.
+		etcdLeaderCandidate := controlPlane.Machines.Filter(collections.Not(collections.HasDeletionTimestamp)).Newest()
+		if etcdLeaderCandidate != nil {
+			if err := workloadCluster.ForwardEtcdLeadership(ctx, deletingMachine, etcdLeaderCandidate); err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "failed to move etcd leadership to candidate Machine %s", etcdLeaderCandidate.Name)
+			}
+		} else {
+			logger.Info("Skipping etcd leadership forwarding, there is no other control plane machine without a deletion timestamp")
+		}
+
+		if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, deletingMachine); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to remove etcd member for Machine %s", deletingMachine.Name)
+		}
+	}
+
+	if err := r.removePreTerminateHook(ctx, deletingMachine); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Removed the etcd member and the pre-terminate hook of the deleting machine")
+
+	return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+}
+
+// removePreTerminateHook removes the pre-terminate hook from the machine, if any.
+func (r *RKE2ControlPlaneReconciler) removePreTerminateHook(ctx context.Context, machine *clusterv1.Machine) error {
+	if _, ok := machine.Annotations[controlplanev1.PreTerminateHookCleanupAnnotation]; !ok {
+		return nil
+	}
+
+	patchHelper, err := patch.NewHelper(machine, r.Client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create patch helper for Machine/%s", machine.Name)
+	}
+
+	delete(machine.Annotations, controlplanev1.PreTerminateHookCleanupAnnotation)
+
+	if err := patchHelper.Patch(ctx, machine); err != nil {
+		return errors.Wrapf(err, "failed to remove pre-terminate hook from Machine/%s", machine.Name)
+	}
+
+	return nil
+}
+
+// isWaitingForPreTerminateHook returns true if the deletion of the machine waits for the pre-terminate hook of the controller,
+// i.e. the node has been drained and the other pre-terminate hooks have been removed.
+func isWaitingForPreTerminateHook(machine *clusterv1.Machine) bool {
+	if _, ok := machine.Annotations[controlplanev1.PreTerminateHookCleanupAnnotation]; !ok || machineHasOtherPreTerminateHooks(machine) {
+		return false
+	}
+
+	c := conditions.Get(machine, clusterv1.PreTerminateDeleteHookSucceededCondition)
+
+	return c != nil && c.Status == corev1.ConditionFalse && c.Reason == clusterv1.WaitingExternalHookReason
+}
+
+// machineHasOtherPreTerminateHooks returns true if the machine has pre-terminate hooks other than the one of the controller.
+func machineHasOtherPreTerminateHooks(machine *clusterv1.Machine) bool {
+	for k := range machine.Annotations {
+		if strings.HasPrefix(k, clusterv1.PreTerminateDeleteHookAnnotationPrefix) && k != controlplanev1.PreTerminateHookCleanupAnnotation {
+			return true
+		}
+	}
+
+	return false
+}
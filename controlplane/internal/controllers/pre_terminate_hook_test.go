@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakePreTerminateWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	operations []string
+}
+
+func (f *fakePreTerminateWorkloadCluster) ForwardEtcdLeadership(_ context.Context, machine, leaderCandidate *clusterv1.Machine) error {
+	f.operations = append(f.operations, "forward "+machine.Name+" to "+leaderCandidate.Name)
+
+	return nil
+}
+
+func (f *fakePreTerminateWorkloadCluster) RemoveEtcdMemberForMachine(_ context.Context, machine *clusterv1.Machine) error {
+	f.operations = append(f.operations, "remove "+machine.Name)
+
+	return nil
+}
+
+var _ = Describe("Pre-terminate hook", func() {
+	var (
+		cl         client.Client
+		r          *RKE2ControlPlaneReconciler
+		workload   *fakePreTerminateWorkloadCluster
+		m1, m2, m3 *clusterv1.Machine
+	)
+
+	withHook := func(machines ...*clusterv1.Machine) {
+		for _, machine := range machines {
+			machine.SetAnnotations(map[string]string{controlplanev1.PreTerminateHookCleanupAnnotation: ""})
+		}
+	}
+
+	deleting := func(machine *clusterv1.Machine, waitingForHook bool) {
+		machine.DeletionTimestamp = &metav1.Time{Time: machine.CreationTimestamp.Add(100)}
+		machine.Finalizers = []string{clusterv1.MachineFinalizer}
+
+		if waitingForHook {
+			conditions.MarkFalse(machine, clusterv1.PreTerminateDeleteHookSucceededCondition, clusterv1.WaitingExternalHookReason,
+				clusterv1.ConditionSeverityInfo, "")
+		}
+	}
+
+	controlPlane := func(machines ...*clusterv1.Machine) *rke2.ControlPlane {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+
+		objs := []client.Object{}
+		for _, machine := range machines {
+			objs = append(objs, machine)
+		}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		workload = &fakePreTerminateWorkloadCluster{}
+		r = &RKE2ControlPlaneReconciler{
+			Client:            cl,
+			managementCluster: &fakeManagementCluster{workload: workload},
+		}
+
+		for _, machine := range machines {
+			Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), machine)).To(Succeed())
+		}
+
+		return &rke2.ControlPlane{
+			RCP:      &controlplanev1.RKE2ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"}},
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(machines...),
+		}
+	}
+
+	hasHook := func(machine *clusterv1.Machine) bool {
+		m := &clusterv1.Machine{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(machine), m)).To(Succeed())
+
+		_, ok := m.Annotations[controlplanev1.PreTerminateHookCleanupAnnotation]
+
+		return ok
+	}
+
+	BeforeEach(func() {
+		m1, m2, m3 = restoreTestMachine("m1", 3), restoreTestMachine("m2", 2), restoreTestMachine("m3", 1)
+	})
+
+	It("should set the hook on the machines without it", func() {
+		withHook(m1)
+		deleting(m2, false)
+
+		Expect(r.ensurePreTerminateHook(ctx, controlPlane(m1, m2, m3))).To(Succeed())
+		Expect(hasHook(m1)).To(BeTrue())
+		Expect(hasHook(m2)).To(BeFalse())
+		Expect(hasHook(m3)).To(BeTrue())
+	})
+
+	It("should wait for the machine controller to wait for the hook", func() {
+		withHook(m1, m2, m3)
+		deleting(m1, false)
+
+		result, err := r.reconcilePreTerminateHook(ctx, controlPlane(m1, m2, m3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{RequeueAfter: deleteRequeueAfter}))
+		Expect(workload.operations).To(BeEmpty())
+		Expect(hasHook(m1)).To(BeTrue())
+	})
+
+	It("should wait for the other pre-terminate hooks to be removed", func() {
+		withHook(m1, m2, m3)
+		m1.Annotations[clusterv1.PreTerminateDeleteHookAnnotationPrefix+"/other"] = ""
+		deleting(m1, true)
+
+		result, err := r.reconcilePreTerminateHook(ctx, controlPlane(m1, m2, m3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{RequeueAfter: deleteRequeueAfter}))
+		Expect(workload.operations).To(BeEmpty())
+		Expect(hasHook(m1)).To(BeTrue())
+	})
+
+	It("should remove the etcd member of the drained machine before removing the hook", func() {
+		withHook(m1, m2, m3)
+		deleting(m1, true)
+
+		result, err := r.reconcilePreTerminateHook(ctx, controlPlane(m1, m2, m3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{RequeueAfter: deleteRequeueAfter}))
+		Expect(workload.operations).To(Equal([]string{"forward m1 to m3", "remove m1"}))
+		Expect(hasHook(m1)).To(BeFalse())
+	})
+
+	It("should only remove the hook of the last machine", func() {
+		withHook(m1)
+		deleting(m1, true)
+
+		_, err := r.reconcilePreTerminateHook(ctx, controlPlane(m1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workload.operations).To(BeEmpty())
+		Expect(hasHook(m1)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Pre-terminate hook on control plane deletion", func() {
+	It("should remove the hook of the machines deleted with the control plane", func() {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		rcp := &controlplanev1.RKE2ControlPlane{
+			TypeMeta:   metav1.TypeMeta{APIVersion: controlplanev1.GroupVersion.String(), Kind: "RKE2ControlPlane"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default", UID: "uid"},
+		}
+
+		objs := []client.Object{}
+
+		for _, machine := range []*clusterv1.Machine{restoreTestMachine("m1", 2), restoreTestMachine("m2", 1)} {
+			machine.Labels = map[string]string{
+				clusterv1.ClusterNameLabel:         cluster.Name,
+				clusterv1.MachineControlPlaneLabel: "",
+			}
+			machine.Annotations = map[string]string{controlplanev1.PreTerminateHookCleanupAnnotation: ""}
+			machine.Finalizers = []string{clusterv1.MachineFinalizer}
+			machine.Spec.InfrastructureRef = corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+				Kind:       "GenericInfrastructureMachine",
+				Name:       machine.Name,
+			}
+			machine.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(rcp, controlplanev1.GroupVersion.WithKind("RKE2ControlPlane"))}
+			objs = append(objs, machine)
+		}
+
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		r := &RKE2ControlPlaneReconciler{
+			Client:            cl,
+			managementCluster: &rke2.Management{Client: cl},
+			recorder:          record.NewFakeRecorder(32),
+		}
+
+		result, err := r.reconcileDelete(ctx, cluster, rcp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{RequeueAfter: deleteRequeueAfter}))
+
+		machines := &clusterv1.MachineList{}
+		Expect(cl.List(ctx, machines)).To(Succeed())
+		Expect(machines.Items).To(HaveLen(2))
+
+		for _, machine := range machines.Items {
+			Expect(machine.DeletionTimestamp.IsZero()).To(BeFalse())
+			Expect(machine.Annotations).ToNot(HaveKey(controlplanev1.PreTerminateHookCleanupAnnotation))
+		}
+	})
+})
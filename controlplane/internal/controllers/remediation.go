@@ -0,0 +1,606 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// reconcileUnhealthyMachines tries to remediate RKE2ControlPlane unhealthy machines
+// based on the process described in https://github.com/kubernetes-sigs/cluster-api/blob/main/docs/proposals/20191017-kubeadm-based-control-plane.md#remediation-using-delete-and-recreate
+//
+//nolint:lll
+func (r *RKE2ControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.Context, controlPlane *rke2.ControlPlane) (ret ctrl.Result, retErr error) {
+	logger := ctrl.LoggerFrom(ctx)
+	reconciliationTime := time.Now().UTC()
+
+	// Cleanup pending remediation actions not completed for any reasons (e.g. number of current replicas is less or equal to 1)
+	// if the underlying machine is now back to healthy / not deleting.
+	if err := r.cleanupRemediationOnHealthyMachines(ctx, controlPlane); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Gets all machines that have `MachineHealthCheckSucceeded=False` (indicating a problem was detected on the machine)
+	// and `MachineOwnerRemediated` present, indicating that this controller is responsible for performing remediation.
+	unhealthyMachines := controlPlane.UnhealthyMachines()
+
+	// If there are no unhealthy machines, return so RCP can proceed with other operations (ctrl.Result nil).
+	if len(unhealthyMachines) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	// Select the machine to be remediated.
+	machineToBeRemediated, err := getMachineToBeRemediated(ctx, controlPlane, unhealthyMachines)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to select machine for remediation")
+	}
+
+	// Returns if the machine is in the process of being deleted.
+	if !machineToBeRemediated.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	logger = logger.WithValues("Machine", klog.KObj(machineToBeRemediated), "initialized", controlPlane.RCP.Status.Initialized)
+
+	// Returns if another remediation is in progress but the new Machine is not yet created.
+	// Note: This condition is checked after we check for unhealthy Machines and if machineToBeRemediated
+	// is being deleted to avoid unnecessary logs if no further remediation should be done.
+	if _, ok := controlPlane.RCP.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		logger.Info("Another remediation is already in progress. Skipping remediation.")
+
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(machineToBeRemediated, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	defer func() {
+		// Always attempt to Patch the Machine conditions after each reconcileUnhealthyMachines.
+		if err := patchHelper.Patch(ctx, machineToBeRemediated, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			clusterv1.MachineOwnerRemediatedCondition,
+		}}); err != nil {
+			logger.Error(err, "Failed to patch control plane Machine", "Machine", machineToBeRemediated.Name)
+
+			if retErr == nil {
+				retErr = errors.Wrapf(err, "failed to patch control plane Machine %s", machineToBeRemediated.Name)
+			}
+		}
+	}()
+
+	// Before starting remediation, run preflight checks in order to verify it is safe to remediate.
+	// If any of the following checks fails, we'll surface the reason in the MachineOwnerRemediated condition.
+
+	// Check if RCP is allowed to remediate considering retry limits:
+	// - Remediation cannot happen because retryPeriod is not yet expired.
+	// - RCP already reached MaxRetries limit.
+	remediationInProgressData, canRemediate, err := checkRetryLimits(logger, machineToBeRemediated, controlPlane, reconciliationTime)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !canRemediate {
+		// NOTE: log lines and conditions surfacing why it is not possible to remediate are set by checkRetryLimits.
+		return ctrl.Result{}, nil
+	}
+
+	if controlPlane.RCP.Status.Initialized {
+		canRemediate, result, err := r.remediateInitializedControlPlane(ctx, logger, controlPlane, machineToBeRemediated)
+		if err != nil || !canRemediate {
+			// NOTE: log lines and conditions surfacing why it is not possible to remediate are set by remediateInitializedControlPlane.
+			return result, err
+		}
+	}
+
+	// Delete the machine; its etcd member is removed by the pre-terminate hook, once the machine has been drained.
+	if err := r.Client.Delete(ctx, machineToBeRemediated); err != nil {
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.RemediationFailedReason,
+			clusterv1.ConditionSeverityError,
+			err.Error())
+
+		return ctrl.Result{}, errors.Wrapf(err, "failed to delete unhealthy machine %s", machineToBeRemediated.Name)
+	}
+
+	// Surface the operation is in progress.
+	logger.Info("Remediating unhealthy machine")
+	conditions.MarkFalse(machineToBeRemediated,
+		clusterv1.MachineOwnerRemediatedCondition,
+		clusterv1.RemediationInProgressReason,
+		clusterv1.ConditionSeverityWarning, "")
+	r.recorder.Eventf(controlPlane.RCP, corev1.EventTypeNormal, "MachineRemediation",
+		"Remediating unhealthy control plane Machine %s (retry %d)", machineToBeRemediated.Name, remediationInProgressData.RetryCount)
+
+	// Prepare the info for tracking the remediation progress into the RemediationInProgressAnnotation.
+	remediationInProgressValue, err := remediationInProgressData.Marshal()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Set annotations tracking remediation details so they can be picked up by the machine
+	// that will be created as part of the scale up action that completes the remediation.
+	annotations.AddAnnotations(controlPlane.RCP, map[string]string{
+		controlplanev1.RemediationInProgressAnnotation: remediationInProgressValue,
+	})
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// cleanupRemediationOnHealthyMachines removes the MachineOwnerRemediated condition from machines that went
+// back to healthy before the remediation was completed.
+func (r *RKE2ControlPlaneReconciler) cleanupRemediationOnHealthyMachines(ctx context.Context, controlPlane *rke2.ControlPlane) error {
+	errList := []error{}
+
+	for _, m := range controlPlane.HealthyMachines() {
+		if conditions.IsTrue(m, clusterv1.MachineHealthCheckSucceededCondition) &&
+			conditions.IsFalse(m, clusterv1.MachineOwnerRemediatedCondition) &&
+			m.DeletionTimestamp.IsZero() {
+			patchHelper, err := patch.NewHelper(m, r.Client)
+			if err != nil {
+				errList = append(errList, err)
+
+				continue
+			}
+
+			conditions.Delete(m, clusterv1.MachineOwnerRemediatedCondition)
+
+			if err := patchHelper.Patch(ctx, m, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+				clusterv1.MachineOwnerRemediatedCondition,
+			}}); err != nil {
+				errList = append(errList, err)
+			}
+		}
+	}
+
+	return kerrors.NewAggregate(errList)
+}
+
+// remediateInitializedControlPlane executes the checks that apply only if the control plane is already initialized
+// and, if those are passing, removes the etcd member hosted on the machine to be remediated; it returns true if
+// the machine can be deleted.
+// In this case RCP can remediate only if it can safely assume that the operation preserves the operation state of the
+// existing cluster (or at least it doesn't make it worse).
+// When it is not possible to remediate, the MachineOwnerRemediated condition on the machine is set to false
+// with the WaitingForRemediation or RemediationFailed reason.
+func (r *RKE2ControlPlaneReconciler) remediateInitializedControlPlane(
+	ctx context.Context,
+	logger logr.Logger,
+	controlPlane *rke2.ControlPlane,
+	machineToBeRemediated *clusterv1.Machine,
+) (bool, ctrl.Result, error) {
+	// The cluster MUST have more than one replica, because this is the smallest cluster size that allows any etcd failure tolerance.
+	if controlPlane.Machines.Len() <= 1 {
+		logger.Info("A control plane machine needs remediation, but the number of current replicas is less or equal to 1. Skipping remediation",
+			"Replicas", controlPlane.Machines.Len())
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.WaitingForRemediationReason,
+			clusterv1.ConditionSeverityWarning,
+			"RCP can't remediate if current replicas are less or equal to 1")
+
+		return false, ctrl.Result{}, nil
+	}
+
+	// The cluster MUST NOT have healthy machines still being provisioned. This rule prevents RCP taking actions while the cluster is in a transitional state.
+	if controlPlane.HasHealthyMachineStillProvisioning() {
+		logger.Info("A control plane machine needs remediation, but there are other control-plane machines being provisioned. Skipping remediation")
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.WaitingForRemediationReason,
+			clusterv1.ConditionSeverityWarning,
+			"RCP waiting for control plane machine provisioning to complete before triggering remediation")
+
+		return false, ctrl.Result{}, nil
+	}
+
+	// The cluster MUST have no machines with a deletion timestamp and all the other control plane machines
+	// MUST be healthy. This rule prevents RCP taking actions while the cluster is in a transitional state.
+	if result := r.preflightChecks(ctx, controlPlane, machineToBeRemediated); !result.IsZero() {
+		logger.Info("A control plane machine needs remediation, but the control plane is not passing preflight checks. Skipping remediation")
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.WaitingForRemediationReason,
+			clusterv1.ConditionSeverityWarning,
+			"RCP waiting for the control plane to pass preflight checks before triggering remediation")
+
+		return false, result, nil
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		logger.Error(err, "Failed to create client to workload cluster")
+
+		return false, ctrl.Result{}, errors.Wrapf(err, "failed to create client to workload cluster")
+	}
+
+	// Remediation MUST preserve etcd quorum. This rule ensures that RCP will not remove a member that would result in etcd
+	// losing a majority of members and thus become unable to field new requests.
+	canSafelyRemediate, err := canSafelyRemoveEtcdMember(ctx, workloadCluster, controlPlane, machineToBeRemediated)
+	if err != nil {
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.RemediationFailedReason,
+			clusterv1.ConditionSeverityError,
+			err.Error())
+
+		return false, ctrl.Result{}, err
+	}
+
+	if !canSafelyRemediate {
+		logger.Info("A control plane machine needs remediation, but removing this machine could result in etcd quorum loss. Skipping remediation")
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.WaitingForRemediationReason,
+			clusterv1.ConditionSeverityWarning,
+			"RCP can't remediate this machine because this could result in etcd loosing quorum")
+
+		return false, ctrl.Result{}, nil
+	}
+
+	// If the machine that is about to be deleted is the etcd leader, move it to the newest member available.
+	etcdLeaderCandidate := controlPlane.HealthyMachines().Newest()
+	if etcdLeaderCandidate == nil {
+		logger.Info("A control plane machine needs remediation, but there is no healthy machine to forward etcd leadership to")
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.RemediationFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"A control plane machine needs remediation, but there is no healthy machine to forward etcd leadership to. Skipping remediation")
+
+		return false, ctrl.Result{}, nil
+	}
+
+	if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToBeRemediated, etcdLeaderCandidate); err != nil {
+		logger.Error(err, "Failed to move etcd leadership to candidate machine", "candidate", klog.KObj(etcdLeaderCandidate))
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.RemediationFailedReason,
+			clusterv1.ConditionSeverityError,
+			err.Error())
+
+		return false, ctrl.Result{}, err
+	}
+
+	// NOTE: etcd member removal is performed by the pre-terminate hook, once the machine has been drained.
+
+	return true, ctrl.Result{}, nil
+}
+
+// getMachineToBeRemediated gets the machine to be remediated, which is the oldest machine marked as unhealthy
+// not yet provisioned (if any) or the machine selected among the unhealthy ones with the same criteria used for scale down.
+func getMachineToBeRemediated(
+	ctx context.Context,
+	controlPlane *rke2.ControlPlane,
+	unhealthyMachines collections.Machines,
+) (*clusterv1.Machine, error) {
+	if machine := unhealthyMachines.Filter(collections.Not(collections.HasNode())).Oldest(); machine != nil {
+		return machine, nil
+	}
+
+	machine, err := selectMachineForScaleDown(ctx, controlPlane, unhealthyMachines)
+	if err != nil {
+		return nil, err
+	}
+
+	// selectMachineForScaleDown gives precedence to machines with the delete annotation across the whole control plane,
+	// so make sure the selected machine is actually one of the unhealthy machines.
+	if _, ok := unhealthyMachines[machine.Name]; !ok {
+		return unhealthyMachines.Oldest(), nil
+	}
+
+	return machine, nil
+}
+
+// checkRetryLimits checks if RCP is allowed to remediate considering retry limits:
+// - Remediation cannot happen because retryPeriod is not yet expired.
+// - RCP already reached the maximum number of retries for a machine.
+// NOTE: Counting the number of retries is required In order to prevent infinite remediation e.g. in case the
+// first Control Plane machine is failing due to quota issue.
+func checkRetryLimits(
+	logger logr.Logger,
+	machineToBeRemediated *clusterv1.Machine,
+	controlPlane *rke2.ControlPlane,
+	reconciliationTime time.Time,
+) (*RemediationData, bool, error) {
+	// Get last remediation info from the machine.
+	var lastRemediationData *RemediationData
+
+	if value, ok := machineToBeRemediated.Annotations[controlplanev1.RemediationForAnnotation]; ok {
+		l, err := RemediationDataFromAnnotation(value)
+		if err != nil {
+			return nil, false, err
+		}
+
+		lastRemediationData = l
+	}
+
+	remediationInProgressData := &RemediationData{
+		Machine:    machineToBeRemediated.Name,
+		Timestamp:  metav1.Time{Time: reconciliationTime},
+		RetryCount: 0,
+	}
+
+	// If there is no last remediation, this is the first try of a new retry sequence.
+	if lastRemediationData == nil {
+		return remediationInProgressData, true, nil
+	}
+
+	// Gets MinHealthyPeriod and RetryPeriod from the remediation strategy, or use defaults.
+	strategy := controlPlane.RCP.Spec.RemediationStrategy
+
+	minHealthyPeriod := controlplanev1.DefaultMinHealthyPeriod
+	if strategy != nil && strategy.MinHealthyPeriod != nil {
+		minHealthyPeriod = strategy.MinHealthyPeriod.Duration
+	}
+
+	retryPeriod := time.Duration(0)
+	if strategy != nil {
+		retryPeriod = strategy.RetryPeriod.Duration
+	}
+
+	// Gets the timestamp of the last remediation; if missing, default to a value
+	// that ensures both MinHealthyPeriod and RetryPeriod are expired.
+	// NOTE: this could potentially lead to executing more retries than expected or to executing retries before than
+	// expected, but this is considered acceptable when the system recovers from someone/something changes or deletes
+	// the RemediationForAnnotation on Machines.
+	lastRemediationTime := reconciliationTime.Add(-2 * max(minHealthyPeriod, retryPeriod))
+	if !lastRemediationData.Timestamp.IsZero() {
+		lastRemediationTime = lastRemediationData.Timestamp.Time
+	}
+
+	// Once we get here we already know that there was a last remediation for the Machine.
+	// If the current remediation is happening before minHealthyPeriod is expired, then RCP considers this
+	// as a remediation for the same previously unhealthy machine.
+	// If the retry for the same machine is not in progress, this is the first try of a new retry sequence.
+	if !lastRemediationTime.Add(minHealthyPeriod).After(reconciliationTime) {
+		return remediationInProgressData, true, nil
+	}
+
+	logger = logger.WithValues("RemediationRetryFor", klog.KRef(machineToBeRemediated.Namespace, lastRemediationData.Machine))
+
+	// If the remediation is for the same machine, carry over the retry count.
+	remediationInProgressData.RetryCount = lastRemediationData.RetryCount
+
+	// Check if remediation can happen because retryPeriod is passed.
+	if lastRemediationTime.Add(retryPeriod).After(reconciliationTime) {
+		logger.Info(fmt.Sprintf("A control plane machine needs remediation, but the operation already failed in the latest %s. Skipping remediation",
+			retryPeriod))
+		conditions.MarkFalse(machineToBeRemediated,
+			clusterv1.MachineOwnerRemediatedCondition,
+			clusterv1.WaitingForRemediationReason,
+			clusterv1.ConditionSeverityWarning,
+			"RCP can't remediate this machine because the operation already failed in the latest %s (RetryPeriod)", retryPeriod)
+
+		return remediationInProgressData, false, nil
+	}
+
+	// Check if remediation can happen because of maxRetry is not reached yet, if defined.
+	if strategy != nil && strategy.MaxRetry != nil {
+		maxRetry := int(*strategy.MaxRetry)
+		if remediationInProgressData.RetryCount >= maxRetry {
+			logger.Info(fmt.Sprintf("A control plane machine needs remediation, but the operation already failed %d times (MaxRetry %d). Skipping remediation",
+				remediationInProgressData.RetryCount, maxRetry))
+			conditions.MarkFalse(machineToBeRemediated,
+				clusterv1.MachineOwnerRemediatedCondition,
+				clusterv1.WaitingForRemediationReason,
+				clusterv1.ConditionSeverityWarning,
+				"RCP can't remediate this machine because the operation already failed %d times (MaxRetry)", maxRetry)
+
+			return remediationInProgressData, false, nil
+		}
+	}
+
+	// All the check passed, increase the remediation retry count.
+	remediationInProgressData.RetryCount++
+
+	return remediationInProgressData, true, nil
+}
+
+// canSafelyRemoveEtcdMember assess if it is possible to remove the member hosted on the machine to be remediated
+// without loosing etcd quorum.
+//
+// The answer mostly depend on the existence of other failing members on top of the one being deleted, and according
+// to the etcd fault tolerance specification (see https://etcd.io/docs/v3.3/faq/#what-is-failure-tolerance):
+//   - 3 CP cluster does not tolerate additional failing members on top of the one being deleted (the target
+//     cluster size after deletion is 2, fault tolerance 0)
+//   - 5 CP cluster tolerates 1 additional failing members on top of the one being deleted (the target
+//     cluster size after deletion is 4, fault tolerance 1)
+//   - 7 CP cluster tolerates 2 additional failing members on top of the one being deleted (the target
+//     cluster size after deletion is 6, fault tolerance 2)
+//   - etc.
+//
+// NOTE: this func assumes the list of members in sync with the list of machines/nodes, it is required to call reconcileEtcdMembers
+// as well as reconcileControlPlaneConditions before this.
+func canSafelyRemoveEtcdMember(
+	ctx context.Context,
+	workloadCluster rke2.WorkloadCluster,
+	controlPlane *rke2.ControlPlane,
+	machineToBeRemediated *clusterv1.Machine,
+) (bool, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	// Gets the etcd status.
+	// This makes it possible to have a set of etcd members status different from the MHC unhealthy/unhealthy conditions.
+	etcdMembers, err := workloadCluster.EtcdMembers(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get etcdStatus for workload cluster %s", controlPlane.Cluster.Name)
+	}
+
+	logger.Info("etcd cluster before remediation",
+		"currentTotalMembers", len(etcdMembers),
+		"currentMembers", etcdMembers)
+
+	// Projects the target etcd cluster after remediation, considering all the etcd members except the one being remediated.
+	targetTotalMembers := 0
+	targetUnhealthyMembers := 0
+
+	healthyMembers := []string{}
+	unhealthyMembers := []string{}
+
+	for _, etcdMember := range etcdMembers {
+		// Skip the machine to be deleted because it won't be part of the target etcd cluster.
+		if machineToBeRemediated.Status.NodeRef != nil && machineToBeRemediated.Status.NodeRef.Name == etcdMember {
+			continue
+		}
+
+		// Include the member in the target etcd cluster.
+		targetTotalMembers++
+
+		// Search for the machine corresponding to the etcd member.
+		var machine *clusterv1.Machine
+
+		for _, m := range controlPlane.Machines {
+			if m.Status.NodeRef != nil && m.Status.NodeRef.Name == etcdMember {
+				machine = m
+
+				break
+			}
+		}
+
+		// If an etcd member does not have a corresponding machine it is not possible to retrieve etcd member health,
+		// so RCP is assuming the worst scenario and considering the member unhealthy.
+		//
+		// NOTE: This should not happen given that RCP is running reconcileEtcdMembers before calling this method.
+		if machine == nil {
+			logger.Info("An etcd member does not have a corresponding machine, assuming this member is unhealthy", "MemberName", etcdMember)
+
+			targetUnhealthyMembers++
+
+			unhealthyMembers = append(unhealthyMembers, fmt.Sprintf("%s (no machine)", etcdMember))
+
+			continue
+		}
+
+		// Check member health as reported by machine's health conditions
+		if !conditions.IsTrue(machine, controlplanev1.MachineEtcdMemberHealthyCondition) {
+			targetUnhealthyMembers++
+
+			unhealthyMembers = append(unhealthyMembers, fmt.Sprintf("%s (%s)", etcdMember, machine.Name))
+
+			continue
+		}
+
+		healthyMembers = append(healthyMembers, fmt.Sprintf("%s (%s)", etcdMember, machine.Name))
+	}
+
+	// See https://etcd.io/docs/v3.3/faq/#what-is-failure-tolerance for fault tolerance formula explanation.
+	targetQuorum := (targetTotalMembers / 2.0) + 1 //nolint:gomnd
+	canSafelyRemediate := targetTotalMembers-targetUnhealthyMembers >= targetQuorum
+
+	logger.Info(fmt.Sprintf("etcd cluster projected after remediation of %s", machineToBeRemediated.Name),
+		"healthyMembers", healthyMembers,
+		"unhealthyMembers", unhealthyMembers,
+		"targetTotalMembers", targetTotalMembers,
+		"targetQuorum", targetQuorum,
+		"targetUnhealthyMembers", targetUnhealthyMembers,
+		"canSafelyRemediate", canSafelyRemediate)
+
+	return canSafelyRemediate, nil
+}
+
+// getLastRemediation returns the remediation currently in progress, if any, or the most recent remediation
+// tracked on the machines.
+func getLastRemediation(rcp *controlplanev1.RKE2ControlPlane, machines collections.Machines) (*RemediationData, error) {
+	if v, ok := rcp.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		return RemediationDataFromAnnotation(v)
+	}
+
+	var lastRemediation *RemediationData
+
+	for _, m := range machines.UnsortedList() {
+		v, ok := m.Annotations[controlplanev1.RemediationForAnnotation]
+		if !ok {
+			continue
+		}
+
+		remediationData, err := RemediationDataFromAnnotation(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastRemediation == nil || lastRemediation.Timestamp.Time.Before(remediationData.Timestamp.Time) {
+			lastRemediation = remediationData
+		}
+	}
+
+	return lastRemediation, nil
+}
+
+// RemediationData struct is used to keep track of information stored in the RemediationInProgressAnnotation in RCP
+// during remediation and then into the RemediationForAnnotation on the replacement machine once it is created.
+type RemediationData struct {
+	// Machine is the machine name of the latest machine being remediated.
+	Machine string `json:"machine"`
+
+	// Timestamp is when last remediation happened. It is represented in RFC3339 form and is in UTC.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// RetryCount used to keep track of remediation retry for the last remediated machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails.
+	RetryCount int `json:"retryCount"`
+}
+
+// RemediationDataFromAnnotation gets RemediationData from an annotation value.
+func RemediationDataFromAnnotation(value string) (*RemediationData, error) {
+	ret := &RemediationData{}
+	if err := json.Unmarshal([]byte(value), ret); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal value %s for %s annotation", value, controlplanev1.RemediationForAnnotation)
+	}
+
+	return ret, nil
+}
+
+// Marshal an RemediationData into an annotation value.
+func (r *RemediationData) Marshal() (string, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal value for %s annotation", controlplanev1.RemediationForAnnotation)
+	}
+
+	return string(b), nil
+}
+
+// ToStatus converts a RemediationData into a LastRemediationStatus struct.
+func (r *RemediationData) ToStatus() *controlplanev1.LastRemediationStatus {
+	return &controlplanev1.LastRemediationStatus{
+		Machine:    r.Machine,
+		Timestamp:  r.Timestamp,
+		RetryCount: int32(r.RetryCount),
+	}
+}
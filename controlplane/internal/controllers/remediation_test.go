@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakeWorkloadCluster struct {
+	rke2.WorkloadCluster
+
+	etcdMembers []string
+}
+
+func (f *fakeWorkloadCluster) EtcdMembers(_ context.Context) ([]string, error) {
+	return f.etcdMembers, nil
+}
+
+func remediationTestMachine(name string, etcdHealthy bool) *clusterv1.Machine {
+	m := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Status: clusterv1.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: name},
+		},
+	}
+
+	if etcdHealthy {
+		conditions.MarkTrue(m, controlplanev1.MachineEtcdMemberHealthyCondition)
+	} else {
+		conditions.MarkFalse(m, controlplanev1.MachineEtcdMemberHealthyCondition, "", clusterv1.ConditionSeverityError, "")
+	}
+
+	return m
+}
+
+func remediationDataAnnotation(machine string, timestamp time.Time, retryCount int) string {
+	value, err := (&RemediationData{
+		Machine:    machine,
+		Timestamp:  metav1.Time{Time: timestamp},
+		RetryCount: retryCount,
+	}).Marshal()
+	Expect(err).ToNot(HaveOccurred())
+
+	return value
+}
+
+var _ = Describe("Remediation", func() {
+	Context("checkRetryLimits", func() {
+		var (
+			now          time.Time
+			controlPlane *rke2.ControlPlane
+			machine      *clusterv1.Machine
+		)
+
+		BeforeEach(func() {
+			now = time.Now().UTC()
+			controlPlane = &rke2.ControlPlane{
+				RCP: &controlplanev1.RKE2ControlPlane{},
+			}
+			machine = remediationTestMachine("m1", false)
+		})
+
+		It("should allow the first remediation", func() {
+			data, canRemediate, err := checkRetryLimits(klog.Background(), machine, controlPlane, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canRemediate).To(BeTrue())
+			Expect(data.Machine).To(Equal("m1"))
+			Expect(data.RetryCount).To(Equal(0))
+		})
+
+		It("should start a new retry sequence when MinHealthyPeriod is expired", func() {
+			machine.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("m0", now.Add(-2*time.Hour), 3),
+			}
+
+			data, canRemediate, err := checkRetryLimits(klog.Background(), machine, controlPlane, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canRemediate).To(BeTrue())
+			Expect(data.RetryCount).To(Equal(0))
+		})
+
+		It("should increase the retry count when remediating a replacement machine", func() {
+			machine.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("m0", now.Add(-time.Minute), 1),
+			}
+
+			data, canRemediate, err := checkRetryLimits(klog.Background(), machine, controlPlane, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canRemediate).To(BeTrue())
+			Expect(data.RetryCount).To(Equal(2))
+		})
+
+		It("should wait for RetryPeriod before retrying", func() {
+			controlPlane.RCP.Spec.RemediationStrategy = &controlplanev1.RemediationStrategy{
+				RetryPeriod: metav1.Duration{Duration: 10 * time.Minute},
+			}
+			machine.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("m0", now.Add(-time.Minute), 1),
+			}
+
+			_, canRemediate, err := checkRetryLimits(klog.Background(), machine, controlPlane, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canRemediate).To(BeFalse())
+			Expect(conditions.GetReason(machine, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(clusterv1.WaitingForRemediationReason))
+		})
+
+		It("should stop retrying when MaxRetry is reached", func() {
+			controlPlane.RCP.Spec.RemediationStrategy = &controlplanev1.RemediationStrategy{
+				MaxRetry: ptr.To[int32](2),
+			}
+			machine.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("m0", now.Add(-time.Minute), 2),
+			}
+
+			_, canRemediate, err := checkRetryLimits(klog.Background(), machine, controlPlane, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canRemediate).To(BeFalse())
+			Expect(conditions.GetReason(machine, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(clusterv1.WaitingForRemediationReason))
+		})
+	})
+
+	Context("canSafelyRemoveEtcdMember", func() {
+		It("should allow remediation of a 3 members cluster with no other unhealthy members", func() {
+			m1 := remediationTestMachine("m1", false)
+			controlPlane := &rke2.ControlPlane{
+				Cluster:  &clusterv1.Cluster{},
+				Machines: collections.FromMachines(m1, remediationTestMachine("m2", true), remediationTestMachine("m3", true)),
+			}
+			workload := &fakeWorkloadCluster{etcdMembers: []string{"m1", "m2", "m3"}}
+
+			canSafelyRemediate, err := canSafelyRemoveEtcdMember(ctx, workload, controlPlane, m1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canSafelyRemediate).To(BeTrue())
+		})
+
+		It("should not allow remediation of a 3 members cluster with another unhealthy member", func() {
+			m1 := remediationTestMachine("m1", false)
+			controlPlane := &rke2.ControlPlane{
+				Cluster:  &clusterv1.Cluster{},
+				Machines: collections.FromMachines(m1, remediationTestMachine("m2", false), remediationTestMachine("m3", true)),
+			}
+			workload := &fakeWorkloadCluster{etcdMembers: []string{"m1", "m2", "m3"}}
+
+			canSafelyRemediate, err := canSafelyRemoveEtcdMember(ctx, workload, controlPlane, m1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canSafelyRemediate).To(BeFalse())
+		})
+
+		It("should consider members without a machine as unhealthy", func() {
+			m1 := remediationTestMachine("m1", false)
+			controlPlane := &rke2.ControlPlane{
+				Cluster: &clusterv1.Cluster{},
+				Machines: collections.FromMachines(m1, remediationTestMachine("m2", true), remediationTestMachine("m3", true),
+					remediationTestMachine("m4", true)),
+			}
+			workload := &fakeWorkloadCluster{etcdMembers: []string{"m1", "m2", "m3", "m4", "m5"}}
+
+			canSafelyRemediate, err := canSafelyRemoveEtcdMember(ctx, workload, controlPlane, m1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canSafelyRemediate).To(BeTrue())
+
+			workload.etcdMembers = []string{"m1", "m2", "m3", "m5", "m6"}
+			canSafelyRemediate, err = canSafelyRemoveEtcdMember(ctx, workload, controlPlane, m1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canSafelyRemediate).To(BeFalse())
+		})
+	})
+
+	Context("getLastRemediation", func() {
+		It("should prefer the remediation in progress", func() {
+			now := time.Now().UTC()
+			rcp := &controlplanev1.RKE2ControlPlane{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					controlplanev1.RemediationInProgressAnnotation: remediationDataAnnotation("m1", now, 1),
+				},
+			}}
+			m2 := remediationTestMachine("m2", true)
+			m2.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("m0", now.Add(time.Minute), 0),
+			}
+
+			lastRemediation, err := getLastRemediation(rcp, collections.FromMachines(m2))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastRemediation.Machine).To(Equal("m1"))
+			Expect(lastRemediation.ToStatus().RetryCount).To(Equal(int32(1)))
+		})
+
+		It("should return the most recent remediation tracked on machines", func() {
+			now := time.Now().UTC()
+			rcp := &controlplanev1.RKE2ControlPlane{}
+			m1 := remediationTestMachine("m1", true)
+			m1.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("old", now.Add(-time.Hour), 0),
+			}
+			m2 := remediationTestMachine("m2", true)
+			m2.Annotations = map[string]string{
+				controlplanev1.RemediationForAnnotation: remediationDataAnnotation("recent", now, 0),
+			}
+
+			lastRemediation, err := getLastRemediation(rcp, collections.FromMachines(m1, m2, remediationTestMachine("m3", true)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastRemediation.Machine).To(Equal("recent"))
+		})
+
+		It("should return nil when there are no remediations", func() {
+			lastRemediation, err := getLastRemediation(&controlplanev1.RKE2ControlPlane{}, collections.FromMachines(remediationTestMachine("m1", true)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lastRemediation).To(BeNil())
+		})
+	})
+})
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -49,6 +50,7 @@ import (
 	"sigs.k8s.io/cluster-api/util/patch"
 
 	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/kubeconfig"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/registration"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
@@ -72,6 +74,10 @@ type RKE2ControlPlaneReconciler struct {
 
 	SecretCachingClient client.Client
 
+	// KubeconfigRotationWindow is the time before the expiry of the kubeconfig client certificate
+	// at which the kubeconfig secret is regenerated.
+	KubeconfigRotationWindow time.Duration
+
 	managementClusterUncached rke2.ManagementCluster
 	managementCluster         rke2.ManagementCluster
 	recorder                  record.EventRecorder
@@ -83,6 +89,7 @@ type RKE2ControlPlaneReconciler struct {
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2etcdsnapshotrestores;rke2etcdsnapshotrestores/status,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status;machinesets;machines;machines/status;machinepools;machinepools/status,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets;events;configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="bootstrap.cluster.x-k8s.io",resources=rke2configs,verbs=get;list;watch;create;patch;delete
@@ -235,6 +242,31 @@ func (r *RKE2ControlPlaneReconciler) SetupWithManager(ctx context.Context, mgr c
 		return errors.Wrap(err, "failed adding Watch for Clusters to controller manager")
 	}
 
+	err = c.Watch(
+		source.Kind(mgr.GetCache(), &controlplanev1.RKE2EtcdSnapshotRestore{}),
+		handler.EnqueueRequestsFromMapFunc(r.EtcdSnapshotRestoreToRKE2ControlPlane(ctx)),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed adding Watch for RKE2EtcdSnapshotRestores to controller manager")
+	}
+
+	// Only the metadata of the ConfigMaps and Secrets is cached, to be notified of the changes of the manifests.
+	err = c.Watch(
+		source.Kind(mgr.GetCache(), &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}}),
+		handler.EnqueueRequestsFromMapFunc(r.ConfigMapToRKE2ControlPlane(ctx)),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed adding Watch for manifests ConfigMaps to controller manager")
+	}
+
+	err = c.Watch(
+		source.Kind(mgr.GetCache(), &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}}),
+		handler.EnqueueRequestsFromMapFunc(r.SecretToRKE2ControlPlane(ctx)),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed adding Watch for manifests Secrets to controller manager")
+	}
+
 	r.controller = c
 	r.recorder = mgr.GetEventRecorderFor("rke2-control-plane-controller")
 
@@ -273,12 +305,29 @@ func (r *RKE2ControlPlaneReconciler) SetupWithManager(ctx context.Context, mgr c
 	}
 
 	if r.managementClusterUncached == nil {
-		r.managementClusterUncached = &rke2.Management{Client: mgr.GetClient()}
+		r.managementClusterUncached = &rke2.Management{Client: &uncachedClient{Client: mgr.GetClient(), reader: mgr.GetAPIReader()}}
 	}
 
 	return nil
 }
 
+// uncachedClient is a client reading objects directly from the API server instead of the cache of the manager,
+// e.g. to observe a deletion which is not in the cache yet.
+type uncachedClient struct {
+	client.Client
+	reader client.Reader
+}
+
+// Get reads the object from the API server.
+func (c *uncachedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+// List reads the objects from the API server.
+func (c *uncachedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.reader.List(ctx, list, opts...)
+}
+
 func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *controlplanev1.RKE2ControlPlane, cluster *clusterv1.Cluster) error {
 	logger := log.FromContext(ctx)
 
@@ -290,6 +339,8 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 		return errors.Wrap(err, "failed to get list of owned machines")
 	}
 
+	defer recordControlPlaneMetrics(rcp, cluster.Name, ownedMachines)
+
 	readyMachines := ownedMachines.Filter(collections.IsReady())
 	for _, readyMachine := range readyMachines {
 		logger.V(3).Info("Ready Machine : " + readyMachine.Name)
@@ -303,6 +354,24 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 	}
 
 	rcp.Status.UpdatedReplicas = int32(len(controlPlane.UpToDateMachines()))
+
+	// Surface the lowest version running on ready machines, which agents must not exceed.
+	if lowestVersion := readyMachines.LowestVersion(); lowestVersion != nil {
+		rcp.Status.Version = lowestVersion
+	}
+
+	// Surface lastRemediation data in status.
+	// LastRemediation is the remediation currently in progress, in any, or the
+	// most recent of the remediation we are keeping track on machines.
+	lastRemediation, err := getLastRemediation(rcp, ownedMachines)
+	if err != nil {
+		return err
+	}
+
+	if lastRemediation != nil {
+		rcp.Status.LastRemediation = lastRemediation.ToStatus()
+	}
+
 	replicas := int32(len(ownedMachines))
 	desiredReplicas := *rcp.Spec.Replicas
 
@@ -383,6 +452,11 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 		rcp.Status.Initialized = true
 	}
 
+	// Machines join an imported cluster through its existing servers until the control plane machines are ready.
+	if len(readyMachines) == 0 {
+		rcp.Status.AvailableServerIPs = importedServerAddresses(rcp)
+	}
+
 	if len(ownedMachines) == 0 {
 		logger.Info(fmt.Sprintf("no Control Plane Machines exist for RKE2ControlPlane %s/%s", rcp.Namespace, rcp.Name))
 
@@ -395,7 +469,8 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 		return nil
 	}
 
-	availableCPMachines := readyMachines
+	// New nodes should not join through servers which are being deleted or are unhealthy.
+	availableCPMachines := registration.JoinableMachines(readyMachines)
 
 	registrationmethod, err := registration.NewRegistrationMethod(string(rcp.Spec.RegistrationMethod))
 	if err != nil {
@@ -436,7 +511,7 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 		return ctrl.Result{}, nil
 	}
 
-	certificates := secret.NewCertificatesForInitialControlPlane()
+	certificates := r.controlPlaneCertificates(rcp)
 	controllerRef := metav1.NewControllerRef(rcp, controlplanev1.GroupVersion.WithKind("RKE2ControlPlane"))
 
 	if err := certificates.LookupOrGenerate(ctx, r.Client, util.ObjectKey(cluster), *controllerRef); err != nil {
@@ -451,6 +526,14 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 
 	conditions.MarkTrue(rcp, controlplanev1.CertificatesAvailableCondition)
 
+	if rcp.Spec.Import != nil {
+		if err := r.reconcileImportedToken(ctx, cluster, rcp); err != nil {
+			logger.Error(err, "failed to reconcile the server token of the imported cluster")
+
+			return ctrl.Result{}, err
+		}
+	}
+
 	// If ControlPlaneEndpoint is not set, return early
 	if !cluster.Spec.ControlPlaneEndpoint.IsValid() {
 		logger.Info("Cluster does not yet have a ControlPlaneEndpoint defined")
@@ -479,6 +562,19 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 		return ctrl.Result{}, err
 	}
 
+	// Adopt the control plane machines which are not controlled by any object, then wait
+	// for the update event of the ownership change to reconcile them as owned machines.
+	adoptableMachines := controlPlaneMachines.Filter(collections.AdoptableControlPlaneMachines(cluster.Name))
+	if len(adoptableMachines) > 0 {
+		if err := r.adoptMachines(ctx, rcp, adoptableMachines); err != nil {
+			logger.Error(err, "failed to adopt control plane machines")
+
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
 	ownedMachines := controlPlaneMachines.Filter(collections.OwnedMachines(rcp))
 	if len(ownedMachines) != len(controlPlaneMachines) {
 		logger.Info("Not all control plane machines are owned by this RKE2ControlPlane, refusing to operate in mixed management mode") //nolint:lll
@@ -500,6 +596,12 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 		conditions.AddSourceRef(),
 		conditions.WithStepCounterIf(false))
 
+	// Restores an etcd snapshot requested through a RKE2EtcdSnapshotRestore; the normal reconciliation is paused
+	// until the control plane is reduced to the restored machine, and resumes to join fresh machines.
+	if result, err := r.reconcileEtcdSnapshotRestore(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
 	// Updates conditions reporting the status of static pods and the status of the etcd cluster.
 	// NOTE: Conditions reporting RCP operation progress like e.g. Resized or SpecUpToDate are inlined with the rest of the execution.
 	if result, err := r.reconcileControlPlaneConditions(ctx, controlPlane); err != nil || !result.IsZero() {
@@ -514,19 +616,118 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 		return ctrl.Result{}, err
 	}
 
+	// Sets the pre-terminate hook on the control plane machines, and removes the etcd member of the deleting machines
+	// once they have been drained before removing their hook.
+	if err := r.ensurePreTerminateHook(ctx, controlPlane); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if result, err := r.reconcilePreTerminateHook(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Checks the etcd members database size and alarms, defragmenting members and disarming alarms when needed.
+	if result, err := r.reconcileEtcdMaintenance(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Rotates the server token once TokenRotateAfter has passed, regenerating the bootstrap data of machines not provisioned yet.
+	if result, err := r.reconcileTokenRotation(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Rotates the agent token once AgentTokenRotateAfter has passed, the control plane machines created before being rolled out.
+	if result, err := r.reconcileAgentTokenRotation(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Rotates the secrets encryption keys once SecretsEncryptionKeyRotateAfter has passed, restarting the servers in turn.
+	if result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Records the certificates expiry of control plane machines so that machines approaching
+	// expiry can be rolled out when RolloutBefore is configured.
+	if err := r.reconcileCertificateExpiries(ctx, controlPlane); err != nil {
+		logger.Error(err, "failed to reconcile certificate expiries")
+
+		return ctrl.Result{}, err
+	}
+
+	// Applies the chart values to the HelmChartConfigs of the workload cluster.
+	if err := r.reconcileChartValues(ctx, controlPlane); err != nil {
+		logger.Error(err, "failed to reconcile chart values")
+
+		return ctrl.Result{}, err
+	}
+
+	// Applies the manifests of the ManifestsConfigMapReference and ManifestSources to the workload cluster, and prunes the removed objects.
+	nextManifestsSync, err := r.reconcileManifests(ctx, controlPlane)
+	if err != nil {
+		logger.Error(err, "failed to reconcile manifests")
+
+		return ctrl.Result{}, err
+	}
+
+	// Reconcile unhealthy machines by triggering deletion and requeue if it is considered safe to remediate,
+	// otherwise continue with the other RCP operations.
+	if result, err := r.reconcileUnhealthyMachines(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
 	// Control plane machines rollout due to configuration changes (e.g. upgrades) takes precedence over other operations.
 	needRollout := controlPlane.MachinesNeedingRollout()
+	rolloutStarted := rcp.Status.Rollout != nil && rcp.Status.Rollout.StartTime != nil
+	updateRolloutStatus(controlPlane, needRollout)
+
+	now := time.Now()
+	nextOperation := nextScheduledOperation(rcp, now, nextManifestsSync)
+
+	// Control plane machines can only be replaced within the maintenance window, if any.
+	maintenanceWindowOpen, nextMaintenanceWindow, err := maintenanceWindowState(rcp, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	switch {
-	case len(needRollout) > 0:
-		logger.Info("Rolling out Control Plane machines", "needRollout", needRollout.Names())
+	case len(needRollout) > 0 && !maintenanceWindowOpen:
+		logger.Info("Deferring rollout of Control Plane machines until the next maintenance window",
+			"needRollout", needRollout.Names(), "nextMaintenanceWindow", nextMaintenanceWindow)
+
 		conditions.MarkFalse(controlPlane.RCP,
 			controlplanev1.MachinesSpecUpToDateCondition,
-			controlplanev1.RollingUpdateInProgressReason,
-			clusterv1.ConditionSeverityWarning,
-			"Rolling %d replicas with outdated spec (%d replicas up to date)",
+			controlplanev1.RolloutDeferredReason,
+			clusterv1.ConditionSeverityInfo,
+			"Rolling %d replicas with outdated spec is pending until the next maintenance window at %s",
 			len(needRollout),
-			len(controlPlane.Machines)-len(needRollout))
+			nextMaintenanceWindow.UTC().Format(time.RFC3339))
+	case len(needRollout) > 0:
+		logger.Info("Rolling out Control Plane machines", "needRollout", needRollout.Names())
+
+		if rolloutAfter := controlPlane.MachinesNeedingRolloutAfter(); len(rolloutAfter) > 0 {
+			conditions.MarkFalse(controlPlane.RCP,
+				controlplanev1.MachinesSpecUpToDateCondition,
+				controlplanev1.RollingUpdateInProgressReason,
+				clusterv1.ConditionSeverityWarning,
+				"Rolling %d replicas with outdated spec, %d of them created before rolloutAfter %s (%d replicas up to date)",
+				len(needRollout),
+				len(rolloutAfter),
+				rcp.Spec.RolloutAfter.UTC().Format(time.RFC3339),
+				len(controlPlane.Machines)-len(needRollout))
+		} else {
+			conditions.MarkFalse(controlPlane.RCP,
+				controlplanev1.MachinesSpecUpToDateCondition,
+				controlplanev1.RollingUpdateInProgressReason,
+				clusterv1.ConditionSeverityWarning,
+				"Rolling %d replicas with outdated spec (%d replicas up to date)",
+				len(needRollout),
+				len(controlPlane.Machines)-len(needRollout))
+		}
+
+		// Rollouts can be paused after some machines have been updated, until they are approved.
+		if r.reconcileRolloutPause(ctx, controlPlane) {
+			return ctrl.Result{}, nil
+		}
 
 		return r.upgradeControlPlane(ctx, cluster, rcp, controlPlane, needRollout)
 	default:
@@ -536,13 +737,29 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 		if conditions.Has(controlPlane.RCP, controlplanev1.MachinesSpecUpToDateCondition) {
 			conditions.MarkTrue(controlPlane.RCP, controlplanev1.MachinesSpecUpToDateCondition)
 		}
+
+		if rolloutStarted {
+			recordRolloutCompletion(rcp, cluster.Name, now)
+		}
 	}
 
-	// If we've made it this far, we can assume that all ownedMachines are up to date
+	// If we've made it this far, we can assume that all ownedMachines are up to date,
+	// or that their rollout is deferred until the next maintenance window.
 	numMachines := len(ownedMachines)
 	desiredReplicas := int(*rcp.Spec.Replicas)
 
 	switch {
+	// We are joining the first replica to the existing servers of an imported cluster
+	case numMachines < desiredReplicas && numMachines == 0 && rcp.Spec.Import != nil:
+		if !rcp.Status.Initialized {
+			logger.Info("Waiting for the imported cluster to be reachable before joining control plane machines")
+
+			return ctrl.Result{RequeueAfter: DefaultRequeueTime}, nil
+		}
+
+		logger.Info("Joining the imported control plane", "Desired", desiredReplicas, "Existing", numMachines)
+
+		return r.scaleUpControlPlane(ctx, cluster, rcp, controlPlane)
 	// We are creating the first replica
 	case numMachines < desiredReplicas && numMachines == 0:
 		// Create new Machine w/ init
@@ -562,12 +779,83 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(
 
 	// We are scaling down
 	case numMachines > desiredReplicas:
+		if !maintenanceWindowOpen {
+			logger.Info("Deferring scale down of control plane until the next maintenance window",
+				"Desired", desiredReplicas, "Existing", numMachines, "nextMaintenanceWindow", nextMaintenanceWindow)
+
+			return ctrl.Result{RequeueAfter: shortestRequeueAfter(nextMaintenanceWindow.Sub(now), nextOperation)}, nil
+		}
+
 		logger.Info("Scaling down control plane", "Desired", desiredReplicas, "Existing", numMachines)
 		// The last parameter (i.e. machines needing to be rolled out) should always be empty here.
 		return r.scaleDownControlPlane(ctx, cluster, rcp, controlPlane, collections.Machines{})
 	}
 
-	return ctrl.Result{}, nil
+	if len(needRollout) > 0 {
+		return ctrl.Result{RequeueAfter: shortestRequeueAfter(nextMaintenanceWindow.Sub(now), nextOperation)}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: nextOperation}, nil
+}
+
+// nextScheduledOperation returns how long to wait before the next operation scheduled on the control plane,
+// or zero if none is scheduled.
+func nextScheduledOperation(rcp *controlplanev1.RKE2ControlPlane, now time.Time, nextManifestsSync time.Duration) time.Duration {
+	return shortestRequeueAfter(
+		nextEtcdMaintenance(rcp, now),
+		nextTokenRotation(rcp, now),
+		nextAgentTokenRotation(rcp, now),
+		nextSecretsEncryptionKeyRotation(rcp, now),
+		nextManifestsSync,
+		nextRolloutAfter(rcp, now),
+	)
+}
+
+// nextRolloutAfter returns how long to wait before the machines must be rolled out, if RolloutAfter is in the future.
+func nextRolloutAfter(rcp *controlplanev1.RKE2ControlPlane, now time.Time) time.Duration {
+	rolloutAfter := rcp.Spec.RolloutAfter
+	if rolloutAfter == nil || !rolloutAfter.After(now) {
+		return 0
+	}
+
+	return rolloutAfter.Sub(now)
+}
+
+// shortestRequeueAfter returns the shortest of the given durations, ignoring the zero ones.
+func shortestRequeueAfter(durations ...time.Duration) time.Duration {
+	shortest := time.Duration(0)
+
+	for _, duration := range durations {
+		if duration > 0 && (shortest == 0 || duration < shortest) {
+			shortest = duration
+		}
+	}
+
+	return shortest
+}
+
+// maintenanceWindowState returns whether control plane machines can be replaced at the given time and,
+// if they cannot, when the next maintenance window opens.
+func maintenanceWindowState(rcp *controlplanev1.RKE2ControlPlane, now time.Time) (bool, time.Time, error) {
+	if rcp.Spec.MaintenanceWindow == nil {
+		return true, time.Time{}, nil
+	}
+
+	open, err := rcp.Spec.MaintenanceWindow.IsOpen(now)
+	if err != nil {
+		return false, time.Time{}, errors.Wrap(err, "failed to evaluate maintenance window")
+	}
+
+	if open {
+		return true, time.Time{}, nil
+	}
+
+	next, err := rcp.Spec.MaintenanceWindow.NextOpening(now)
+	if err != nil {
+		return false, time.Time{}, errors.Wrap(err, "failed to evaluate maintenance window")
+	}
+
+	return false, next, nil
 }
 
 // GetWorkloadCluster builds a cluster object.
@@ -583,6 +871,83 @@ func (r *RKE2ControlPlaneReconciler) GetWorkloadCluster(ctx context.Context, con
 	return r.workloadCluster, nil
 }
 
+// reconcileCertificateExpiries reads the kube-apiserver serving certificate expiry of each control plane machine
+// through the workload cluster and records it on the Machine using the certificates expiry annotation.
+// The expiry is read once, and read again once it gets within the RolloutBefore window,
+// as RKE2 renews certificates close to their expiry when the server restarts.
+func (r *RKE2ControlPlaneReconciler) reconcileCertificateExpiries(ctx context.Context, controlPlane *rke2.ControlPlane) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	// Return if there are no RCP-owned control-plane machines.
+	if controlPlane.Machines.Len() == 0 {
+		return nil
+	}
+
+	// Return if RCP is not yet initialized (no API server to contact for checking certificate expiration).
+	if !controlPlane.RCP.Status.Initialized {
+		return nil
+	}
+
+	needRollout := controlPlane.MachinesNeedingRollout()
+	machines := controlPlane.Machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		func(machine *clusterv1.Machine) bool {
+			_, found := machine.GetAnnotations()[clusterv1.MachineCertificatesExpiryDateAnnotation]
+			_, rollingOut := needRollout[machine.Name]
+
+			return !found || rollingOut
+		},
+	)
+	if machines.Len() == 0 {
+		return nil
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		return errors.Wrap(err, "failed to reconcile certificate expiries: cannot get remote client to workload cluster")
+	}
+
+	for _, machine := range machines {
+		if machine.Status.NodeRef == nil {
+			// Skip if the Machine is still provisioning.
+			continue
+		}
+
+		nodeName := machine.Status.NodeRef.Name
+
+		certificateExpiry, err := workloadCluster.GetAPIServerCertificateExpiry(ctx, nodeName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to reconcile certificate expiry for Machine/%s", machine.Name)
+		}
+
+		expiry := certificateExpiry.UTC().Format(time.RFC3339)
+		if machine.GetAnnotations()[clusterv1.MachineCertificatesExpiryDateAnnotation] == expiry {
+			continue
+		}
+
+		log.V(2).Info("Setting certificate expiry", "machine", machine.Name, "node", nodeName, "expiry", expiry)
+
+		patchHelper, err := patch.NewHelper(machine, r.Client)
+		if err != nil {
+			return errors.Wrapf(err, "failed to reconcile certificate expiry for Machine/%s", machine.Name)
+		}
+
+		annotations := machine.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[clusterv1.MachineCertificatesExpiryDateAnnotation] = expiry
+		machine.SetAnnotations(annotations)
+
+		if err := patchHelper.Patch(ctx, machine); err != nil {
+			return errors.Wrapf(err, "failed to reconcile certificate expiry for Machine/%s", machine.Name)
+		}
+	}
+
+	return nil
+}
+
 // reconcileEtcdMembers ensures the number of etcd members is in sync with the number of machines/nodes.
 // This is usually required after a machine deletion.
 //
@@ -619,6 +984,21 @@ func (r *RKE2ControlPlaneReconciler) reconcileEtcdMembers(ctx context.Context, c
 		return errors.Wrap(err, "cannot get remote client to workload cluster")
 	}
 
+	// The existing servers of an imported cluster have no machines: their etcd members are kept as long as their node
+	// exists, and are removed like the members of the machines once the servers are removed from the cluster.
+	if controlPlane.RCP.Spec.Import != nil {
+		controlPlaneNodeNames, err := workloadCluster.ControlPlaneNodeNames(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get the control plane nodes of the imported cluster")
+		}
+
+		for _, nodeName := range controlPlaneNodeNames {
+			if !slices.Contains(nodeNames, nodeName) {
+				nodeNames = append(nodeNames, nodeName)
+			}
+		}
+	}
+
 	parsedVersion, err := semver.ParseTolerant(controlPlane.RCP.GetDesiredVersion())
 	if err != nil {
 		return errors.Wrapf(err, "failed to parse kubernetes version %q", controlPlane.RCP.GetDesiredVersion())
@@ -697,11 +1077,19 @@ func (r *RKE2ControlPlaneReconciler) reconcileDelete(ctx context.Context,
 		return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
 	}
 
+	var errs []error
+
+	// The etcd members don't need to be removed when deleting the whole control plane, and the last member
+	// can't be removed anyway, so the pre-terminate hook is removed from all the machines.
+	for i := range ownedMachines {
+		if err := r.removePreTerminateHook(ctx, ownedMachines[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	// Delete control plane machines in parallel
 	machinesToDelete := ownedMachines.Filter(collections.Not(collections.HasDeletionTimestamp))
 
-	var errs []error
-
 	for i := range machinesToDelete {
 		m := machinesToDelete[i]
 		logger := logger.WithValues("machine", m)
@@ -765,6 +1153,35 @@ func (r *RKE2ControlPlaneReconciler) reconcileKubeconfig(
 		return ctrl.Result{}, nil
 	}
 
+	rotationWindow := r.KubeconfigRotationWindow
+	if rotationWindow == 0 {
+		rotationWindow = consts.DefaultKubeconfigRotationWindow
+	}
+
+	needsRotation, err := kubeconfig.NeedsClientCertRotation(configSecret, rotationWindow)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to check kubeconfig client certificate expiry")
+	}
+
+	if needsRotation {
+		logger.Info("Rotating kubeconfig secret")
+
+		if err := kubeconfig.RegenerateSecret(ctx, r.Client, clusterName, configSecret); err != nil {
+			if errors.Is(err, kubeconfig.ErrDependentCertificateNotFound) {
+				return ctrl.Result{RequeueAfter: dependentCertRequeueAfter}, nil
+			}
+
+			r.recorder.Eventf(rcp, corev1.EventTypeWarning, "KubeconfigRotationFailed",
+				"Failed to rotate kubeconfig secret for cluster %s/%s: %v", clusterName.Namespace, clusterName.Name, err)
+
+			return ctrl.Result{}, errors.Wrap(err, "failed to regenerate kubeconfig")
+		}
+
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "KubeconfigRotated",
+			"Rotated kubeconfig secret for cluster %s/%s: client certificate was expiring within %s",
+			clusterName.Namespace, clusterName.Name, rotationWindow)
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -778,10 +1195,14 @@ func (r *RKE2ControlPlaneReconciler) reconcileControlPlaneConditions(
 	readyCPMachines := controlPlane.Machines.Filter(collections.IsReady())
 
 	if readyCPMachines.Len() == 0 {
-		controlPlane.RCP.Status.Initialized = false
+		// An imported cluster is initialized and served by its existing servers until the control plane machines are ready.
+		if controlPlane.RCP.Spec.Import == nil {
+			controlPlane.RCP.Status.Initialized = false
+		}
+
 		controlPlane.RCP.Status.Ready = false
 		controlPlane.RCP.Status.ReadyReplicas = 0
-		controlPlane.RCP.Status.AvailableServerIPs = nil
+		controlPlane.RCP.Status.AvailableServerIPs = importedServerAddresses(controlPlane.RCP)
 		conditions.MarkFalse(
 			controlPlane.RCP,
 			controlplanev1.AvailableCondition,
@@ -863,30 +1284,49 @@ func (r *RKE2ControlPlaneReconciler) upgradeControlPlane(
 		return ctrl.Result{}, err
 	}
 
+	// The start of the rollout is recorded once, it is kept in the status until all the machines are up to date.
+	if rollout := rcp.Status.Rollout; rollout != nil && rollout.StartTime == nil {
+		startTime := metav1.Now()
+		rollout.StartTime = &startTime
+		recordRolloutStart(rcp, cluster.Name, startTime.Time)
+	}
+
 	switch rcp.Spec.RolloutStrategy.Type {
 	case controlplanev1.RollingUpdateStrategyType:
-		// RolloutStrategy is currently defaulted and validated to be RollingUpdate.
-		// Defaulted to 1 if not specified
-		maxSurge := intstr.FromInt(1)
-		if rcp.Spec.RolloutStrategy.RollingUpdate != nil && rcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge != nil {
-			maxSurge = *rcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge
-		}
-
-		maxNodes := *rcp.Spec.Replicas + int32(maxSurge.IntValue())
-		if int32(controlPlane.Machines.Len()) < maxNodes {
-			// scaleUpControlPlane ensures that we don't continue scaling up while waiting for Machines to have NodeRefs
-			return r.scaleUpControlPlane(ctx, cluster, rcp, controlPlane)
-		}
-
-		return r.scaleDownControlPlane(ctx, cluster, rcp, controlPlane, machinesRequireUpgrade)
+		return r.rollingUpdateControlPlane(ctx, cluster, rcp, controlPlane, machinesRequireUpgrade)
+	case controlplanev1.InPlaceStrategyType:
+		return r.upgradeControlPlaneInPlace(ctx, cluster, rcp, controlPlane, workloadCluster, machinesRequireUpgrade)
 	default:
 		err := fmt.Errorf("unknown rollout strategy type %q", rcp.Spec.RolloutStrategy.Type)
-		logger.Error(err, "RolloutStrategy type is not set to RollingUpdateStrategyType, unable to determine the strategy for rolling out machines")
+		logger.Error(err, "RolloutStrategy type is not set to a known strategy, unable to determine the strategy for rolling out machines")
 
 		return ctrl.Result{}, nil
 	}
 }
 
+// rollingUpdateControlPlane replaces the given machines by scaling up and down the control plane, one machine at a time.
+func (r *RKE2ControlPlaneReconciler) rollingUpdateControlPlane(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	rcp *controlplanev1.RKE2ControlPlane,
+	controlPlane *rke2.ControlPlane,
+	machinesRequireUpgrade collections.Machines,
+) (ctrl.Result, error) {
+	// Defaulted to 1 if not specified
+	maxSurge := intstr.FromInt(1)
+	if rcp.Spec.RolloutStrategy.RollingUpdate != nil && rcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge != nil {
+		maxSurge = *rcp.Spec.RolloutStrategy.RollingUpdate.MaxSurge
+	}
+
+	maxNodes := *rcp.Spec.Replicas + int32(maxSurge.IntValue())
+	if int32(controlPlane.Machines.Len()) < maxNodes {
+		// scaleUpControlPlane ensures that we don't continue scaling up while waiting for Machines to have NodeRefs
+		return r.scaleUpControlPlane(ctx, cluster, rcp, controlPlane)
+	}
+
+	return r.scaleDownControlPlane(ctx, cluster, rcp, controlPlane, machinesRequireUpgrade)
+}
+
 // ClusterToRKE2ControlPlane is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
 // for RKE2ControlPlane based on updates to a Cluster.
 func (r *RKE2ControlPlaneReconciler) ClusterToRKE2ControlPlane(ctx context.Context) handler.MapFunc {
@@ -909,6 +1349,28 @@ func (r *RKE2ControlPlaneReconciler) ClusterToRKE2ControlPlane(ctx context.Conte
 	}
 }
 
+// EtcdSnapshotRestoreToRKE2ControlPlane is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
+// for RKE2ControlPlane based on updates to a RKE2EtcdSnapshotRestore.
+func (r *RKE2ControlPlaneReconciler) EtcdSnapshotRestoreToRKE2ControlPlane(ctx context.Context) handler.MapFunc {
+	log := log.FromContext(ctx)
+
+	return func(ctx context.Context, o client.Object) []ctrl.Request {
+		restore, ok := o.(*controlplanev1.RKE2EtcdSnapshotRestore)
+		if !ok {
+			log.Error(nil, fmt.Sprintf("Expected a RKE2EtcdSnapshotRestore but got a %T", o))
+
+			return nil
+		}
+
+		cluster := &clusterv1.Cluster{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.ClusterName}, cluster); err != nil {
+			return nil
+		}
+
+		return r.ClusterToRKE2ControlPlane(ctx)(ctx, cluster)
+	}
+}
+
 // getWorkloadCluster gets a cluster object.
 // The cluster comes with an etcd client generator to connect to any etcd pod living on a managed machine.
 func (r *RKE2ControlPlaneReconciler) getWorkloadCluster(ctx context.Context, clusterKey types.NamespacedName) (rke2.WorkloadCluster, error) {
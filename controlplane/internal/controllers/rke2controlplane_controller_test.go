@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
@@ -248,3 +250,59 @@ var _ = Describe("Reconclie control plane conditions", func() {
 			"Control plane node missing-machine does not have a corresponding machine"))
 	})
 })
+
+var _ = Describe("Requeue of a deferred rollout", func() {
+	// 2024-06-03 is a Monday.
+	now := time.Date(2024, time.June, 3, 1, 0, 0, 0, time.UTC)
+
+	var rcp *controlplanev1.RKE2ControlPlane
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				MaintenanceWindow: &controlplanev1.MaintenanceWindow{
+					Windows: []controlplanev1.TimeWindow{{Start: "04:00", End: "06:00"}},
+				},
+			},
+		}
+	})
+
+	It("should requeue when the next maintenance window opens", func() {
+		open, next, err := maintenanceWindowState(rcp, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(open).To(BeFalse())
+		Expect(shortestRequeueAfter(next.Sub(now), nextScheduledOperation(rcp, now, 0))).To(Equal(3 * time.Hour))
+	})
+
+	It("should requeue for the operations scheduled before the next maintenance window", func() {
+		rcp.Spec.TokenRotateAfter = &metav1.Time{Time: now.Add(time.Hour)}
+
+		_, next, err := maintenanceWindowState(rcp, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shortestRequeueAfter(next.Sub(now), nextScheduledOperation(rcp, now, 0))).To(Equal(time.Hour))
+		Expect(shortestRequeueAfter(next.Sub(now), nextScheduledOperation(rcp, now, time.Minute))).To(Equal(time.Minute))
+	})
+})
+
+var _ = Describe("Requeue at RolloutAfter", func() {
+	now := time.Date(2024, time.June, 3, 1, 0, 0, 0, time.UTC)
+
+	It("should requeue when RolloutAfter is reached", func() {
+		rcp := &controlplanev1.RKE2ControlPlane{
+			Spec: controlplanev1.RKE2ControlPlaneSpec{RolloutAfter: &metav1.Time{Time: now.Add(2 * time.Hour)}},
+		}
+
+		Expect(nextRolloutAfter(rcp, now)).To(Equal(2 * time.Hour))
+		Expect(nextScheduledOperation(rcp, now, 0)).To(Equal(2 * time.Hour))
+		Expect(nextScheduledOperation(rcp, now, time.Minute)).To(Equal(time.Minute))
+	})
+
+	It("should not requeue for a past RolloutAfter", func() {
+		rcp := &controlplanev1.RKE2ControlPlane{
+			Spec: controlplanev1.RKE2ControlPlaneSpec{RolloutAfter: &metav1.Time{Time: now.Add(-time.Hour)}},
+		}
+
+		Expect(nextRolloutAfter(rcp, now)).To(BeZero())
+		Expect(nextRolloutAfter(&controlplanev1.RKE2ControlPlane{}, now)).To(BeZero())
+	})
+})
@@ -0,0 +1,252 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+const (
+	// etcdSnapshotRequeueAfter is how long to wait before checking again the progress of an etcd snapshot.
+	etcdSnapshotRequeueAfter = 20 * time.Second
+
+	rke2EtcdSnapshotControllerName = "rke2-etcd-snapshot-controller"
+)
+
+// RKE2EtcdSnapshotReconciler reconciles a RKE2EtcdSnapshot object.
+type RKE2EtcdSnapshotReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// remoteClientGetter returns a client for the workload cluster, defaults to remote.NewClusterClient.
+	remoteClientGetter remote.ClusterClientGetter
+	recorder           record.EventRecorder
+}
+
+//nolint:lll
+//+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2etcdsnapshots,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2etcdsnapshots/status,verbs=get;update;patch
+
+// Reconcile takes an on-demand etcd snapshot on a control plane node of the referenced cluster and
+// records its outcome in the RKE2EtcdSnapshot status.
+func (r *RKE2EtcdSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, reterr error) {
+	logger := log.FromContext(ctx)
+
+	snapshot := &controlplanev1.RKE2EtcdSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// Snapshots are taken only once.
+	if snapshot.Status.Phase == controlplanev1.RKE2EtcdSnapshotPhaseSucceeded ||
+		snapshot.Status.Phase == controlplanev1.RKE2EtcdSnapshotPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(snapshot, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to create patch helper")
+	}
+
+	defer func() {
+		if err := patchHelper.Patch(ctx, snapshot, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			controlplanev1.EtcdSnapshotCompletedCondition,
+		}}); err != nil {
+			logger.Error(err, "Failed to patch RKE2EtcdSnapshot")
+			reterr = kerrors.NewAggregate([]error{reterr, err})
+		}
+	}()
+
+	if snapshot.Status.Phase == "" {
+		snapshot.Status.Phase = controlplanev1.RKE2EtcdSnapshotPhasePending
+	}
+
+	cluster, err := bsutil.GetClusterByName(ctx, r.Client, snapshot.Namespace, snapshot.Spec.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to get Cluster %s/%s", snapshot.Namespace, snapshot.Spec.ClusterName)
+	}
+
+	snapshot.OwnerReferences = util.EnsureOwnerRef(snapshot.OwnerReferences, metav1.OwnerReference{
+		APIVersion: clusterv1.GroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       cluster.Name,
+		UID:        cluster.UID,
+	})
+
+	rcp, err := r.getControlPlane(ctx, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if rcp == nil || !rcp.Status.Initialized {
+		logger.Info("Waiting for the control plane to be initialized before taking an etcd snapshot")
+		conditions.MarkFalse(snapshot, controlplanev1.EtcdSnapshotCompletedCondition,
+			controlplanev1.WaitingForControlPlaneReason, clusterv1.ConditionSeverityInfo, "")
+
+		return ctrl.Result{RequeueAfter: etcdSnapshotRequeueAfter}, nil
+	}
+
+	if snapshot.Status.NodeName == "" {
+		nodeName, err := r.selectSnapshotNode(ctx, cluster, snapshot)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if nodeName == "" {
+			logger.Info("Waiting for a healthy control plane machine to take an etcd snapshot")
+			conditions.MarkFalse(snapshot, controlplanev1.EtcdSnapshotCompletedCondition,
+				controlplanev1.WaitingForControlPlaneReason, clusterv1.ConditionSeverityInfo, "No control plane machine available")
+
+			return ctrl.Result{RequeueAfter: etcdSnapshotRequeueAfter}, nil
+		}
+
+		snapshot.Status.NodeName = nodeName
+	}
+
+	remoteClient, err := r.remoteClientGetter(ctx, rke2EtcdSnapshotControllerName, r.Client, util.ObjectKey(cluster))
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "cannot get remote client to workload cluster")
+	}
+
+	workload := &rke2.Workload{Client: remoteClient}
+
+	result, err := workload.SaveEtcdSnapshot(ctx, string(snapshot.UID), snapshot.Status.NodeName, snapshot.GetSnapshotName(),
+		upgradeImage(rcp))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch result.Phase {
+	case rke2.EtcdSnapshotSucceeded:
+		snapshot.Status.Phase = controlplanev1.RKE2EtcdSnapshotPhaseSucceeded
+		snapshot.Status.SnapshotName = result.SnapshotName
+		snapshot.Status.Location = result.Location
+		snapshot.Status.Size = result.Size
+		snapshot.Status.CompletionTime = result.CompletionTime
+		conditions.MarkTrue(snapshot, controlplanev1.EtcdSnapshotCompletedCondition)
+		r.recorder.Eventf(snapshot, corev1.EventTypeNormal, "SnapshotCompleted",
+			"Etcd snapshot %s taken on node %s", result.SnapshotName, snapshot.Status.NodeName)
+
+		return ctrl.Result{}, nil
+	case rke2.EtcdSnapshotFailed:
+		snapshot.Status.Phase = controlplanev1.RKE2EtcdSnapshotPhaseFailed
+		conditions.MarkFalse(snapshot, controlplanev1.EtcdSnapshotCompletedCondition,
+			controlplanev1.EtcdSnapshotFailedReason, clusterv1.ConditionSeverityError, "%s", result.Message)
+		r.recorder.Eventf(snapshot, corev1.EventTypeWarning, "SnapshotFailed",
+			"Etcd snapshot on node %s failed: %s", snapshot.Status.NodeName, result.Message)
+
+		return ctrl.Result{}, nil
+	default:
+		snapshot.Status.Phase = controlplanev1.RKE2EtcdSnapshotPhaseRunning
+		conditions.MarkFalse(snapshot, controlplanev1.EtcdSnapshotCompletedCondition,
+			controlplanev1.EtcdSnapshotInProgressReason, clusterv1.ConditionSeverityInfo, "Taking snapshot on node %s", snapshot.Status.NodeName)
+
+		return ctrl.Result{RequeueAfter: etcdSnapshotRequeueAfter}, nil
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RKE2EtcdSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.remoteClientGetter == nil {
+		r.remoteClientGetter = remote.NewClusterClient
+	}
+
+	r.recorder = mgr.GetEventRecorderFor(rke2EtcdSnapshotControllerName)
+
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1.RKE2EtcdSnapshot{}).
+		Complete(r); err != nil {
+		return errors.Wrap(err, "failed setting up with a controller manager")
+	}
+
+	return nil
+}
+
+// getControlPlane returns the RKE2ControlPlane of the cluster, or nil if the cluster is not managed by a RKE2ControlPlane.
+func (r *RKE2EtcdSnapshotReconciler) getControlPlane(ctx context.Context, cluster *clusterv1.Cluster) (*controlplanev1.RKE2ControlPlane, error) {
+	if cluster.Spec.ControlPlaneRef == nil || cluster.Spec.ControlPlaneRef.Kind != "RKE2ControlPlane" {
+		return nil, nil
+	}
+
+	rcp := &controlplanev1.RKE2ControlPlane{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: cluster.Spec.ControlPlaneRef.Name}, rcp); err != nil {
+		return nil, errors.Wrapf(err, "failed to get RKE2ControlPlane %s/%s", cluster.Namespace, cluster.Spec.ControlPlaneRef.Name)
+	}
+
+	return rcp, nil
+}
+
+// selectSnapshotNode returns the node of the requested machine, or of the oldest ready control plane machine.
+func (r *RKE2EtcdSnapshotReconciler) selectSnapshotNode(
+	ctx context.Context, cluster *clusterv1.Cluster, snapshot *controlplanev1.RKE2EtcdSnapshot,
+) (string, error) {
+	machineList := &clusterv1.MachineList{}
+	if err := r.List(ctx, machineList, client.InNamespace(cluster.Namespace), client.MatchingLabels{
+		clusterv1.ClusterNameLabel:         cluster.Name,
+		clusterv1.MachineControlPlaneLabel: "",
+	}); err != nil {
+		return "", errors.Wrap(err, "failed to list control plane machines")
+	}
+
+	machines := collections.FromMachineList(machineList).Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		collections.HasNode(),
+	)
+
+	if snapshot.Spec.MachineName != "" {
+		machine, ok := machines[snapshot.Spec.MachineName]
+		if !ok {
+			return "", nil
+		}
+
+		return machine.Status.NodeRef.Name, nil
+	}
+
+	machine := machines.Filter(collections.IsReady()).Oldest()
+	if machine == nil {
+		return "", nil
+	}
+
+	return machine.Status.NodeRef.Name, nil
+}
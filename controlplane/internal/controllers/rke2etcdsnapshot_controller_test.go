@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+var _ = Describe("RKE2EtcdSnapshot reconciler", func() {
+	var (
+		cl           client.Client
+		remoteClient client.Client
+		r            *RKE2EtcdSnapshotReconciler
+		snapshot     *controlplanev1.RKE2EtcdSnapshot
+		rcp          *controlplanev1.RKE2ControlPlane
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+		cluster := &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneRef: &corev1.ObjectReference{Kind: "RKE2ControlPlane", Name: "test-control-plane"},
+			},
+		}
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+			Spec:       controlplanev1.RKE2ControlPlaneSpec{Version: "v1.29.3+rke2r1"},
+			Status:     controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-cp-1",
+				Namespace: "default",
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel:         "test",
+					clusterv1.MachineControlPlaneLabel: "",
+				},
+			},
+			Spec: clusterv1.MachineSpec{ClusterName: "test"},
+			Status: clusterv1.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "node-1"},
+			},
+		}
+		conditions.MarkTrue(machine, clusterv1.ReadyCondition)
+
+		snapshot = &controlplanev1.RKE2EtcdSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "before-upgrade", Namespace: "default", UID: "1234"},
+			Spec:       controlplanev1.RKE2EtcdSnapshotSpec{ClusterName: "test"},
+		}
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(cluster, rcp, machine, snapshot).
+			WithStatusSubresource(rcp, snapshot).
+			Build()
+		// The node still runs the previous RKE2 version, as during an upgrade of the control plane.
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.28.9+rke2r1"}},
+		}
+		remoteClient = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(node).Build()
+
+		r = &RKE2EtcdSnapshotReconciler{
+			Client: cl,
+			remoteClientGetter: func(_ context.Context, _ string, _ client.Client, _ client.ObjectKey) (client.Client, error) {
+				return remoteClient, nil
+			},
+			recorder: record.NewFakeRecorder(32),
+		}
+	})
+
+	reconcile := func() *controlplanev1.RKE2EtcdSnapshot {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(snapshot)})
+		Expect(err).ToNot(HaveOccurred())
+
+		updated := &controlplanev1.RKE2EtcdSnapshot{}
+		Expect(cl.Get(ctx, client.ObjectKeyFromObject(snapshot), updated)).To(Succeed())
+
+		return updated
+	}
+
+	snapshotJob := func() *batchv1.Job {
+		jobs := &batchv1.JobList{}
+		Expect(remoteClient.List(ctx, jobs)).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+
+		return &jobs.Items[0]
+	}
+
+	It("should take a snapshot on a control plane node and record its completion", func() {
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotPhaseRunning))
+		Expect(updated.Status.NodeName).To(Equal("node-1"))
+		Expect(updated.OwnerReferences).To(HaveLen(1))
+
+		job := snapshotJob()
+		Expect(job.Spec.Template.Spec.NodeName).To(Equal("node-1"))
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("rancher/rke2-upgrade:v1.28.9-rke2r1"))
+		Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElement(ContainSubstring(`etcd-snapshot save --name "before-upgrade"`)))
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(remoteClient.Status().Update(ctx, job)).To(Succeed())
+
+		updated = reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotPhaseSucceeded))
+		Expect(updated.Status.SnapshotName).To(Equal("before-upgrade-node-1"))
+		Expect(conditions.IsTrue(updated, controlplanev1.EtcdSnapshotCompletedCondition)).To(BeTrue())
+	})
+
+	It("should record a failed snapshot", func() {
+		reconcile()
+
+		job := snapshotJob()
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "boom"}}
+		Expect(remoteClient.Status().Update(ctx, job)).To(Succeed())
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotPhaseFailed))
+		Expect(conditions.GetReason(updated, controlplanev1.EtcdSnapshotCompletedCondition)).To(Equal(controlplanev1.EtcdSnapshotFailedReason))
+		Expect(conditions.GetMessage(updated, controlplanev1.EtcdSnapshotCompletedCondition)).To(Equal("boom"))
+	})
+
+	It("should wait for the control plane to be initialized", func() {
+		rcp.Status.Initialized = false
+		Expect(cl.Status().Update(ctx, rcp)).To(Succeed())
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(controlplanev1.RKE2EtcdSnapshotPhasePending))
+		Expect(conditions.GetReason(updated, controlplanev1.EtcdSnapshotCompletedCondition)).To(Equal(controlplanev1.WaitingForControlPlaneReason))
+	})
+})
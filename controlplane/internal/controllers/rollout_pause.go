@@ -0,0 +1,122 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// updateRolloutStatus reports the progress of the rollout of the control plane machines in the RKE2ControlPlane status.
+// The rollout status, including the approvals of a paused rollout, is cleared once no machines need to be rolled out.
+func updateRolloutStatus(controlPlane *rke2.ControlPlane, needRollout collections.Machines) {
+	rcp := controlPlane.RCP
+
+	if len(needRollout) == 0 {
+		rcp.Status.Rollout = nil
+
+		return
+	}
+
+	if rcp.Status.Rollout == nil {
+		rcp.Status.Rollout = &controlplanev1.RolloutStatus{}
+	}
+
+	pendingMachines := needRollout.Names()
+	sort.Strings(pendingMachines)
+
+	rcp.Status.Rollout.UpdatedMachines = int32(len(controlPlane.Machines) - len(needRollout))
+	rcp.Status.Rollout.PendingMachines = pendingMachines
+}
+
+// reconcileRolloutPause pauses the rollout once the number of updated machines reaches one of the pause points
+// of the rollout pause policy, until the rollout is approved using the RolloutApprovedAnnotation.
+// It returns true while the rollout is paused.
+//
+// NOTE: this func relies on the rollout status, it is required to call updateRolloutStatus before this.
+func (r *RKE2ControlPlaneReconciler) reconcileRolloutPause(ctx context.Context, controlPlane *rke2.ControlPlane) bool {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+	rollout := rcp.Status.Rollout
+
+	if rcp.Spec.RolloutStrategy == nil || rcp.Spec.RolloutStrategy.PausePolicy == nil || rollout == nil {
+		return false
+	}
+
+	// Pause only between two replacements, once the outdated machine replaced by the last new machine has been removed.
+	if int32(controlPlane.Machines.Len()) > *rcp.Spec.Replicas {
+		return false
+	}
+
+	pause := false
+
+	for _, pauseAfter := range rcp.Spec.RolloutStrategy.PausePolicy.PauseAfter {
+		if rollout.UpdatedMachines >= pauseAfter && pauseAfter > rollout.ApprovedMachines {
+			pause = true
+		}
+	}
+
+	if !pause {
+		rollout.Paused = false
+
+		return false
+	}
+
+	if _, ok := rcp.Annotations[controlplanev1.RolloutApprovedAnnotation]; ok {
+		logger.Info("Resuming approved rollout", "updatedMachines", rollout.UpdatedMachines)
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "RolloutApproved",
+			"Rollout approved after %d updated machines, %d machines pending", rollout.UpdatedMachines, len(rollout.PendingMachines))
+
+		delete(rcp.Annotations, controlplanev1.RolloutApprovedAnnotation)
+		rollout.ApprovedMachines = rollout.UpdatedMachines
+		rollout.Paused = false
+
+		return false
+	}
+
+	if !rollout.Paused {
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "RolloutPaused",
+			"Rollout paused after %d updated machines, waiting for approval with the %s annotation",
+			rollout.UpdatedMachines, controlplanev1.RolloutApprovedAnnotation)
+	}
+
+	logger.Info("Rollout paused, waiting for approval", "updatedMachines", rollout.UpdatedMachines,
+		"pendingMachines", rollout.PendingMachines)
+
+	rollout.Paused = true
+
+	conditions.MarkFalse(rcp,
+		controlplanev1.MachinesSpecUpToDateCondition,
+		controlplanev1.RolloutPausedReason,
+		clusterv1.ConditionSeverityInfo,
+		"Rollout paused after %d updated replicas, waiting for approval with the %s annotation (%d replicas pending)",
+		rollout.UpdatedMachines,
+		controlplanev1.RolloutApprovedAnnotation,
+		len(rollout.PendingMachines))
+
+	return true
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("Rollout pause policy", func() {
+	var (
+		r            *RKE2ControlPlaneReconciler
+		rcp          *controlplanev1.RKE2ControlPlane
+		controlPlane *rke2.ControlPlane
+		outdated     collections.Machines
+	)
+
+	machine := func(name string) *clusterv1.Machine {
+		return &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+
+	BeforeEach(func() {
+		r = &RKE2ControlPlaneReconciler{recorder: record.NewFakeRecorder(32)}
+		rcp = &controlplanev1.RKE2ControlPlane{
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Replicas: ptr.To[int32](3),
+				RolloutStrategy: &controlplanev1.RolloutStrategy{
+					Type:        controlplanev1.RollingUpdateStrategyType,
+					PausePolicy: &controlplanev1.RolloutPausePolicy{PauseAfter: []int32{1}},
+				},
+			},
+		}
+		outdated = collections.FromMachines(machine("old-2"), machine("old-3"))
+		controlPlane = &rke2.ControlPlane{
+			RCP:      rcp,
+			Machines: collections.FromMachines(machine("new-1"), machine("old-2"), machine("old-3")),
+		}
+	})
+
+	It("should pause the rollout after the first replaced machine until it is approved", func() {
+		updateRolloutStatus(controlPlane, outdated)
+		Expect(rcp.Status.Rollout.UpdatedMachines).To(Equal(int32(1)))
+		Expect(rcp.Status.Rollout.PendingMachines).To(Equal([]string{"old-2", "old-3"}))
+
+		Expect(r.reconcileRolloutPause(ctx, controlPlane)).To(BeTrue())
+		Expect(rcp.Status.Rollout.Paused).To(BeTrue())
+		Expect(conditions.GetReason(rcp, controlplanev1.MachinesSpecUpToDateCondition)).To(Equal(controlplanev1.RolloutPausedReason))
+
+		rcp.Annotations = map[string]string{controlplanev1.RolloutApprovedAnnotation: ""}
+
+		Expect(r.reconcileRolloutPause(ctx, controlPlane)).To(BeFalse())
+		Expect(rcp.Annotations).ToNot(HaveKey(controlplanev1.RolloutApprovedAnnotation))
+		Expect(rcp.Status.Rollout.Paused).To(BeFalse())
+		Expect(rcp.Status.Rollout.ApprovedMachines).To(Equal(int32(1)))
+
+		// The approval holds for the rest of the rollout.
+		Expect(r.reconcileRolloutPause(ctx, controlPlane)).To(BeFalse())
+
+		updateRolloutStatus(controlPlane, collections.Machines{})
+		Expect(rcp.Status.Rollout).To(BeNil())
+	})
+
+	It("should not pause while the replaced machine is still being removed", func() {
+		controlPlane.Machines.Insert(machine("old-1"))
+		outdated.Insert(machine("old-1"))
+
+		updateRolloutStatus(controlPlane, outdated)
+		Expect(r.reconcileRolloutPause(ctx, controlPlane)).To(BeFalse())
+	})
+
+	It("should not pause without a pause policy", func() {
+		rcp.Spec.RolloutStrategy.PausePolicy = nil
+
+		updateRolloutStatus(controlPlane, outdated)
+		Expect(r.reconcileRolloutPause(ctx, controlPlane)).To(BeFalse())
+	})
+})
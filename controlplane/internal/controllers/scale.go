@@ -28,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/storage/names"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -133,6 +134,15 @@ func (r *RKE2ControlPlaneReconciler) scaleDownControlPlane(
 ) (ctrl.Result, error) {
 	logger := controlPlane.Logger()
 
+	if rcp.Spec.ScaleDownPolicy == controlplanev1.AvoidEtcdLeaderScaleDownPolicy {
+		leader, err := r.workloadCluster.EtcdLeader(ctx)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to get etcd leader")
+		}
+
+		controlPlane.EtcdLeaderNodeName = leader
+	}
+
 	// Pick the Machine that we should scale down.
 	machineToDelete, err := selectMachineForScaleDown(ctx, controlPlane, outdatedMachines)
 	if err != nil {
@@ -151,20 +161,8 @@ func (r *RKE2ControlPlaneReconciler) scaleDownControlPlane(
 		return ctrl.Result{}, errors.New("failed to pick control plane Machine to delete")
 	}
 
-	// If etcd leadership is on machine that is about to be deleted, move it to the newest member available.
-	etcdLeaderCandidate := controlPlane.Machines.Newest()
-	if err := r.workloadCluster.ForwardEtcdLeadership(ctx, machineToDelete, etcdLeaderCandidate); err != nil {
-		logger.Error(err, "Failed to move leadership to candidate machine", "candidate", etcdLeaderCandidate.Name)
-
-		return ctrl.Result{}, err
-	}
-
-	if err := r.workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToDelete); err != nil {
-		logger.Error(err, "Failed to remove etcd member for machine")
-
-		return ctrl.Result{}, err
-	}
-
+	// NOTE: etcd leadership forwarding and etcd member removal are performed by the pre-terminate hook,
+	// once the machine has been drained.
 	logger = logger.WithValues("machine", machineToDelete)
 	if err := r.Client.Delete(ctx, machineToDelete); err != nil && !apierrors.IsNotFound(err) {
 		logger.Error(err, "Failed to delete control plane machine")
@@ -205,6 +203,7 @@ func (r *RKE2ControlPlaneReconciler) preflightChecks(
 			strings.Join(controlPlane.Machines.Filter(collections.HasDeletionTimestamp).Names(),
 				", ",
 			))
+		recordPreflightCheckFailure(controlPlane.RCP, controlPlane.Cluster.Name, preflightMachineDeletingReason)
 
 		return ctrl.Result{RequeueAfter: deleteRequeueAfter}
 	}
@@ -215,6 +214,7 @@ func (r *RKE2ControlPlaneReconciler) preflightChecks(
 		controlplanev1.MachineEtcdMemberHealthyCondition,
 	}
 	machineErrors := []error{}
+	failedConditions := sets.Set[clusterv1.ConditionType]{}
 
 loopmachines:
 	for _, machine := range controlPlane.Machines {
@@ -229,6 +229,7 @@ loopmachines:
 		for _, condition := range allMachineHealthConditions {
 			if err := preflightCheckCondition("machine", machine, condition); err != nil {
 				machineErrors = append(machineErrors, err)
+				failedConditions.Insert(condition)
 			}
 		}
 	}
@@ -239,6 +240,10 @@ loopmachines:
 			"Waiting for control plane to pass preflight checks to continue reconciliation: %v", aggregatedError)
 		logger.Info("Waiting for control plane to pass preflight checks", "failures", aggregatedError.Error())
 
+		for _, condition := range sets.List(failedConditions) {
+			recordPreflightCheckFailure(controlPlane.RCP, controlPlane.Cluster.Name, string(condition))
+		}
+
 		return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}
 	}
 
@@ -262,22 +267,38 @@ func preflightCheckCondition(kind string, obj conditions.Getter, condition clust
 	return nil
 }
 
+// selectMachineForScaleDown selects the machine to remove, giving precedence to machines with the delete annotation,
+// then to outdated machines and then to not ready machines. Machines protected by the ScaleDownProtectedAnnotation
+// are never selected, unless they are not ready or unhealthy.
 func selectMachineForScaleDown(
 	ctx context.Context,
 	controlPlane *rke2.ControlPlane,
 	outdatedMachines collections.Machines,
 ) (*clusterv1.Machine, error) {
-	machines := controlPlane.Machines
+	machines := controlPlane.MachinesEligibleForScaleDown(controlPlane.Machines)
+
+	// Removing an up-to-date machine while all the outdated machines are protected would only
+	// cause it to be created again, so wait for the protection to be removed instead.
+	eligibleOutdatedMachines := controlPlane.MachinesEligibleForScaleDown(outdatedMachines)
+	if outdatedMachines.Len() > 0 && eligibleOutdatedMachines.Len() == 0 {
+		return nil, errors.Errorf("outdated machines %s are protected from scale down with the %s annotation",
+			strings.Join(outdatedMachines.Names(), ", "), controlplanev1.ScaleDownProtectedAnnotation)
+	}
+
+	if machines.Len() == 0 {
+		return nil, errors.Errorf("all the control plane machines are protected from scale down with the %s annotation",
+			controlplanev1.ScaleDownProtectedAnnotation)
+	}
 
 	switch {
-	case controlPlane.MachineWithDeleteAnnotation(outdatedMachines).Len() > 0:
-		machines = controlPlane.MachineWithDeleteAnnotation(outdatedMachines)
+	case controlPlane.MachineWithDeleteAnnotation(eligibleOutdatedMachines).Len() > 0:
+		machines = controlPlane.MachineWithDeleteAnnotation(eligibleOutdatedMachines)
 		controlPlane.Logger().V(5).Info("Inside the withDeleteAnnotation-outdated case", "machines", machines.Names())
 	case controlPlane.MachineWithDeleteAnnotation(machines).Len() > 0:
 		machines = controlPlane.MachineWithDeleteAnnotation(machines)
 		controlPlane.Logger().V(5).Info("Inside the withDeleteAnnotation case", "machines", machines.Names())
-	case outdatedMachines.Len() > 0:
-		machines = outdatedMachines
+	case eligibleOutdatedMachines.Len() > 0:
+		machines = eligibleOutdatedMachines
 		controlPlane.Logger().V(5).Info("Inside the Outdated case", "machines", machines.Names())
 	case machines.Filter(collections.Not(collections.IsReady())).Len() > 0:
 		machines = machines.Filter(collections.Not(collections.IsReady()))
@@ -447,11 +468,27 @@ func (r *RKE2ControlPlaneReconciler) generateMachine(
 		return errors.Wrap(err, "failed to marshal cluster configuration")
 	}
 
-	machine.SetAnnotations(map[string]string{controlplanev1.RKE2ServerConfigurationAnnotation: string(serverConfig)})
+	machineAnnotations := map[string]string{
+		controlplanev1.RKE2ServerConfigurationAnnotation: string(serverConfig),
+		controlplanev1.PreTerminateHookCleanupAnnotation: "",
+	}
+
+	// In case this machine is being created as a consequence of a remediation, then add an annotation
+	// tracking remediating data.
+	// NOTE: This is required in order to track remediation retries.
+	if remediationData, ok := rcp.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		machineAnnotations[controlplanev1.RemediationForAnnotation] = remediationData
+	}
+
+	machine.SetAnnotations(machineAnnotations)
 
 	if err := r.Client.Create(ctx, machine); err != nil {
 		return errors.Wrap(err, "failed to create machine")
 	}
 
+	// Remove the annotation tracking that a remediation is in progress (the remediation completed when
+	// the replacement machine has been created above).
+	delete(rcp.Annotations, controlplanev1.RemediationInProgressAnnotation)
+
 	return nil
 }
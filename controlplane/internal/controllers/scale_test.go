@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("Machine selection for scale down", func() {
+	var (
+		m1, m2, m3   *clusterv1.Machine
+		controlPlane *rke2.ControlPlane
+	)
+
+	protect := func(machine *clusterv1.Machine) {
+		machine.SetAnnotations(map[string]string{controlplanev1.ScaleDownProtectedAnnotation: ""})
+	}
+
+	BeforeEach(func() {
+		m1, m2, m3 = restoreTestMachine("m1", 3), restoreTestMachine("m2", 2), restoreTestMachine("m3", 1)
+		controlPlane = &rke2.ControlPlane{
+			RCP:      &controlplanev1.RKE2ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"}},
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(m1, m2, m3),
+		}
+	})
+
+	It("should select the oldest machine by default", func() {
+		machine, err := selectMachineForScaleDown(ctx, controlPlane, collections.New())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m1"))
+	})
+
+	It("should select the newest machine with the Newest policy", func() {
+		controlPlane.RCP.Spec.ScaleDownPolicy = controlplanev1.NewestScaleDownPolicy
+
+		machine, err := selectMachineForScaleDown(ctx, controlPlane, collections.FromMachines(m1, m2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m2"))
+	})
+
+	It("should not select the etcd leader with the AvoidEtcdLeader policy", func() {
+		controlPlane.RCP.Spec.ScaleDownPolicy = controlplanev1.AvoidEtcdLeaderScaleDownPolicy
+		controlPlane.EtcdLeaderNodeName = "node-m1"
+
+		machine, err := selectMachineForScaleDown(ctx, controlPlane, collections.New())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m2"))
+
+		machine, err = selectMachineForScaleDown(ctx, controlPlane, collections.FromMachines(m1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m1"))
+	})
+
+	It("should not select protected machines unless they are unhealthy", func() {
+		protect(m1)
+
+		machine, err := selectMachineForScaleDown(ctx, controlPlane, collections.New())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m2"))
+
+		_, err = selectMachineForScaleDown(ctx, controlPlane, collections.FromMachines(m1))
+		Expect(err).To(HaveOccurred())
+
+		conditions.MarkFalse(m1, clusterv1.MachineHealthCheckSucceededCondition, clusterv1.MachineHasFailureReason,
+			clusterv1.ConditionSeverityWarning, "")
+		conditions.MarkFalse(m1, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason,
+			clusterv1.ConditionSeverityWarning, "")
+
+		machine, err = selectMachineForScaleDown(ctx, controlPlane, collections.FromMachines(m1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m1"))
+	})
+
+	It("should not select protected machines even with the delete annotation", func() {
+		protect(m2)
+		m2.Annotations[clusterv1.DeleteMachineAnnotation] = ""
+
+		machine, err := selectMachineForScaleDown(ctx, controlPlane, collections.New())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(machine.Name).To(Equal("m1"))
+	})
+})
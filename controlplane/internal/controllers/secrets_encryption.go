@@ -0,0 +1,234 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// secretsEncryptionKeyRotationRequeueAfter is how long to wait before checking a running step of the key rotation again.
+const secretsEncryptionKeyRotationRequeueAfter = 10 * time.Second
+
+// secretsEncryptionKeyRotationStages are the stages of the rotation of the secrets encryption keys, in order,
+// with the `rke2 secrets-encrypt` command run on the first server at the start of each stage.
+var secretsEncryptionKeyRotationStages = []struct {
+	stage   controlplanev1.SecretsEncryptionKeyRotationStage
+	command string
+}{
+	{stage: controlplanev1.SecretsEncryptionKeyRotationPrepareStage, command: rke2.SecretsEncryptionPrepareCommand},
+	{stage: controlplanev1.SecretsEncryptionKeyRotationRotateStage, command: rke2.SecretsEncryptionRotateCommand},
+	{stage: controlplanev1.SecretsEncryptionKeyRotationReencryptStage, command: rke2.SecretsEncryptionReencryptCommand},
+}
+
+// reconcileSecretsEncryptionKeyRotation rotates the keys encrypting the Kubernetes Secrets once SecretsEncryptionKeyRotateAfter
+// has passed. For each stage of the rotation, the `rke2 secrets-encrypt` command is run on the oldest server, then all
+// the servers are restarted one at a time, oldest first, so that they all use the same encryption configuration before
+// the next stage. It returns a non-zero result while the rotation is running, so that the control plane is not changed
+// until all the servers are restarted. The rotation is not started or continued while servers are joining or leaving
+// the cluster; it then returns a zero result, so that remediation and scaling can proceed, and the rotation is resumed
+// by the requeue of nextSecretsEncryptionKeyRotation. A failed step is only reported, so that it does not block the
+// other operations on the control plane.
+func (r *RKE2ControlPlaneReconciler) reconcileSecretsEncryptionKeyRotation(
+	ctx context.Context,
+	controlPlane *rke2.ControlPlane,
+) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+
+	if rcp.Spec.SecretsEncryptionKeyRotateAfter == nil {
+		rcp.Status.SecretsEncryptionKeyRotation = nil
+
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	if !secretsEncryptionKeyRotationDue(rcp, now) || !rcp.Status.Initialized {
+		return ctrl.Result{}, nil
+	}
+
+	nodeNames := []string{}
+
+	for _, machine := range controlPlane.Machines.SortedByCreationTimestamp() {
+		// Do not rotate the keys while servers are joining or leaving the cluster.
+		if machine.Status.NodeRef == nil || !machine.DeletionTimestamp.IsZero() {
+			logger.Info("Waiting for the control plane machines to be stable to rotate the secrets encryption keys")
+
+			return ctrl.Result{}, nil
+		}
+
+		nodeNames = append(nodeNames, machine.Status.NodeRef.Name)
+	}
+
+	if len(nodeNames) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	rotation := rcp.Status.SecretsEncryptionKeyRotation
+	if rotation == nil {
+		rotation = &controlplanev1.SecretsEncryptionKeyRotationStatus{Stage: secretsEncryptionKeyRotationStages[0].stage}
+		rcp.Status.SecretsEncryptionKeyRotation = rotation
+
+		r.recorder.Event(rcp, corev1.EventTypeNormal, "SecretsEncryptionKeyRotationStarted", "Rotating the secrets encryption keys")
+	}
+
+	stageIndex := -1
+
+	for i, s := range secretsEncryptionKeyRotationStages {
+		if s.stage == rotation.Stage {
+			stageIndex = i
+		}
+	}
+
+	if stageIndex < 0 {
+		return ctrl.Result{}, errors.Errorf("unknown secrets encryption key rotation stage %q", rotation.Stage)
+	}
+
+	nodeIndex := slices.IndexFunc(nodeNames, func(nodeName string) bool {
+		return !slices.Contains(rotation.RestartedNodes, nodeName)
+	})
+
+	// The command of the stage is run on the first server, before it is restarted.
+	command := ""
+	if len(rotation.RestartedNodes) == 0 {
+		command = secretsEncryptionKeyRotationStages[stageIndex].command
+	}
+
+	if nodeIndex >= 0 {
+		nodeName := nodeNames[nodeIndex]
+		id := secretsEncryptionKeyRotationStepID(rcp, rotation.Stage, nodeName)
+
+		workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "cannot get remote client to workload cluster")
+		}
+
+		phase, message, err := workloadCluster.RunSecretsEncryptionStep(ctx, id, nodeName, command, upgradeImage(rcp))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		switch phase { //nolint:exhaustive
+		case rke2.SecretsEncryptionStepRunning:
+			logger.Info("Waiting for the secrets encryption key rotation step to complete", "stage", rotation.Stage, "node", nodeName)
+			conditions.MarkFalse(rcp,
+				controlplanev1.SecretsEncryptionKeyRotationCondition,
+				controlplanev1.SecretsEncryptionKeyRotationInProgressReason,
+				clusterv1.ConditionSeverityInfo,
+				"Running the %s stage on Node %s", rotation.Stage, nodeName)
+
+			return ctrl.Result{RequeueAfter: secretsEncryptionKeyRotationRequeueAfter}, nil
+		case rke2.SecretsEncryptionStepFailed:
+			conditions.MarkFalse(rcp,
+				controlplanev1.SecretsEncryptionKeyRotationCondition,
+				controlplanev1.SecretsEncryptionKeyRotationFailedReason,
+				clusterv1.ConditionSeverityWarning,
+				"Failed to run the %s stage on Node %s: %s", rotation.Stage, nodeName, message)
+			r.recorder.Eventf(rcp, corev1.EventTypeWarning, "SecretsEncryptionKeyRotationFailed",
+				"Failed to run the %s stage on Node %s: %s", rotation.Stage, nodeName, message)
+
+			return ctrl.Result{}, nil
+		}
+
+		if err := workloadCluster.CleanupSecretsEncryptionStep(ctx, id); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		rotation.RestartedNodes = append(rotation.RestartedNodes, nodeName)
+
+		if nodeIndex < len(nodeNames)-1 {
+			return ctrl.Result{Requeue: true}, nil
+		}
+	}
+
+	// All the servers were restarted, move on to the next stage.
+	if stageIndex < len(secretsEncryptionKeyRotationStages)-1 {
+		rotation.Stage = secretsEncryptionKeyRotationStages[stageIndex+1].stage
+		rotation.RestartedNodes = nil
+
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	rcp.Status.SecretsEncryptionKeyRotation = nil
+	rcp.Status.LastSecretsEncryptionKeyRotationTime = &metav1.Time{Time: now}
+	conditions.MarkTrue(rcp, controlplanev1.SecretsEncryptionKeyRotationCondition)
+
+	r.recorder.Event(rcp, corev1.EventTypeNormal, "SecretsEncryptionKeysRotated", "Secrets encryption keys rotated")
+
+	return ctrl.Result{}, nil
+}
+
+// secretsEncryptionKeyRotationStepID identifies a step of the rotation by the requested time, so that a new rotation
+// is run each time SecretsEncryptionKeyRotateAfter is changed.
+func secretsEncryptionKeyRotationStepID(
+	rcp *controlplanev1.RKE2ControlPlane,
+	stage controlplanev1.SecretsEncryptionKeyRotationStage,
+	nodeName string,
+) string {
+	return string(rcp.UID) + "-" + rcp.Spec.SecretsEncryptionKeyRotateAfter.UTC().Format(time.RFC3339) + "-" + string(stage) + "-" + nodeName
+}
+
+// secretsEncryptionKeyRotationDue returns true if SecretsEncryptionKeyRotateAfter has passed and the keys have not been
+// rotated since. The keys of a custom encryption configuration are not managed by RKE2 and cannot be rotated.
+func secretsEncryptionKeyRotationDue(rcp *controlplanev1.RKE2ControlPlane, now time.Time) bool {
+	rotateAfter := rcp.Spec.SecretsEncryptionKeyRotateAfter
+	if rotateAfter == nil || now.Before(rotateAfter.Time) {
+		return false
+	}
+
+	if secretsEncryption := rcp.Spec.ServerConfig.SecretsEncryption; secretsEncryption != nil &&
+		(secretsEncryption.EncryptionConfigSecret != nil || (secretsEncryption.Enabled != nil && !*secretsEncryption.Enabled)) {
+		return false
+	}
+
+	last := rcp.Status.LastSecretsEncryptionKeyRotationTime
+
+	return last == nil || last.Before(rotateAfter)
+}
+
+// nextSecretsEncryptionKeyRotation returns how long to wait before the secrets encryption keys must be rotated,
+// if a rotation is requested in the future, or before checking again a due rotation which is waiting for the control
+// plane machines to be stable. A failed rotation is not retried.
+func nextSecretsEncryptionKeyRotation(rcp *controlplanev1.RKE2ControlPlane, now time.Time) time.Duration {
+	if secretsEncryptionKeyRotationDue(rcp, now) {
+		if conditions.GetReason(rcp, controlplanev1.SecretsEncryptionKeyRotationCondition) ==
+			controlplanev1.SecretsEncryptionKeyRotationFailedReason {
+			return 0
+		}
+
+		return secretsEncryptionKeyRotationRequeueAfter
+	}
+
+	rotateAfter := rcp.Spec.SecretsEncryptionKeyRotateAfter
+	if rotateAfter == nil || !rotateAfter.After(now) {
+		return 0
+	}
+
+	return rotateAfter.Sub(now)
+}
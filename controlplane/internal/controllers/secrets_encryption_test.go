@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+)
+
+type fakeSecretsEncryptionWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	phase rke2.SecretsEncryptionStepPhase
+	steps []string
+}
+
+func (f *fakeSecretsEncryptionWorkloadCluster) RunSecretsEncryptionStep(
+	_ context.Context, _, nodeName, command, _ string,
+) (rke2.SecretsEncryptionStepPhase, string, error) {
+	if f.phase != rke2.SecretsEncryptionStepSucceeded {
+		return f.phase, "boom", nil
+	}
+
+	f.steps = append(f.steps, nodeName+":"+command)
+
+	return f.phase, "", nil
+}
+
+func (f *fakeSecretsEncryptionWorkloadCluster) CleanupSecretsEncryptionStep(_ context.Context, _ string) error {
+	return nil
+}
+
+var _ = Describe("Secrets encryption key rotation", func() {
+	var (
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeSecretsEncryptionWorkloadCluster
+		rcp      *controlplanev1.RKE2ControlPlane
+		cp       *rke2.ControlPlane
+	)
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default", UID: "uid"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				SecretsEncryptionKeyRotateAfter: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		workload = &fakeSecretsEncryptionWorkloadCluster{phase: rke2.SecretsEncryptionStepSucceeded}
+		r = &RKE2ControlPlaneReconciler{
+			managementCluster: &fakeManagementCluster{workload: workload},
+			recorder:          record.NewFakeRecorder(32),
+		}
+		cp = &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(restoreTestMachine("m1", 1), restoreTestMachine("m2", 3), restoreTestMachine("m3", 2)),
+		}
+	})
+
+	It("should run the stages on the oldest server and restart all the servers in order", func() {
+		results := []ctrl.Result{}
+
+		for i := 0; i < 9; i++ {
+			result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, cp)
+			Expect(err).ToNot(HaveOccurred())
+
+			results = append(results, result)
+		}
+
+		Expect(workload.steps).To(Equal([]string{
+			"node-m2:prepare", "node-m3:", "node-m1:",
+			"node-m2:rotate", "node-m3:", "node-m1:",
+			"node-m2:reencrypt", "node-m3:", "node-m1:",
+		}))
+		Expect(results[7].Requeue).To(BeTrue())
+		Expect(results[8].IsZero()).To(BeTrue())
+		Expect(rcp.Status.SecretsEncryptionKeyRotation).To(BeNil())
+		Expect(rcp.Status.LastSecretsEncryptionKeyRotationTime).ToNot(BeNil())
+		Expect(conditions.IsTrue(rcp, controlplanev1.SecretsEncryptionKeyRotationCondition)).To(BeTrue())
+
+		// The keys are not rotated again for the same request.
+		result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(workload.steps).To(HaveLen(9))
+	})
+
+	It("should wait for a running step to complete", func() {
+		workload.phase = rke2.SecretsEncryptionStepRunning
+
+		result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(secretsEncryptionKeyRotationRequeueAfter))
+		Expect(rcp.Status.SecretsEncryptionKeyRotation.Stage).To(Equal(controlplanev1.SecretsEncryptionKeyRotationPrepareStage))
+		Expect(rcp.Status.SecretsEncryptionKeyRotation.RestartedNodes).To(BeEmpty())
+		Expect(conditions.GetReason(rcp, controlplanev1.SecretsEncryptionKeyRotationCondition)).To(
+			Equal(controlplanev1.SecretsEncryptionKeyRotationInProgressReason))
+	})
+
+	It("should report a failed step without blocking the control plane", func() {
+		rcp.Status.SecretsEncryptionKeyRotation = &controlplanev1.SecretsEncryptionKeyRotationStatus{
+			Stage:          controlplanev1.SecretsEncryptionKeyRotationRotateStage,
+			RestartedNodes: []string{"node-m2"},
+		}
+		workload.phase = rke2.SecretsEncryptionStepFailed
+
+		result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(conditions.GetReason(rcp, controlplanev1.SecretsEncryptionKeyRotationCondition)).To(
+			Equal(controlplanev1.SecretsEncryptionKeyRotationFailedReason))
+		Expect(rcp.Status.SecretsEncryptionKeyRotation.RestartedNodes).To(Equal([]string{"node-m2"}))
+		Expect(nextSecretsEncryptionKeyRotation(rcp, time.Now())).To(BeZero())
+	})
+
+	It("should not block the control plane while the machines are not stable", func() {
+		deleting := restoreTestMachine("m4", 4)
+		deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		cp.Machines.Insert(deleting)
+
+		result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(workload.steps).To(BeEmpty())
+		Expect(rcp.Status.SecretsEncryptionKeyRotation).To(BeNil())
+
+		// The rotation is checked again once the control plane is reconciled.
+		Expect(nextSecretsEncryptionKeyRotation(rcp, time.Now())).To(Equal(secretsEncryptionKeyRotationRequeueAfter))
+	})
+
+	It("should not rotate the keys of a custom encryption configuration", func() {
+		rcp.Spec.ServerConfig.SecretsEncryption = &controlplanev1.SecretsEncryption{
+			EncryptionConfigSecret: &corev1.ObjectReference{Name: "encryption-config", Namespace: "default"},
+		}
+
+		result, err := r.reconcileSecretsEncryptionKeyRotation(ctx, cp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(workload.steps).To(BeEmpty())
+		Expect(rcp.Status.SecretsEncryptionKeyRotation).To(BeNil())
+	})
+
+	It("should schedule a rotation requested in the future", func() {
+		now := time.Now()
+		rcp.Spec.SecretsEncryptionKeyRotateAfter = &metav1.Time{Time: now.Add(time.Hour)}
+
+		Expect(nextSecretsEncryptionKeyRotation(rcp, now)).To(Equal(time.Hour))
+		Expect(shortestRequeueAfter(0, 2*time.Hour, nextSecretsEncryptionKeyRotation(rcp, now))).To(Equal(time.Hour))
+	})
+})
@@ -0,0 +1,369 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+const (
+	// tokenRotationRequeueAfter is how long to wait before checking a running token rotation again.
+	tokenRotationRequeueAfter = 10 * time.Second
+
+	// rotatedTokenLength is the number of random bytes of a rotated token, like the tokens generated by the bootstrap provider.
+	rotatedTokenLength = 16
+)
+
+// reconcileTokenRotation rotates the server token of the cluster once TokenRotateAfter has passed: `rke2 token rotate`
+// is run on the oldest ready control plane machine, then the token Secret of the management cluster is updated and the
+// bootstrap data of the machines not yet provisioned is regenerated with the new token. A dedicated agent token is
+// rotated by reconcileAgentTokenRotation, as `rke2 token rotate` only supports the server token.
+// A failed rotation is only reported, so that it does not block the other operations on the control plane.
+func (r *RKE2ControlPlaneReconciler) reconcileTokenRotation(ctx context.Context, controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+	cluster := controlPlane.Cluster
+
+	now := time.Now()
+	if !tokenRotationDue(rcp, now) || !rcp.Status.Initialized {
+		return ctrl.Result{}, nil
+	}
+
+	machine := controlPlane.Machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		collections.HasNode(),
+		collections.IsReady(),
+	).Oldest()
+	if machine == nil {
+		logger.Info("Waiting for a ready control plane machine to rotate the server token")
+
+		return ctrl.Result{RequeueAfter: tokenRotationRequeueAfter}, nil
+	}
+
+	tokenSecret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: bsutil.TokenName(cluster.Name)}, tokenSecret); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get the server token Secret")
+	}
+
+	token := tokenSecret.Data["value"]
+
+	newToken, err := bsutil.Random(rotatedTokenLength)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to generate a new server token")
+	}
+
+	workloadCluster, err := r.GetWorkloadCluster(ctx, controlPlane)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "cannot get remote client to workload cluster")
+	}
+
+	// The rotation is identified by the requested time, so that a new rotation is run each time TokenRotateAfter is changed.
+	id := string(rcp.UID) + "-" + rcp.Spec.TokenRotateAfter.UTC().Format(time.RFC3339)
+
+	result, err := workloadCluster.RotateToken(ctx, id, machine.Status.NodeRef.Name, string(token), newToken, upgradeImage(rcp))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch result.Phase { //nolint:exhaustive
+	case rke2.TokenRotationRunning:
+		logger.Info("Waiting for the server token to be rotated", "machine", machine.Name)
+		conditions.MarkFalse(rcp,
+			controlplanev1.TokenRotationCondition,
+			controlplanev1.TokenRotationInProgressReason,
+			clusterv1.ConditionSeverityInfo,
+			"Rotating the server token on Machine %s", machine.Name)
+
+		return ctrl.Result{RequeueAfter: tokenRotationRequeueAfter}, nil
+	case rke2.TokenRotationFailed:
+		conditions.MarkFalse(rcp,
+			controlplanev1.TokenRotationCondition,
+			controlplanev1.TokenRotationFailedReason,
+			clusterv1.ConditionSeverityWarning,
+			"Failed to rotate the server token on Machine %s: %s", machine.Name, result.Message)
+		r.recorder.Eventf(rcp, corev1.EventTypeWarning, "TokenRotationFailed",
+			"Failed to rotate the server token on Machine %s: %s", machine.Name, result.Message)
+
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.storeRotatedToken(ctx, cluster, tokenSecret, []byte(result.NewToken)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.regenerateUnprovisionedBootstrapData(ctx, cluster); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := workloadCluster.CleanupTokenRotation(ctx, id); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	rcp.Status.LastTokenRotationTime = &metav1.Time{Time: now}
+	conditions.MarkTrue(rcp, controlplanev1.TokenRotationCondition)
+
+	r.recorder.Eventf(rcp, corev1.EventTypeNormal, "TokenRotated",
+		"Server token rotated on Machine %s", machine.Name)
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileAgentTokenRotation rotates the agent token of the cluster once AgentTokenRotateAfter has passed: a new
+// agent token is stored in the agent token Secret of the management cluster and the bootstrap data of the machines not
+// yet provisioned is regenerated with it. The servers are configured with the new agent token by rolling out the
+// control plane machines created before LastAgentTokenRotationTime.
+func (r *RKE2ControlPlaneReconciler) reconcileAgentTokenRotation(ctx context.Context, controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	rcp := controlPlane.RCP
+	cluster := controlPlane.Cluster
+
+	now := time.Now()
+	if !agentTokenRotationDue(rcp, now) || !rcp.Status.Initialized {
+		return ctrl.Result{}, nil
+	}
+
+	newToken, err := bsutil.Random(rotatedTokenLength)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to generate a new agent token")
+	}
+
+	if err := r.storeRotatedAgentToken(ctx, cluster, []byte(newToken)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.regenerateUnprovisionedBootstrapData(ctx, cluster); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	rcp.Status.LastAgentTokenRotationTime = &metav1.Time{Time: now}
+
+	logger.Info("Agent token rotated, rolling out the control plane machines created before the rotation")
+	r.recorder.Eventf(rcp, corev1.EventTypeNormal, "AgentTokenRotated",
+		"Agent token rotated, the control plane machines created before the rotation are rolled out")
+
+	// The control plane machines created before the rotation are rolled out once the rotation time is recorded.
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// storeRotatedAgentToken stores the rotated token in the agent token Secret, creating the Secret for clusters
+// provisioned before the agents had their own token.
+func (r *RKE2ControlPlaneReconciler) storeRotatedAgentToken(ctx context.Context, cluster *clusterv1.Cluster, newToken []byte) error {
+	agentSecret := &corev1.Secret{}
+
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: bsutil.AgentTokenName(cluster.Name)}, agentSecret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get the agent token Secret")
+	}
+
+	if apierrors.IsNotFound(err) {
+		agentSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bsutil.AgentTokenName(cluster.Name),
+				Namespace: cluster.Namespace,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel: cluster.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       cluster.Name,
+						UID:        cluster.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Data: map[string][]byte{
+				"value": newToken,
+			},
+			Type: clusterv1.ClusterSecretType,
+		}
+
+		if err := r.Client.Create(ctx, agentSecret); err != nil {
+			return errors.Wrap(err, "failed to create the agent token Secret")
+		}
+
+		return nil
+	}
+
+	if agentSecret.Data == nil {
+		agentSecret.Data = map[string][]byte{}
+	}
+
+	agentSecret.Data["value"] = newToken
+
+	if err := r.Client.Update(ctx, agentSecret); err != nil {
+		return errors.Wrap(err, "failed to update the agent token Secret")
+	}
+
+	return nil
+}
+
+// storeRotatedToken stores the rotated token in the server token Secret. The agent token Secret is updated as well
+// when it still holds the previous server token, as created for clusters provisioned before agents had their own token.
+func (r *RKE2ControlPlaneReconciler) storeRotatedToken(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	tokenSecret *corev1.Secret,
+	newToken []byte,
+) error {
+	agentSecret := &corev1.Secret{}
+
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: bsutil.AgentTokenName(cluster.Name)}, agentSecret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get the agent token Secret")
+	}
+
+	if err == nil && bytes.Equal(agentSecret.Data["value"], tokenSecret.Data["value"]) {
+		agentSecret.Data["value"] = newToken
+
+		if err := r.Client.Update(ctx, agentSecret); err != nil {
+			return errors.Wrap(err, "failed to update the agent token Secret")
+		}
+	}
+
+	if tokenSecret.Data == nil {
+		tokenSecret.Data = map[string][]byte{}
+	}
+
+	tokenSecret.Data["value"] = newToken
+
+	if err := r.Client.Update(ctx, tokenSecret); err != nil {
+		return errors.Wrap(err, "failed to update the server token Secret")
+	}
+
+	return nil
+}
+
+// regenerateUnprovisionedBootstrapData requests the bootstrap data of the machines of the cluster which are not
+// provisioned yet to be generated again, so that they join the cluster with the rotated token.
+func (r *RKE2ControlPlaneReconciler) regenerateUnprovisionedBootstrapData(ctx context.Context, cluster *clusterv1.Cluster) error {
+	machineList := &clusterv1.MachineList{}
+	if err := r.List(ctx, machineList, client.InNamespace(cluster.Namespace), client.MatchingLabels{
+		clusterv1.ClusterNameLabel: cluster.Name,
+	}); err != nil {
+		return errors.Wrap(err, "failed to list cluster machines")
+	}
+
+	machines := collections.FromMachineList(machineList).Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		func(machine *clusterv1.Machine) bool {
+			return !machine.Status.InfrastructureReady &&
+				machine.Spec.Bootstrap.ConfigRef != nil &&
+				machine.Spec.Bootstrap.ConfigRef.Kind == "RKE2Config"
+		},
+	)
+
+	for _, machine := range machines {
+		config := &bootstrapv1.RKE2Config{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machine.Spec.Bootstrap.ConfigRef.Name}, config); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			return errors.Wrapf(err, "failed to get RKE2Config of Machine %s", machine.Name)
+		}
+
+		// Bootstrap data not generated yet is generated with the rotated token anyway.
+		if !config.Status.Ready {
+			continue
+		}
+
+		patchHelper, err := patch.NewHelper(config, r.Client)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create patch helper for RKE2Config %s", config.Name)
+		}
+
+		annotations := config.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+
+		annotations[bootstrapv1.RegenerateBootstrapDataAnnotation] = ""
+		config.SetAnnotations(annotations)
+
+		if err := patchHelper.Patch(ctx, config); err != nil {
+			return errors.Wrapf(err, "failed to annotate RKE2Config %s", config.Name)
+		}
+	}
+
+	return nil
+}
+
+// tokenRotationDue returns true if TokenRotateAfter has passed and the token has not been rotated since.
+func tokenRotationDue(rcp *controlplanev1.RKE2ControlPlane, now time.Time) bool {
+	rotateAfter := rcp.Spec.TokenRotateAfter
+	if rotateAfter == nil || now.Before(rotateAfter.Time) {
+		return false
+	}
+
+	last := rcp.Status.LastTokenRotationTime
+
+	return last == nil || last.Before(rotateAfter)
+}
+
+// agentTokenRotationDue returns true if AgentTokenRotateAfter has passed and the agent token has not been rotated since.
+func agentTokenRotationDue(rcp *controlplanev1.RKE2ControlPlane, now time.Time) bool {
+	rotateAfter := rcp.Spec.AgentTokenRotateAfter
+	if rotateAfter == nil || now.Before(rotateAfter.Time) {
+		return false
+	}
+
+	last := rcp.Status.LastAgentTokenRotationTime
+
+	return last == nil || last.Before(rotateAfter)
+}
+
+// nextTokenRotation returns how long to wait before the server token must be rotated, if a rotation is requested in the future.
+func nextTokenRotation(rcp *controlplanev1.RKE2ControlPlane, now time.Time) time.Duration {
+	rotateAfter := rcp.Spec.TokenRotateAfter
+	if rotateAfter == nil || !rotateAfter.After(now) {
+		return 0
+	}
+
+	return rotateAfter.Sub(now)
+}
+
+// nextAgentTokenRotation returns how long to wait before the agent token must be rotated, if a rotation is requested in the future.
+func nextAgentTokenRotation(rcp *controlplanev1.RKE2ControlPlane, now time.Time) time.Duration {
+	rotateAfter := rcp.Spec.AgentTokenRotateAfter
+	if rotateAfter == nil || !rotateAfter.After(now) {
+		return 0
+	}
+
+	return rotateAfter.Sub(now)
+}
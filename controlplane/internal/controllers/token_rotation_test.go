@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/rke2"
+	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+type fakeTokenRotationWorkloadCluster struct {
+	fakeWorkloadCluster
+
+	result   *rke2.TokenRotationResult
+	nodeName string
+	token    string
+	cleanup  []string
+}
+
+func (f *fakeTokenRotationWorkloadCluster) RotateToken(
+	_ context.Context, _, nodeName, token, _, _ string,
+) (*rke2.TokenRotationResult, error) {
+	f.nodeName = nodeName
+	f.token = token
+
+	return f.result, nil
+}
+
+func (f *fakeTokenRotationWorkloadCluster) CleanupTokenRotation(_ context.Context, id string) error {
+	f.cleanup = append(f.cleanup, id)
+
+	return nil
+}
+
+var _ = Describe("Token rotation", func() {
+	var (
+		cl       client.Client
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeTokenRotationWorkloadCluster
+		rcp      *controlplanev1.RKE2ControlPlane
+		cluster  *clusterv1.Cluster
+		m1, m2   *clusterv1.Machine
+	)
+
+	tokenSecret := func(name, value string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Data:       map[string][]byte{"value": []byte(value)},
+		}
+	}
+
+	tokenValue := func(name string) string {
+		secret := &corev1.Secret{}
+		Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, secret)).To(Succeed())
+
+		return string(secret.Data["value"])
+	}
+
+	workerWithConfig := func(name string, infrastructureReady bool) (*clusterv1.Machine, *bootstrapv1.RKE2Config) {
+		config := &bootstrapv1.RKE2Config{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     bootstrapv1.RKE2ConfigStatus{Ready: true},
+		}
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{clusterv1.ClusterNameLabel: cluster.Name},
+			},
+			Spec: clusterv1.MachineSpec{
+				ClusterName: cluster.Name,
+				Bootstrap: clusterv1.Bootstrap{
+					ConfigRef: &corev1.ObjectReference{Kind: "RKE2Config", Name: name, Namespace: "default"},
+				},
+			},
+			Status: clusterv1.MachineStatus{InfrastructureReady: infrastructureReady},
+		}
+
+		return machine, config
+	}
+
+	hasRegenerateAnnotation := func(name string) bool {
+		config := &bootstrapv1.RKE2Config{}
+		Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, config)).To(Succeed())
+
+		_, ok := config.Annotations[bootstrapv1.RegenerateBootstrapDataAnnotation]
+
+		return ok
+	}
+
+	controlPlane := func(objs ...client.Object) *rke2.ControlPlane {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&bootstrapv1.RKE2Config{}).Build()
+		r = &RKE2ControlPlaneReconciler{
+			Client:            cl,
+			managementCluster: &fakeManagementCluster{workload: workload},
+			recorder:          record.NewFakeRecorder(32),
+		}
+
+		return &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  cluster,
+			Machines: collections.FromMachines(m1, m2),
+		}
+	}
+
+	BeforeEach(func() {
+		cluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default", UID: "uid"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				TokenRotateAfter: &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		m1, m2 = restoreTestMachine("m1", 2), restoreTestMachine("m2", 1)
+		workload = &fakeTokenRotationWorkloadCluster{}
+	})
+
+	It("should not rotate the token before TokenRotateAfter", func() {
+		rcp.Spec.TokenRotateAfter = &metav1.Time{Time: time.Now().Add(time.Hour)}
+
+		result, err := r.reconcileTokenRotation(ctx, controlPlane())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(workload.nodeName).To(BeEmpty())
+		Expect(nextTokenRotation(rcp, time.Now())).To(BeNumerically("~", time.Hour, time.Minute))
+	})
+
+	It("should not rotate the token again once rotated", func() {
+		rcp.Status.LastTokenRotationTime = &metav1.Time{Time: time.Now()}
+
+		result, err := r.reconcileTokenRotation(ctx, controlPlane())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(workload.nodeName).To(BeEmpty())
+		Expect(nextTokenRotation(rcp, time.Now())).To(BeZero())
+	})
+
+	It("should wait for the rotation to complete on the oldest machine", func() {
+		workload.result = &rke2.TokenRotationResult{Phase: rke2.TokenRotationRunning}
+
+		result, err := r.reconcileTokenRotation(ctx, controlPlane(tokenSecret(bsutil.TokenName(cluster.Name), "old")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(tokenRotationRequeueAfter))
+		Expect(workload.nodeName).To(Equal("node-m1"))
+		Expect(workload.token).To(Equal("old"))
+		Expect(conditions.GetReason(rcp, controlplanev1.TokenRotationCondition)).To(Equal(controlplanev1.TokenRotationInProgressReason))
+		Expect(rcp.Status.LastTokenRotationTime).To(BeNil())
+	})
+
+	It("should report a failed rotation without changing the token", func() {
+		workload.result = &rke2.TokenRotationResult{Phase: rke2.TokenRotationFailed, Message: "BackoffLimitExceeded"}
+
+		result, err := r.reconcileTokenRotation(ctx, controlPlane(tokenSecret(bsutil.TokenName(cluster.Name), "old")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(tokenValue(bsutil.TokenName(cluster.Name))).To(Equal("old"))
+		Expect(conditions.GetReason(rcp, controlplanev1.TokenRotationCondition)).To(Equal(controlplanev1.TokenRotationFailedReason))
+		Expect(rcp.Status.LastTokenRotationTime).To(BeNil())
+	})
+
+	It("should store the rotated token and regenerate the bootstrap data of unprovisioned machines", func() {
+		workload.result = &rke2.TokenRotationResult{Phase: rke2.TokenRotationSucceeded, NewToken: "new"}
+
+		provisioned, provisionedConfig := workerWithConfig("provisioned", true)
+		pending, pendingConfig := workerWithConfig("pending", false)
+
+		result, err := r.reconcileTokenRotation(ctx, controlPlane(
+			tokenSecret(bsutil.TokenName(cluster.Name), "old"),
+			tokenSecret(bsutil.AgentTokenName(cluster.Name), "old"),
+			provisioned, provisionedConfig, pending, pendingConfig,
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(tokenValue(bsutil.TokenName(cluster.Name))).To(Equal("new"))
+		Expect(tokenValue(bsutil.AgentTokenName(cluster.Name))).To(Equal("new"))
+		Expect(hasRegenerateAnnotation("pending")).To(BeTrue())
+		Expect(hasRegenerateAnnotation("provisioned")).To(BeFalse())
+		Expect(workload.cleanup).To(HaveLen(1))
+		Expect(conditions.IsTrue(rcp, controlplanev1.TokenRotationCondition)).To(BeTrue())
+		Expect(rcp.Status.LastTokenRotationTime).ToNot(BeNil())
+	})
+
+	It("should keep a separate agent token", func() {
+		workload.result = &rke2.TokenRotationResult{Phase: rke2.TokenRotationSucceeded, NewToken: "new"}
+
+		_, err := r.reconcileTokenRotation(ctx, controlPlane(
+			tokenSecret(bsutil.TokenName(cluster.Name), "old"),
+			tokenSecret(bsutil.AgentTokenName(cluster.Name), "agent"),
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tokenValue(bsutil.TokenName(cluster.Name))).To(Equal("new"))
+		Expect(tokenValue(bsutil.AgentTokenName(cluster.Name))).To(Equal("agent"))
+	})
+
+	Describe("Agent token", func() {
+		BeforeEach(func() {
+			rcp.Spec.TokenRotateAfter = nil
+			rcp.Spec.AgentTokenRotateAfter = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+		})
+
+		It("should not rotate the agent token before AgentTokenRotateAfter", func() {
+			rcp.Spec.AgentTokenRotateAfter = &metav1.Time{Time: time.Now().Add(time.Hour)}
+
+			result, err := r.reconcileAgentTokenRotation(ctx, controlPlane(tokenSecret(bsutil.AgentTokenName(cluster.Name), "agent")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+			Expect(tokenValue(bsutil.AgentTokenName(cluster.Name))).To(Equal("agent"))
+			Expect(rcp.Status.LastAgentTokenRotationTime).To(BeNil())
+			Expect(nextAgentTokenRotation(rcp, time.Now())).To(BeNumerically("~", time.Hour, time.Minute))
+		})
+
+		It("should not rotate the agent token again once rotated", func() {
+			rcp.Status.LastAgentTokenRotationTime = &metav1.Time{Time: time.Now()}
+
+			result, err := r.reconcileAgentTokenRotation(ctx, controlPlane(tokenSecret(bsutil.AgentTokenName(cluster.Name), "agent")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.IsZero()).To(BeTrue())
+			Expect(tokenValue(bsutil.AgentTokenName(cluster.Name))).To(Equal("agent"))
+		})
+
+		It("should store a new agent token and regenerate the bootstrap data of unprovisioned machines", func() {
+			provisioned, provisionedConfig := workerWithConfig("provisioned", true)
+			pending, pendingConfig := workerWithConfig("pending", false)
+
+			result, err := r.reconcileAgentTokenRotation(ctx, controlPlane(
+				tokenSecret(bsutil.TokenName(cluster.Name), "server"),
+				tokenSecret(bsutil.AgentTokenName(cluster.Name), "agent"),
+				provisioned, provisionedConfig, pending, pendingConfig,
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Requeue).To(BeTrue())
+			Expect(tokenValue(bsutil.TokenName(cluster.Name))).To(Equal("server"))
+			Expect(tokenValue(bsutil.AgentTokenName(cluster.Name))).ToNot(BeElementOf("agent", "server", ""))
+			Expect(hasRegenerateAnnotation("pending")).To(BeTrue())
+			Expect(hasRegenerateAnnotation("provisioned")).To(BeFalse())
+			Expect(rcp.Status.LastAgentTokenRotationTime).ToNot(BeNil())
+		})
+
+		It("should create the agent token of a cluster provisioned without agent token", func() {
+			_, err := r.reconcileAgentTokenRotation(ctx, controlPlane(tokenSecret(bsutil.TokenName(cluster.Name), "server")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenValue(bsutil.TokenName(cluster.Name))).To(Equal("server"))
+			Expect(tokenValue(bsutil.AgentTokenName(cluster.Name))).ToNot(BeElementOf("server", ""))
+			Expect(rcp.Status.LastAgentTokenRotationTime).ToNot(BeNil())
+		})
+	})
+})
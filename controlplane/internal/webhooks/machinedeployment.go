@@ -0,0 +1,137 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhooks contains webhooks for resources not owned by the RKE2 control plane provider.
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	bsutil "github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+// machinedeploymentlog is for logging in this package.
+var machinedeploymentlog = logf.Log.WithName("machinedeployment-resource")
+
+// MachineDeployment warns about MachineDeployments whose version is not compatible
+// with the version of the RKE2ControlPlane of their cluster.
+type MachineDeployment struct {
+	Client client.Client
+}
+
+// SetupWebhookWithManager sets up the Controller Manager for the Webhook for the MachineDeployment resource.
+func (w *MachineDeployment) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if w.Client == nil {
+		w.Client = mgr.GetClient()
+	}
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&clusterv1.MachineDeployment{}).
+		WithValidator(w).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-cluster-x-k8s-io-v1beta1-machinedeployment,mutating=false,failurePolicy=ignore,sideEffects=None,groups=cluster.x-k8s.io,resources=machinedeployments,verbs=create;update,versions=v1beta1,name=vmachinedeployment.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &MachineDeployment{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (w *MachineDeployment) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return w.validate(ctx, obj)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (w *MachineDeployment) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	return w.validate(ctx, newObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type.
+func (w *MachineDeployment) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate never rejects a MachineDeployment, it only returns a warning when its version is not compatible
+// with the control plane version, as the RKE2Config controller will not bootstrap its machines.
+func (w *MachineDeployment) validate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	md, ok := obj.(*clusterv1.MachineDeployment)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected a MachineDeployment but got a %T", obj))
+	}
+
+	if md.Spec.Template.Spec.Version == nil || *md.Spec.Template.Spec.Version == "" {
+		return nil, nil
+	}
+
+	rcp, err := w.getControlPlane(ctx, md)
+	if err != nil {
+		machinedeploymentlog.V(5).Info("Unable to get the control plane of the MachineDeployment",
+			"machine-deployment", klog.KObj(md), "error", err.Error())
+
+		return nil, nil
+	}
+
+	if rcp == nil {
+		return nil, nil
+	}
+
+	controlPlaneVersion := bsutil.ControlPlaneVersion(rcp)
+	if controlPlaneVersion == "" {
+		return nil, nil
+	}
+
+	if err := bsutil.CheckWorkerVersionSkew(*md.Spec.Template.Spec.Version, controlPlaneVersion); err != nil {
+		return admission.Warnings{
+			fmt.Sprintf("MachineDeployment %s version is not compatible with RKE2ControlPlane %s: %s, new machines will not be bootstrapped",
+				md.Name, rcp.Name, err.Error()),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// getControlPlane returns the RKE2ControlPlane of the MachineDeployment cluster, or nil if the cluster
+// is managed by another control plane provider.
+func (w *MachineDeployment) getControlPlane(ctx context.Context, md *clusterv1.MachineDeployment) (*controlplanev1.RKE2ControlPlane, error) {
+	cluster, err := bsutil.GetClusterByName(ctx, w.Client, md.Namespace, md.Spec.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := cluster.Spec.ControlPlaneRef
+	if ref == nil || ref.Kind != "RKE2ControlPlane" || ref.GroupVersionKind().Group != controlplanev1.GroupVersion.Group {
+		return nil, nil
+	}
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = cluster.Namespace
+	}
+
+	return bsutil.GetControlPlaneByName(ctx, w.Client, namespace, ref.Name)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+func TestMachineDeploymentVersionSkewWarnings(t *testing.T) {
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+	NewWithT(t).Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: clusterv1.ClusterSpec{
+			ControlPlaneRef: &corev1.ObjectReference{
+				APIVersion: controlplanev1.GroupVersion.String(),
+				Kind:       "RKE2ControlPlane",
+				Name:       "test-control-plane",
+			},
+		},
+	}
+	rcp := &controlplanev1.RKE2ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-control-plane", Namespace: "default"},
+		Spec:       controlplanev1.RKE2ControlPlaneSpec{Version: "v1.29.4+rke2r1"},
+		Status:     controlplanev1.RKE2ControlPlaneStatus{Version: ptr.To("v1.28.9+rke2r1")},
+	}
+
+	webhook := &MachineDeployment{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, rcp).Build(),
+	}
+
+	tests := []struct {
+		name         string
+		clusterName  string
+		version      *string
+		wantWarnings bool
+	}{
+		{
+			name:        "version matching the control plane",
+			clusterName: "test",
+			version:     ptr.To("v1.28.9+rke2r1"),
+		},
+		{
+			name:        "version within the allowed skew",
+			clusterName: "test",
+			version:     ptr.To("v1.26.15+rke2r1"),
+		},
+		{
+			name:         "version newer than the lowest control plane version",
+			clusterName:  "test",
+			version:      ptr.To("v1.29.4+rke2r1"),
+			wantWarnings: true,
+		},
+		{
+			name:         "version too old",
+			clusterName:  "test",
+			version:      ptr.To("v1.24.17+rke2r1"),
+			wantWarnings: true,
+		},
+		{
+			name:        "no version",
+			clusterName: "test",
+		},
+		{
+			name:        "unknown cluster",
+			clusterName: "other",
+			version:     ptr.To("v1.30.0+rke2r1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			md := &clusterv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-md", Namespace: "default"},
+				Spec: clusterv1.MachineDeploymentSpec{
+					ClusterName: tt.clusterName,
+					Template: clusterv1.MachineTemplateSpec{
+						Spec: clusterv1.MachineSpec{ClusterName: tt.clusterName, Version: tt.version},
+					},
+				},
+			}
+
+			warnings, err := webhook.ValidateCreate(context.Background(), md)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			if tt.wantWarnings {
+				g.Expect(warnings).To(HaveLen(1))
+			} else {
+				g.Expect(warnings).To(BeEmpty())
+			}
+
+			updateWarnings, err := webhook.ValidateUpdate(context.Background(), md, md)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(updateWarnings).To(Equal(warnings))
+		})
+	}
+}
@@ -47,6 +47,7 @@ import (
 	controlplanev1alpha1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1alpha1"
 	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
 	"github.com/rancher/cluster-api-provider-rke2/controlplane/internal/controllers"
+	"github.com/rancher/cluster-api-provider-rke2/controlplane/internal/webhooks"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
 )
 
@@ -67,6 +68,7 @@ var (
 	webhookPort                 int
 	webhookCertDir              string
 	healthAddr                  string
+	kubeconfigRotationWindow    time.Duration
 
 	diagnosticsOptions = flags.DiagnosticsOptions{}
 )
@@ -119,6 +121,9 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
 
+	fs.DurationVar(&kubeconfigRotationWindow, "kubeconfig-rotation-window", consts.DefaultKubeconfigRotationWindow,
+		"Time before the expiry of the kubeconfig client certificate at which the kubeconfig secret is regenerated (e.g. 720h)")
+
 	flags.AddDiagnosticsOptions(fs, &diagnosticsOptions)
 }
 
@@ -235,13 +240,22 @@ func setupReconcilers(ctx context.Context, mgr ctrl.Manager) {
 	}
 
 	if err := (&controllers.RKE2ControlPlaneReconciler{
-		Client:              mgr.GetClient(),
-		Scheme:              mgr.GetScheme(),
-		SecretCachingClient: secretCachingClient,
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		SecretCachingClient:      secretCachingClient,
+		KubeconfigRotationWindow: kubeconfigRotationWindow,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RKE2ControlPlane")
 		os.Exit(1)
 	}
+
+	if err := (&controllers.RKE2EtcdSnapshotReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RKE2EtcdSnapshot")
+		os.Exit(1)
+	}
 }
 
 func setupWebhooks(mgr ctrl.Manager) {
@@ -254,4 +268,9 @@ func setupWebhooks(mgr ctrl.Manager) {
 		setupLog.Error(err, "unable to create webhook", "webhook", "RKE2ControlPlaneTemplate")
 		os.Exit(1)
 	}
+
+	if err := (&webhooks.MachineDeployment{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "MachineDeployment")
+		os.Exit(1)
+	}
 }
@@ -39,6 +39,10 @@ const (
 	// DefaultSyncPeriod is the default resync period for the controller manager's cache.
 	DefaultSyncPeriod = 10 * time.Minute
 
+	// DefaultKubeconfigRotationWindow is the default time before the expiry of the kubeconfig client certificate
+	// at which the kubeconfig secret is regenerated.
+	DefaultKubeconfigRotationWindow = 24 * time.Hour * 180
+
 	// DefaultFileOwner is the default owner of the files created by the controller.
 	DefaultFileOwner = "root:root"
 
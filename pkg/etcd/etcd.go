@@ -24,6 +24,7 @@ import (
 
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"google.golang.org/grpc"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -37,8 +38,11 @@ type GRPCDial func(ctx context.Context, addr string) (net.Conn, error)
 // etcd wraps the etcd client from etcd's clientv3 package.
 // This interface is implemented by both the clientv3 package and the backoff adapter that adds retries to the client.
 type etcd interface {
+	AlarmDisarm(ctx context.Context, m *clientv3.AlarmMember) (*clientv3.AlarmResponse, error)
 	AlarmList(ctx context.Context) (*clientv3.AlarmResponse, error)
 	Close() error
+	Compact(ctx context.Context, rev int64, opts ...clientv3.CompactOption) (*clientv3.CompactResponse, error)
+	Defragment(ctx context.Context, endpoint string) (*clientv3.DefragmentResponse, error)
 	Endpoints() []string
 	MemberList(ctx context.Context) (*clientv3.MemberListResponse, error)
 	MemberRemove(ctx context.Context, id uint64) (*clientv3.MemberRemoveResponse, error)
@@ -83,6 +87,10 @@ const (
 // for read and write operations to etcd.
 const DefaultCallTimeout = 15 * time.Second
 
+// DefragmentTimeout represents the duration that the etcd client waits at most for a member
+// to be defragmented, which blocks the member for a time proportional to its database size.
+const DefragmentTimeout = 5 * time.Minute
+
 // AlarmTypeName provides a text translation for AlarmType codes.
 var AlarmTypeName = map[AlarmType]string{
 	AlarmOK:      "NONE",
@@ -129,6 +137,24 @@ func pbMemberToMember(m *etcdserverpb.Member) *Member {
 	}
 }
 
+// MemberStatus describes the database of an etcd member as reported by the member itself.
+type MemberStatus struct {
+	// ID is the ID of the member.
+	ID uint64
+
+	// LeaderID is the ID of the member the member considers the leader.
+	LeaderID uint64
+
+	// DBSize is the size of the member database, in bytes.
+	DBSize int64
+
+	// DBSizeInUse is the size of the member database actually in use, in bytes.
+	DBSizeInUse int64
+
+	// Revision is the current revision of the key-value store.
+	Revision int64
+}
+
 // ClientConfiguration describes the configuration for an etcd client.
 type ClientConfiguration struct {
 	Endpoint    string
@@ -296,3 +322,59 @@ func (c *Client) Alarms(ctx context.Context) ([]MemberAlarm, error) {
 
 	return memberAlarms, nil
 }
+
+// MemberStatus retrieves the status of the member the client is connected to.
+func (c *Client) MemberStatus(ctx context.Context) (*MemberStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+
+	status, err := c.EtcdClient.Status(ctx, c.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get etcd member status")
+	}
+
+	return &MemberStatus{
+		ID:          status.Header.GetMemberId(),
+		LeaderID:    status.Leader,
+		DBSize:      status.DbSize,
+		DBSizeInUse: status.DbSizeInUse,
+		Revision:    status.Header.GetRevision(),
+	}, nil
+}
+
+// Defragment defragments the database of the member the client is connected to.
+func (c *Client) Defragment(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, DefragmentTimeout)
+	defer cancel()
+
+	_, err := c.EtcdClient.Defragment(ctx, c.Endpoint)
+
+	return errors.Wrapf(err, "failed to defragment etcd member %s", c.Endpoint)
+}
+
+// Compact compacts the key-value store history up to the given revision.
+// Compacting a revision that has already been compacted is not an error.
+func (c *Client) Compact(ctx context.Context, revision int64) error {
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+
+	_, err := c.EtcdClient.Compact(ctx, revision, clientv3.WithCompactPhysical())
+	if errors.Is(err, rpctypes.ErrCompacted) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "failed to compact etcd at revision %d", revision)
+}
+
+// DisarmAlarm disarms the given alarm raised by the given member.
+func (c *Client) DisarmAlarm(ctx context.Context, memberID uint64, alarm AlarmType) error {
+	ctx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+
+	_, err := c.EtcdClient.AlarmDisarm(ctx, &clientv3.AlarmMember{
+		MemberID: memberID,
+		Alarm:    etcdserverpb.AlarmType(alarm),
+	})
+
+	return errors.Wrapf(err, "failed to disarm %s alarm of etcd member %x", AlarmTypeName[alarm], memberID)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 	etcdfake "github.com/rancher/cluster-api-provider-rke2/pkg/etcd/fake"
 	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -101,3 +102,43 @@ func TestEtcdMembers_WithSuccess(t *testing.T) {
 	g.Expect(updatedMembers[0].PeerURLs).To(HaveLen(2))
 	g.Expect(updatedMembers[0].PeerURLs).To(Equal([]string{"https://1.2.3.4:2000", "https://4.5.6.7:2000"}))
 }
+
+func TestEtcdMaintenance(t *testing.T) {
+	g := NewWithT(t)
+
+	fakeEtcdClient := &etcdfake.FakeEtcdClient{
+		EtcdEndpoints: []string{"https://etcd-instance:2379"},
+		AlarmResponse: &clientv3.AlarmResponse{},
+		StatusResponse: &clientv3.StatusResponse{
+			Header:      &etcdserverpb.ResponseHeader{MemberId: 1234, Revision: 42},
+			Leader:      5678,
+			DbSize:      200,
+			DbSizeInUse: 50,
+		},
+	}
+
+	client, err := newEtcdClient(ctx, fakeEtcdClient, DefaultCallTimeout)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	status, err := client.MemberStatus(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*status).To(Equal(MemberStatus{ID: 1234, LeaderID: 5678, DBSize: 200, DBSizeInUse: 50, Revision: 42}))
+
+	g.Expect(client.Compact(ctx, status.Revision)).To(Succeed())
+	g.Expect(fakeEtcdClient.CompactedRevision).To(Equal(int64(42)))
+
+	g.Expect(client.Defragment(ctx)).To(Succeed())
+	g.Expect(fakeEtcdClient.Defragmented).To(Equal([]string{"https://etcd-instance:2379"}))
+
+	g.Expect(client.DisarmAlarm(ctx, 1234, AlarmNoSpace)).To(Succeed())
+	g.Expect(fakeEtcdClient.DisarmedAlarms).To(HaveLen(1))
+	g.Expect(fakeEtcdClient.DisarmedAlarms[0].MemberID).To(Equal(uint64(1234)))
+	g.Expect(fakeEtcdClient.DisarmedAlarms[0].Alarm).To(Equal(etcdserverpb.AlarmType_NOSPACE))
+
+	// Compacting an already compacted revision is not an error.
+	fakeEtcdClient.ErrorResponse = rpctypes.ErrCompacted
+	g.Expect(client.Compact(ctx, status.Revision)).To(Succeed())
+
+	fakeEtcdClient.ErrorResponse = errors.New("something went wrong")
+	g.Expect(client.Defragment(ctx)).ToNot(Succeed())
+}
@@ -33,8 +33,13 @@ type FakeEtcdClient struct { //nolint:revive
 	MoveLeaderResponse   *clientv3.MoveLeaderResponse
 	StatusResponse       *clientv3.StatusResponse
 	ErrorResponse        error
+	CompactResponse      *clientv3.CompactResponse
+	DefragmentResponse   *clientv3.DefragmentResponse
 	MovedLeader          uint64
 	RemovedMember        uint64
+	CompactedRevision    int64
+	Defragmented         []string
+	DisarmedAlarms       []*clientv3.AlarmMember
 }
 
 // Endpoints returns available etcd endpoint.
@@ -80,3 +85,24 @@ func (c *FakeEtcdClient) MemberUpdate(_ context.Context, _ uint64, _ []string) (
 func (c *FakeEtcdClient) Status(_ context.Context, _ string) (*clientv3.StatusResponse, error) {
 	return c.StatusResponse, nil
 }
+
+// AlarmDisarm disarms the given alarm.
+func (c *FakeEtcdClient) AlarmDisarm(_ context.Context, m *clientv3.AlarmMember) (*clientv3.AlarmResponse, error) {
+	c.DisarmedAlarms = append(c.DisarmedAlarms, m)
+
+	return c.AlarmResponse, c.ErrorResponse
+}
+
+// Compact compacts the key-value store up to the given revision.
+func (c *FakeEtcdClient) Compact(_ context.Context, rev int64, _ ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	c.CompactedRevision = rev
+
+	return c.CompactResponse, c.ErrorResponse
+}
+
+// Defragment defragments the member on the given endpoint.
+func (c *FakeEtcdClient) Defragment(_ context.Context, endpoint string) (*clientv3.DefragmentResponse, error) {
+	c.Defragmented = append(c.Defragmented, endpoint)
+
+	return c.DefragmentResponse, c.ErrorResponse
+}
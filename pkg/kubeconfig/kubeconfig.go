@@ -21,6 +21,7 @@ import (
 	"crypto"
 	"crypto/x509"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -166,6 +167,83 @@ func CreateSecretWithOwner(ctx context.Context, c client.Client, clusterName cli
 	return c.Create(ctx, GenerateSecretWithOwner(clusterName, out, owner))
 }
 
+// NeedsClientCertRotation returns whether any of the client certificates in the Kubeconfig secret
+// expires before the given threshold.
+func NeedsClientCertRotation(configSecret *corev1.Secret, threshold time.Duration) (bool, error) {
+	expiry, err := ClientCertExpiry(configSecret)
+	if err != nil {
+		return false, err
+	}
+
+	return time.Until(expiry) < threshold, nil
+}
+
+// ClientCertExpiry returns the earliest expiration time of the client certificates in the Kubeconfig secret.
+func ClientCertExpiry(configSecret *corev1.Secret) (time.Time, error) {
+	config, err := loadConfig(configSecret)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var expiry time.Time
+
+	for _, authInfo := range config.AuthInfos {
+		cert, err := certs.DecodeCertPEM(authInfo.ClientCertificateData)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "failed to decode kubeconfig client certificate")
+		} else if cert == nil {
+			return time.Time{}, errors.New("client certificate not found in kubeconfig")
+		}
+
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+
+	if expiry.IsZero() {
+		return time.Time{}, errors.New("no client certificate found in kubeconfig")
+	}
+
+	return expiry, nil
+}
+
+// RegenerateSecret creates a new Kubeconfig, with a new client certificate, and stores it in the given secret.
+// The server endpoint is preserved from the existing Kubeconfig.
+func RegenerateSecret(ctx context.Context, c client.Client, clusterName client.ObjectKey, configSecret *corev1.Secret) error {
+	config, err := loadConfig(configSecret)
+	if err != nil {
+		return err
+	}
+
+	cluster, ok := config.Clusters[clusterName.Name]
+	if !ok {
+		return errors.Errorf("failed to find cluster %s in kubeconfig", clusterName.Name)
+	}
+
+	out, err := generateKubeconfig(ctx, c, clusterName, cluster.Server)
+	if err != nil {
+		return err
+	}
+
+	configSecret.Data[secret.KubeconfigDataName] = out
+
+	return c.Update(ctx, configSecret)
+}
+
+func loadConfig(configSecret *corev1.Secret) (*api.Config, error) {
+	data, ok := configSecret.Data[secret.KubeconfigDataName]
+	if !ok {
+		return nil, errors.Errorf("missing key %q in secret data", secret.KubeconfigDataName)
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert kubeconfig Secret into a clientcmdapi.Config")
+	}
+
+	return config, nil
+}
+
 // GenerateSecret returns a Kubernetes secret for the given Cluster and kubeconfig data.
 func GenerateSecret(cluster *clusterv1.Cluster, data []byte) *corev1.Secret {
 	name := util.ObjectKey(cluster)
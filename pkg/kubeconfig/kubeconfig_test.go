@@ -0,0 +1,84 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rancher/cluster-api-provider-rke2/pkg/kubeconfig"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/secret"
+)
+
+func TestClientCertRotation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	clusterName := client.ObjectKey{Namespace: "default", Name: "test"}
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Test", Name: "test"}
+	cl := fake.NewClientBuilder().Build()
+
+	certificates := secret.NewCertificatesForInitialControlPlane()
+	g.Expect(certificates.Generate()).To(Succeed())
+	g.Expect(certificates.SaveGenerated(ctx, cl, clusterName, owner)).To(Succeed())
+
+	g.Expect(kubeconfig.CreateSecretWithOwner(ctx, cl, clusterName, "example.com:6443", owner)).To(Succeed())
+
+	configSecret := &corev1.Secret{}
+	g.Expect(cl.Get(ctx, client.ObjectKey{
+		Namespace: clusterName.Namespace,
+		Name:      secret.Name(clusterName.Name, secret.Kubeconfig),
+	}, configSecret)).To(Succeed())
+
+	expiry, err := kubeconfig.ClientCertExpiry(configSecret)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(expiry).To(BeTemporally(">", time.Now()))
+
+	needsRotation, err := kubeconfig.NeedsClientCertRotation(configSecret, time.Hour)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsRotation).To(BeFalse())
+
+	needsRotation, err = kubeconfig.NeedsClientCertRotation(configSecret, 2*365*24*time.Hour)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(needsRotation).To(BeTrue())
+
+	oldData := configSecret.Data[secret.KubeconfigDataName]
+	g.Expect(kubeconfig.RegenerateSecret(ctx, cl, clusterName, configSecret)).To(Succeed())
+
+	rotatedSecret := &corev1.Secret{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(configSecret), rotatedSecret)).To(Succeed())
+	g.Expect(rotatedSecret.Data[secret.KubeconfigDataName]).ToNot(Equal(oldData))
+
+	config, err := clientcmd.Load(rotatedSecret.Data[secret.KubeconfigDataName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(config.Clusters[clusterName.Name].Server).To(Equal("https://example.com:6443"))
+}
+
+func TestNeedsClientCertRotationInvalidSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := kubeconfig.NeedsClientCertRotation(&corev1.Secret{}, time.Hour)
+	g.Expect(err).To(HaveOccurred())
+}
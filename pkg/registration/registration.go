@@ -19,11 +19,16 @@ package registration
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/util"
 )
 
 // GetRegistrationAddresses is a function type that is used to provide different implementations of
@@ -55,7 +60,7 @@ func registrationMethodWithFilter(filter addressFilter) GetRegistrationAddresses
 		validIPAddresses := []string{}
 
 		for _, availableMachine := range availableMachines {
-			ip := filter(availableMachine)
+			ip := filter(availableMachine, rcp.Spec.RegistrationIPFamily)
 			if ip != "" {
 				validIPAddresses = append(validIPAddresses, ip)
 			}
@@ -96,45 +101,82 @@ func registrationMethodControlPlaneEndpoint(cluster *clusterv1.Cluster,
 	return validAddresses, nil
 }
 
-type addressFilter func(machine *clusterv1.Machine) string
+type addressFilter func(machine *clusterv1.Machine, family controlplanev1.IPFamily) string
+
+func filterInternalFirst(machine *clusterv1.Machine, family controlplanev1.IPFamily) string {
+	return firstAddress(machine, family, clusterv1.MachineInternalIP, clusterv1.MachineExternalIP)
+}
+
+func filterInternalOnly(machine *clusterv1.Machine, family controlplanev1.IPFamily) string {
+	return firstAddress(machine, family, clusterv1.MachineInternalIP)
+}
+
+func filterExternalOnly(machine *clusterv1.Machine, family controlplanev1.IPFamily) string {
+	return firstAddress(machine, family, clusterv1.MachineExternalIP)
+}
+
+// firstAddress returns the first machine address of the given types which belongs to the given IP family, or the first
+// address of the given types if the machine has none in the family or if no family is given.
+func firstAddress(machine *clusterv1.Machine, family controlplanev1.IPFamily, addressTypes ...clusterv1.MachineAddressType) string {
+	first := ""
 
-func filterInternalFirst(machine *clusterv1.Machine) string {
 	for _, address := range machine.Status.Addresses {
-		switch address.Type {
-		case clusterv1.MachineInternalIP:
-			if address.Address != "" {
-				return address.Address
-			}
-		case clusterv1.MachineExternalIP:
-			if address.Address != "" {
-				return address.Address
-			}
+		if address.Address == "" || !slices.Contains(addressTypes, address.Type) {
+			continue
+		}
+
+		if family == "" || util.IPFamilyOf(address.Address) == family {
+			return address.Address
+		}
+
+		if first == "" {
+			first = address.Address
 		}
 	}
 
-	return ""
+	return first
 }
 
-func filterInternalOnly(machine *clusterv1.Machine) string {
-	for _, address := range machine.Status.Addresses {
-		if address.Type == clusterv1.MachineInternalIP {
-			if address.Address != "" {
-				return address.Address
-			}
-		}
+// JoinableMachines returns the control plane machines that new nodes can join through, i.e. the machines
+// which are not being deleted and whose agent and etcd member are not unhealthy.
+// All the machines are returned if none of them is joinable, so new nodes still have servers to try.
+func JoinableMachines(machines collections.Machines) collections.Machines {
+	joinableMachines := machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		collections.Not(hasFalseCondition(controlplanev1.MachineAgentHealthyCondition)),
+		collections.Not(hasFalseCondition(controlplanev1.MachineEtcdMemberHealthyCondition)),
+	)
+
+	if joinableMachines.Len() == 0 {
+		return machines
 	}
 
-	return ""
+	return joinableMachines
 }
 
-func filterExternalOnly(machine *clusterv1.Machine) string {
-	for _, address := range machine.Status.Addresses {
-		if address.Type == clusterv1.MachineExternalIP {
-			if address.Address != "" {
-				return address.Address
-			}
-		}
+// OrderJoinAddresses returns the registration addresses in the order a node should try them to join the cluster.
+// The sorted addresses are rotated by a hash of the node machine name, so the joins are spread across the servers
+// while the order stays the same for a given machine.
+func OrderJoinAddresses(addresses []string, machineName string) []string {
+	if len(addresses) == 0 {
+		return nil
 	}
 
-	return ""
+	sorted := append([]string{}, addresses...)
+	sort.Strings(sorted)
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(machineName))
+	offset := int(h.Sum32() % uint32(len(sorted)))
+
+	ordered := make([]string, 0, len(sorted))
+	ordered = append(ordered, sorted[offset:]...)
+
+	return append(ordered, sorted[:offset]...)
+}
+
+func hasFalseCondition(conditionType clusterv1.ConditionType) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		return conditions.IsFalse(machine, conditionType)
+	}
 }
@@ -17,7 +17,9 @@ limitations under the License.
 package registration_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,6 +28,7 @@ import (
 	"github.com/rancher/cluster-api-provider-rke2/pkg/registration"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
 )
 
 func TestNewRegistrationMethod(t *testing.T) {
@@ -232,6 +235,69 @@ func TestExternalOnlyMethod(t *testing.T) {
 	}
 }
 
+func TestRegistrationIPFamily(t *testing.T) {
+	testCases := []struct {
+		name              string
+		method            controlplanev1.RegistrationMethod
+		family            controlplanev1.IPFamily
+		machines          []*clusterv1.Machine
+		expectedAddresses []string
+	}{
+		{
+			name:   "first address without a family",
+			method: controlplanev1.RegistrationMethodFavourInternalIPs,
+			machines: []*clusterv1.Machine{
+				createMachine("machine1", []string{"10.0.0.3", "fd00::3"}, nil),
+			},
+			expectedAddresses: []string{"10.0.0.3"},
+		},
+		{
+			name:   "internal IPv6",
+			method: controlplanev1.RegistrationMethodFavourInternalIPs,
+			family: controlplanev1.IPv6IPFamily,
+			machines: []*clusterv1.Machine{
+				createMachine("machine1", []string{"10.0.0.3", "fd00::3"}, []string{"2001:db8::3"}),
+			},
+			expectedAddresses: []string{"fd00::3"},
+		},
+		{
+			name:   "external IPv4",
+			method: controlplanev1.RegistrationMethodExternalIPs,
+			family: controlplanev1.IPv4IPFamily,
+			machines: []*clusterv1.Machine{
+				createMachine("machine1", []string{"10.0.0.3"}, []string{"2001:db8::3", "201.55.56.77"}),
+			},
+			expectedAddresses: []string{"201.55.56.77"},
+		},
+		{
+			name:   "fallback to the other family",
+			method: controlplanev1.RegistrationMethodInternalIPs,
+			family: controlplanev1.IPv6IPFamily,
+			machines: []*clusterv1.Machine{
+				createMachine("machine1", []string{"10.0.0.3", "fd00::3"}, nil),
+				createMachine("machine2", []string{"10.0.0.4"}, nil),
+			},
+			expectedAddresses: []string{"fd00::3", "10.0.0.4"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			regMethod, err := registration.NewRegistrationMethod(string(tc.method))
+			g.Expect(err).NotTo(HaveOccurred())
+
+			rcp := createControlPlane(string(tc.method), "")
+			rcp.Spec.RegistrationIPFamily = tc.family
+
+			actualAddresses, err := regMethod(nil, rcp, collections.FromMachines(tc.machines...))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(actualAddresses).To(ConsistOf(tc.expectedAddresses))
+		})
+	}
+}
+
 func TestAddressMethod(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -343,6 +409,69 @@ func TestControlPlaneMethod(t *testing.T) {
 	}
 }
 
+func TestJoinableMachines(t *testing.T) {
+	healthy := createMachine("healthy", []string{"10.0.0.1"}, nil)
+	conditions.MarkTrue(healthy, controlplanev1.MachineAgentHealthyCondition)
+	conditions.MarkTrue(healthy, controlplanev1.MachineEtcdMemberHealthyCondition)
+
+	unknown := createMachine("unknown", []string{"10.0.0.2"}, nil)
+
+	deleting := createMachine("deleting", []string{"10.0.0.3"}, nil)
+	deleting.DeletionTimestamp = &v1.Time{Time: time.Now()}
+
+	unhealthyAgent := createMachine("unhealthy-agent", []string{"10.0.0.4"}, nil)
+	conditions.MarkFalse(unhealthyAgent, controlplanev1.MachineAgentHealthyCondition, "PodFailed", clusterv1.ConditionSeverityError, "")
+
+	unhealthyEtcd := createMachine("unhealthy-etcd", []string{"10.0.0.5"}, nil)
+	conditions.MarkFalse(unhealthyEtcd, controlplanev1.MachineEtcdMemberHealthyCondition, "MemberUnhealthy", clusterv1.ConditionSeverityError, "")
+
+	testCases := []struct {
+		name             string
+		machines         []*clusterv1.Machine
+		expectedMachines []string
+	}{
+		{
+			name:             "excludes deleting and unhealthy machines",
+			machines:         []*clusterv1.Machine{healthy, unknown, deleting, unhealthyAgent, unhealthyEtcd},
+			expectedMachines: []string{"healthy", "unknown"},
+		},
+		{
+			name:             "keeps all the machines when none is joinable",
+			machines:         []*clusterv1.Machine{deleting, unhealthyAgent},
+			expectedMachines: []string{"deleting", "unhealthy-agent"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			machines := registration.JoinableMachines(collections.FromMachines(tc.machines...))
+			g.Expect(machines.Names()).To(ConsistOf(tc.expectedMachines))
+		})
+	}
+}
+
+func TestOrderJoinAddresses(t *testing.T) {
+	g := NewWithT(t)
+
+	addresses := []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}
+
+	g.Expect(registration.OrderJoinAddresses(nil, "machine1")).To(BeEmpty())
+
+	ordered := registration.OrderJoinAddresses(addresses, "machine1")
+	g.Expect(ordered).To(ConsistOf(addresses))
+	g.Expect(registration.OrderJoinAddresses([]string{"10.0.0.2", "10.0.0.3", "10.0.0.1"}, "machine1")).To(Equal(ordered))
+	g.Expect(addresses).To(Equal([]string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}))
+
+	firstAddresses := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		firstAddresses[registration.OrderJoinAddresses(addresses, fmt.Sprintf("machine-%d", i))[0]] = true
+	}
+
+	g.Expect(firstAddresses).To(HaveLen(len(addresses)))
+}
+
 func createControlPlane(registrationMethod, registrationAddress string) *controlplanev1.RKE2ControlPlane {
 	return &controlplanev1.RKE2ControlPlane{
 		ObjectMeta: v1.ObjectMeta{
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"fmt"
+	"slices"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
+)
+
+const (
+	// ChartValuesManifestDirectory is the directory of the manifests deployed by RKE2 on the servers.
+	ChartValuesManifestDirectory = "/var/lib/rancher/rke2/server/manifests"
+
+	// ChartValuesLabel is the label of the HelmChartConfigs generated from the chart values of the control plane.
+	ChartValuesLabel = "controlplane.cluster.x-k8s.io/chart-values"
+)
+
+// HelmChartConfigGVK is the GroupVersionKind of the HelmChartConfigs overriding the values of the RKE2 packaged charts.
+var HelmChartConfigGVK = schema.GroupVersionKind{Group: "helm.cattle.io", Version: "v1", Kind: "HelmChartConfig"}
+
+// GenerateChartValuesFiles generates the HelmChartConfig manifests, deployed by RKE2 on the servers,
+// which set the values of the RKE2 packaged charts.
+func GenerateChartValuesFiles(chartValues map[string]apiextensionsv1.JSON) ([]bootstrapv1.File, error) {
+	files := []bootstrapv1.File{}
+
+	for _, chart := range sortedCharts(chartValues) {
+		helmChartConfig, err := newHelmChartConfig(chart, chartValues[chart])
+		if err != nil {
+			return nil, err
+		}
+
+		manifest, err := yaml.Marshal(helmChartConfig.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate HelmChartConfig manifest of chart %s: %w", chart, err)
+		}
+
+		files = append(files, bootstrapv1.File{
+			Path:        chartValuesManifestLocation(chart),
+			Content:     string(manifest),
+			Owner:       consts.DefaultFileOwner,
+			Permissions: consts.DefaultFileMode,
+		})
+	}
+
+	return files, nil
+}
+
+// chartValuesManifestLocation returns the location on the servers of the HelmChartConfig manifest of the given chart.
+func chartValuesManifestLocation(chart string) string {
+	return ChartValuesManifestDirectory + "/" + chart + "-chart-values.yaml"
+}
+
+// newHelmChartConfig returns the HelmChartConfig setting the given values of the chart, as YAML values content.
+func newHelmChartConfig(chart string, values apiextensionsv1.JSON) (*unstructured.Unstructured, error) {
+	valuesContent, err := yaml.JSONToYAML(values.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid values of chart %s: %w", chart, err)
+	}
+
+	helmChartConfig := &unstructured.Unstructured{}
+	helmChartConfig.SetGroupVersionKind(HelmChartConfigGVK)
+	helmChartConfig.SetName(chart)
+	helmChartConfig.SetNamespace(metav1.NamespaceSystem)
+	helmChartConfig.SetLabels(map[string]string{ChartValuesLabel: "true"})
+
+	if err := unstructured.SetNestedField(helmChartConfig.Object, string(valuesContent), "spec", "valuesContent"); err != nil {
+		return nil, err
+	}
+
+	return helmChartConfig, nil
+}
+
+// sortedCharts returns the names of the charts with values, sorted so that the generated files are stable.
+func sortedCharts(chartValues map[string]apiextensionsv1.JSON) []string {
+	charts := make([]string, 0, len(chartValues))
+	for chart := range chartValues {
+		charts = append(charts, chart)
+	}
+
+	slices.Sort(charts)
+
+	return charts
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getHelmChartConfig(ctx context.Context, c client.Client, name string) (*unstructured.Unstructured, error) {
+	helmChartConfig := &unstructured.Unstructured{}
+	helmChartConfig.SetGroupVersionKind(HelmChartConfigGVK)
+
+	err := c.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: name}, helmChartConfig)
+
+	return helmChartConfig, err
+}
+
+func valuesContent(helmChartConfig *unstructured.Unstructured) string {
+	valuesContent, _, _ := unstructured.NestedString(helmChartConfig.Object, "spec", "valuesContent")
+
+	return valuesContent
+}
+
+func TestGenerateChartValuesFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	files, err := GenerateChartValuesFiles(map[string]apiextensionsv1.JSON{
+		"rke2-ingress-nginx": {Raw: []byte(`{"controller":{"kind":"DaemonSet"}}`)},
+		"rke2-coredns":       {Raw: []byte(`{"replicaCount":3}`)},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(files).To(HaveLen(2))
+
+	g.Expect(files[0].Path).To(Equal("/var/lib/rancher/rke2/server/manifests/rke2-coredns-chart-values.yaml"))
+	g.Expect(files[0].Content).To(Equal(`apiVersion: helm.cattle.io/v1
+kind: HelmChartConfig
+metadata:
+  labels:
+    controlplane.cluster.x-k8s.io/chart-values: "true"
+  name: rke2-coredns
+  namespace: kube-system
+spec:
+  valuesContent: |
+    replicaCount: 3
+`))
+	g.Expect(files[1].Path).To(Equal("/var/lib/rancher/rke2/server/manifests/rke2-ingress-nginx-chart-values.yaml"))
+	g.Expect(files[1].Content).To(ContainSubstring("    controller:\n      kind: DaemonSet\n"))
+}
+
+func TestReconcileChartValues(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	unmanaged, err := newHelmChartConfig("rke2-canal", apiextensionsv1.JSON{Raw: []byte(`{"flannel":{"iface":"eth1"}}`)})
+	g.Expect(err).ToNot(HaveOccurred())
+	unmanaged.SetLabels(nil)
+
+	w := &Workload{Client: fake.NewClientBuilder().WithObjects(unmanaged).Build()}
+
+	g.Expect(w.ReconcileChartValues(ctx, map[string]apiextensionsv1.JSON{
+		"rke2-coredns":        {Raw: []byte(`{"replicaCount":3}`)},
+		"rke2-metrics-server": {Raw: []byte(`{"replicaCount":2}`)},
+	})).To(Succeed())
+
+	coreDNS, err := getHelmChartConfig(ctx, w.Client, "rke2-coredns")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(valuesContent(coreDNS)).To(Equal("replicaCount: 3\n"))
+
+	// Updated values are applied, and the HelmChartConfigs of charts without values anymore are deleted.
+	g.Expect(w.ReconcileChartValues(ctx, map[string]apiextensionsv1.JSON{
+		"rke2-coredns": {Raw: []byte(`{"replicaCount":5}`)},
+	})).To(Succeed())
+
+	coreDNS, err = getHelmChartConfig(ctx, w.Client, "rke2-coredns")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(valuesContent(coreDNS)).To(Equal("replicaCount: 5\n"))
+
+	_, err = getHelmChartConfig(ctx, w.Client, "rke2-metrics-server")
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	g.Expect(w.ReconcileChartValues(ctx, nil)).To(Succeed())
+
+	_, err = getHelmChartConfig(ctx, w.Client, "rke2-coredns")
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	// HelmChartConfigs created by other means are left untouched, even when values are set for their chart.
+	err = w.ReconcileChartValues(ctx, map[string]apiextensionsv1.JSON{
+		"rke2-canal":   {Raw: []byte(`{"flannel":{"iface":"eth0"}}`)},
+		"rke2-coredns": {Raw: []byte(`{"replicaCount":3}`)},
+	})
+	g.Expect(errors.Is(err, ErrUnmanagedHelmChartConfig)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("rke2-canal"))
+
+	canal, err := getHelmChartConfig(ctx, w.Client, "rke2-canal")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(valuesContent(canal)).To(Equal("flannel:\n  iface: eth1\n"))
+	g.Expect(canal.GetLabels()).ToNot(HaveKey(ChartValuesLabel))
+
+	coreDNS, err = getHelmChartConfig(ctx, w.Client, "rke2-coredns")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(valuesContent(coreDNS)).To(Equal("replicaCount: 3\n"))
+}
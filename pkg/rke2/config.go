@@ -19,9 +19,13 @@ package rke2
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -39,6 +43,9 @@ const (
 	// DefaultRKE2CloudProviderConfigLocation is the default location for the RKE2 cloud provider config file.
 	DefaultRKE2CloudProviderConfigLocation = "/etc/rancher/rke2/cloud-provider-config"
 
+	// DefaultRKE2EncryptionConfigLocation is the default location for a custom encryption configuration of the API server.
+	DefaultRKE2EncryptionConfigLocation = "/etc/rancher/rke2/encryption-config.yaml"
+
 	// DefaultRKE2JoinPort is the default port used for joining nodes to the cluster. It is open on the control plane nodes.
 	DefaultRKE2JoinPort = 9345
 
@@ -79,6 +86,7 @@ sysctl -p /etc/sysctl.d/90-rke2-cis.conf
 
 type rke2ServerConfig struct {
 	AdvertiseAddress                  string            `json:"advertise-address,omitempty"`
+	AgentToken                        string            `json:"agent-token,omitempty"`
 	AuditPolicyFile                   string            `json:"audit-policy-file,omitempty"`
 	BindAddress                       string            `json:"bind-address,omitempty"`
 	CNI                               []string          `json:"cni,omitempty"`
@@ -123,6 +131,8 @@ type rke2ServerConfig struct {
 	KubeSchedulerExtraEnv             map[string]string `json:"kube-scheduler-extra-env,omitempty"`
 	KubeSchedulerExtraMounts          map[string]string `json:"kube-scheduler-extra-mount,omitempty"`
 	KubeSchedulerImage                string            `json:"kube-scheduler-image,omitempty"`
+	SecretsEncryption                 *bool             `json:"secrets-encryption,omitempty"`
+	SecretsEncryptionProvider         string            `json:"secrets-encryption-provider,omitempty"`
 	ServiceNodePortRange              string            `json:"service-node-port-range,omitempty"`
 	TLSSan                            []string          `json:"tls-san,omitempty"`
 
@@ -150,12 +160,16 @@ type ServerConfigOpts struct {
 	Cluster              clusterv1.Cluster
 	ControlPlaneEndpoint string
 	Token                string
+	AgentToken           string
 	ServerURL            string
 	ServerConfig         controlplanev1.RKE2ServerConfig
 	AgentConfig          bootstrapv1.RKE2AgentConfig
 	Ctx                  context.Context
 	Client               client.Client
 	Version              string
+	KubeVIP              *controlplanev1.KubeVIP
+	NodeIP               string
+	NodeExternalIP       string
 }
 
 func newRKE2ServerConfig(opts ServerConfigOpts) (*rke2ServerConfig, []bootstrapv1.File, error) { // nolint:gocyclo
@@ -187,16 +201,21 @@ func newRKE2ServerConfig(opts ServerConfigOpts) (*rke2ServerConfig, []bootstrapv
 		})
 	}
 
+	if err := validateClusterNetwork(&opts.Cluster, opts.ServerConfig.ClusterDNS); err != nil {
+		return nil, nil, fmt.Errorf("invalid cluster network: %w", err)
+	}
+
+	// Dual-stack clusters have a CIDR per IP family, passed comma-separated to RKE2.
 	if opts.Cluster.Spec.ClusterNetwork != nil &&
 		opts.Cluster.Spec.ClusterNetwork.Pods != nil &&
 		len(opts.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks) > 0 {
-		rke2ServerConfig.ClusterCIDR = opts.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks[0]
+		rke2ServerConfig.ClusterCIDR = strings.Join(opts.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks, ",")
 	}
 
 	if opts.Cluster.Spec.ClusterNetwork != nil &&
 		opts.Cluster.Spec.ClusterNetwork.Services != nil &&
 		len(opts.Cluster.Spec.ClusterNetwork.Services.CIDRBlocks) > 0 {
-		rke2ServerConfig.ServiceCIDR = opts.Cluster.Spec.ClusterNetwork.Services.CIDRBlocks[0]
+		rke2ServerConfig.ServiceCIDR = strings.Join(opts.Cluster.Spec.ClusterNetwork.Services.CIDRBlocks, ",")
 	}
 
 	rke2ServerConfig.BindAddress = opts.ServerConfig.BindAddress
@@ -329,8 +348,22 @@ func newRKE2ServerConfig(opts ServerConfigOpts) (*rke2ServerConfig, []bootstrapv
 	}
 
 	rke2ServerConfig.ServiceNodePortRange = opts.ServerConfig.ServiceNodePortRange
+	rke2ServerConfig.AgentToken = opts.AgentToken
 	rke2ServerConfig.TLSSan = append(opts.ServerConfig.TLSSan, opts.ControlPlaneEndpoint)
 
+	if opts.KubeVIP != nil {
+		if opts.KubeVIP.Address != opts.ControlPlaneEndpoint {
+			rke2ServerConfig.TLSSan = append(rke2ServerConfig.TLSSan, opts.KubeVIP.Address)
+		}
+
+		kubeVIPFiles, err := GenerateKubeVIPFiles(opts.KubeVIP)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		files = append(files, kubeVIPFiles...)
+	}
+
 	if opts.ServerConfig.KubeAPIServer != nil {
 		rke2ServerConfig.KubeAPIServerArgs = opts.ServerConfig.KubeAPIServer.ExtraArgs
 		rke2ServerConfig.KubeAPIserverImage = opts.ServerConfig.KubeAPIServer.OverrideImage
@@ -338,6 +371,15 @@ func newRKE2ServerConfig(opts ServerConfigOpts) (*rke2ServerConfig, []bootstrapv
 		rke2ServerConfig.KubeAPIserverExtraEnv = opts.ServerConfig.KubeAPIServer.ExtraEnv
 	}
 
+	if opts.ServerConfig.SecretsEncryption != nil {
+		encryptionFiles, err := configureSecretsEncryption(opts, rke2ServerConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		files = append(files, encryptionFiles...)
+	}
+
 	if opts.ServerConfig.KubeScheduler != nil {
 		rke2ServerConfig.KubeSchedulerArgs = opts.ServerConfig.KubeScheduler.ExtraArgs
 		rke2ServerConfig.KubeSchedulerImage = opts.ServerConfig.KubeScheduler.OverrideImage
@@ -360,6 +402,53 @@ func newRKE2ServerConfig(opts ServerConfigOpts) (*rke2ServerConfig, []bootstrapv
 	return rke2ServerConfig, files, nil
 }
 
+// configureSecretsEncryption sets the secrets encryption options of the server configuration. A custom encryption
+// configuration replaces the one managed by RKE2: it is passed to the API server, which has the file mounted.
+func configureSecretsEncryption(opts ServerConfigOpts, rke2ServerConfig *rke2ServerConfig) ([]bootstrapv1.File, error) {
+	secretsEncryption := opts.ServerConfig.SecretsEncryption
+
+	if secretsEncryption.EncryptionConfigSecret == nil {
+		rke2ServerConfig.SecretsEncryption = secretsEncryption.Enabled
+		rke2ServerConfig.SecretsEncryptionProvider = string(secretsEncryption.Provider)
+
+		return nil, nil
+	}
+
+	encryptionConfigSecret := &corev1.Secret{}
+	if err := opts.Client.Get(opts.Ctx, types.NamespacedName{
+		Name:      secretsEncryption.EncryptionConfigSecret.Name,
+		Namespace: secretsEncryption.EncryptionConfigSecret.Namespace,
+	}, encryptionConfigSecret); err != nil {
+		return nil, fmt.Errorf("failed to get encryption config secret: %w", err)
+	}
+
+	encryptionConfig, ok := encryptionConfigSecret.Data["encryption-config.yaml"]
+	if !ok {
+		return nil, fmt.Errorf("encryption config secret is missing encryption-config.yaml key")
+	}
+
+	rke2ServerConfig.SecretsEncryption = ptr.To(false)
+	rke2ServerConfig.KubeAPIServerArgs = append(slices.Clone(rke2ServerConfig.KubeAPIServerArgs),
+		"encryption-provider-config="+DefaultRKE2EncryptionConfigLocation)
+
+	extraMounts := maps.Clone(rke2ServerConfig.KubeAPIserverExtraMounts)
+	if extraMounts == nil {
+		extraMounts = map[string]string{}
+	}
+
+	extraMounts[DefaultRKE2EncryptionConfigLocation] = DefaultRKE2EncryptionConfigLocation
+	rke2ServerConfig.KubeAPIserverExtraMounts = extraMounts
+
+	return []bootstrapv1.File{
+		{
+			Path:        DefaultRKE2EncryptionConfigLocation,
+			Content:     string(encryptionConfig),
+			Owner:       consts.DefaultFileOwner,
+			Permissions: "0600",
+		},
+	}, nil
+}
+
 type rke2AgentConfig struct {
 	ContainerRuntimeEndpoint      string            `json:"container-runtime-endpoint,omitempty"`
 	CloudProviderConfig           string            `json:"cloud-provider-config,omitempty"`
@@ -405,6 +494,8 @@ type AgentConfigOpts struct {
 	CloudProviderName      string
 	CloudProviderConfigMap *corev1.ObjectReference
 	Version                string
+	NodeIP                 string
+	NodeExternalIP         string
 }
 
 func newRKE2AgentConfig(opts AgentConfigOpts) (*rke2AgentConfig, []bootstrapv1.File, error) {
@@ -532,11 +623,15 @@ func newRKE2AgentConfig(opts AgentConfigOpts) (*rke2AgentConfig, []bootstrapv1.F
 	}
 
 	rke2AgentConfig.Token = opts.Token
+	rke2AgentConfig.NodeIp = opts.NodeIP
+	rke2AgentConfig.NodeExternalIp = opts.NodeExternalIP
 
 	return rke2AgentConfig, files, nil
 }
 
 // GenerateInitControlPlaneConfig generates the rke2 server and agent config for the init control plane node.
+// Only the init control plane node deploys the HelmChartConfigs of the chart values: once the control plane is
+// initialized, they are reconciled on the workload cluster by the control plane controller.
 func GenerateInitControlPlaneConfig(opts ServerConfigOpts) (*rke2ServerConfig, []bootstrapv1.File, error) {
 	if opts.Token == "" {
 		return nil, nil, fmt.Errorf("token is required")
@@ -547,12 +642,23 @@ func GenerateInitControlPlaneConfig(opts ServerConfigOpts) (*rke2ServerConfig, [
 		return nil, nil, fmt.Errorf("failed to generate rke2 server config: %w", err)
 	}
 
+	if len(opts.ServerConfig.ChartValues) > 0 {
+		chartValuesFiles, err := GenerateChartValuesFiles(opts.ServerConfig.ChartValues)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		serverFiles = append(serverFiles, chartValuesFiles...)
+	}
+
 	rke2AgentConfig, agentFiles, err := newRKE2AgentConfig(AgentConfigOpts{
-		AgentConfig: opts.AgentConfig,
-		Client:      opts.Client,
-		Ctx:         opts.Ctx,
-		Token:       opts.Token,
-		Version:     opts.Version,
+		AgentConfig:    opts.AgentConfig,
+		Client:         opts.Client,
+		Ctx:            opts.Ctx,
+		Token:          opts.Token,
+		Version:        opts.Version,
+		NodeIP:         opts.NodeIP,
+		NodeExternalIP: opts.NodeExternalIP,
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate rke2 agent config: %w", err)
@@ -579,12 +685,14 @@ func GenerateJoinControlPlaneConfig(opts ServerConfigOpts) (*rke2ServerConfig, [
 	}
 
 	rke2AgentConfig, agentFiles, err := newRKE2AgentConfig(AgentConfigOpts{
-		AgentConfig: opts.AgentConfig,
-		Client:      opts.Client,
-		Ctx:         opts.Ctx,
-		ServerURL:   opts.ServerURL,
-		Token:       opts.Token,
-		Version:     opts.Version,
+		AgentConfig:    opts.AgentConfig,
+		Client:         opts.Client,
+		Ctx:            opts.Ctx,
+		ServerURL:      opts.ServerURL,
+		Token:          opts.Token,
+		Version:        opts.Version,
+		NodeIP:         opts.NodeIP,
+		NodeExternalIP: opts.NodeExternalIP,
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate rke2 agent config: %w", err)
@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -54,6 +55,7 @@ var _ = Describe("RKE2ServerConfig", func() {
 				},
 			},
 			ControlPlaneEndpoint: "testendpoint",
+			AgentToken:           "testagenttoken",
 			Ctx:                  context.Background(),
 			Client: fake.NewClientBuilder().WithObjects(
 				&corev1.Secret{
@@ -62,10 +64,11 @@ var _ = Describe("RKE2ServerConfig", func() {
 						Namespace: "test",
 					},
 					Data: map[string][]byte{
-						"aws_access_key_id":     []byte("test_id"),
-						"aws_secret_access_key": []byte("test_secret"),
-						"ca.pem":                []byte("test_ca"),
-						"audit-policy.yaml":     []byte("test_audit"),
+						"aws_access_key_id":      []byte("test_id"),
+						"aws_secret_access_key":  []byte("test_secret"),
+						"ca.pem":                 []byte("test_ca"),
+						"audit-policy.yaml":      []byte("test_audit"),
+						"encryption-config.yaml": []byte("test_encryption_config"),
 					},
 				},
 				&corev1.ConfigMap{
@@ -90,7 +93,7 @@ var _ = Describe("RKE2ServerConfig", func() {
 
 				BindAddress:   "testbindaddress",
 				CNI:           controlplanev1.Cilium,
-				ClusterDNS:    "testdns",
+				ClusterDNS:    "192.169.0.10",
 				ClusterDomain: "testdomain",
 				CloudProviderConfigMap: &corev1.ObjectReference{
 					Name:      "test",
@@ -173,6 +176,7 @@ var _ = Describe("RKE2ServerConfig", func() {
 
 		serverConfig := opts.ServerConfig
 		Expect(rke2ServerConfig.AdvertiseAddress).To(Equal(serverConfig.AdvertiseAddress))
+		Expect(rke2ServerConfig.AgentToken).To(Equal(opts.AgentToken))
 		Expect(rke2ServerConfig.AuditPolicyFile).To(Equal("/etc/rancher/rke2/audit-policy.yaml"))
 		Expect(rke2ServerConfig.BindAddress).To(Equal(serverConfig.BindAddress))
 		Expect(rke2ServerConfig.CNI).To(Equal([]string{string(serverConfig.CNI)}))
@@ -243,6 +247,82 @@ var _ = Describe("RKE2ServerConfig", func() {
 		Expect(files[2].Owner).To(Equal(consts.DefaultFileOwner))
 		Expect(files[2].Permissions).To(Equal("0640"))
 	})
+
+	It("should configure the secrets encryption provider", func() {
+		opts.ServerConfig.SecretsEncryption = &controlplanev1.SecretsEncryption{
+			Provider: controlplanev1.SecretBoxSecretsEncryptionProvider,
+		}
+
+		rke2ServerConfig, files, err := newRKE2ServerConfig(*opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rke2ServerConfig.SecretsEncryption).To(BeNil())
+		Expect(rke2ServerConfig.SecretsEncryptionProvider).To(Equal("secretbox"))
+		Expect(files).To(HaveLen(3))
+	})
+
+	It("should pass a custom encryption configuration to the API server", func() {
+		opts.ServerConfig.SecretsEncryption = &controlplanev1.SecretsEncryption{
+			EncryptionConfigSecret: &corev1.ObjectReference{
+				Name:      "test",
+				Namespace: "test",
+			},
+		}
+
+		rke2ServerConfig, files, err := newRKE2ServerConfig(*opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*rke2ServerConfig.SecretsEncryption).To(BeFalse())
+		Expect(rke2ServerConfig.KubeAPIServerArgs).To(Equal([]string{
+			"testarg",
+			"encryption-provider-config=" + DefaultRKE2EncryptionConfigLocation,
+		}))
+		Expect(rke2ServerConfig.KubeAPIserverExtraMounts).To(HaveKeyWithValue(
+			DefaultRKE2EncryptionConfigLocation, DefaultRKE2EncryptionConfigLocation))
+		Expect(opts.ServerConfig.KubeAPIServer.ExtraArgs).To(Equal([]string{"testarg"}))
+		Expect(opts.ServerConfig.KubeAPIServer.ExtraMounts).To(HaveLen(1))
+
+		Expect(files).To(HaveLen(4))
+		Expect(files[3].Path).To(Equal(DefaultRKE2EncryptionConfigLocation))
+		Expect(files[3].Content).To(Equal("test_encryption_config"))
+		Expect(files[3].Permissions).To(Equal("0600"))
+	})
+
+	It("should pass the CIDRs of both IP families of a dual-stack cluster", func() {
+		opts.Cluster.Spec.ClusterNetwork.Pods.CIDRBlocks = []string{"192.168.0.0/16", "fd00:42::/56"}
+		opts.Cluster.Spec.ClusterNetwork.Services.CIDRBlocks = []string{"192.169.0.0/16", "fd00:43::/112"}
+		opts.ServerConfig.ClusterDNS = "192.169.0.10,fd00:43::a"
+
+		rke2ServerConfig, _, err := newRKE2ServerConfig(*opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rke2ServerConfig.ClusterCIDR).To(Equal("192.168.0.0/16,fd00:42::/56"))
+		Expect(rke2ServerConfig.ServiceCIDR).To(Equal("192.169.0.0/16,fd00:43::/112"))
+		Expect(rke2ServerConfig.ClusterDNS).To(Equal("192.169.0.10,fd00:43::a"))
+	})
+
+	It("should render the chart values into HelmChartConfig manifests of the init server only", func() {
+		opts.Token = "testtoken"
+		opts.ServerURL = "https://testendpoint:9345"
+		opts.ServerConfig.ChartValues = map[string]apiextensionsv1.JSON{
+			"rke2-coredns": {Raw: []byte(`{"replicaCount":3}`)},
+		}
+
+		_, files, err := GenerateInitControlPlaneConfig(*opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(ContainElement(And(
+			HaveField("Path", ChartValuesManifestDirectory+"/rke2-coredns-chart-values.yaml"),
+			HaveField("Content", ContainSubstring("kind: HelmChartConfig")),
+		)))
+
+		_, files, err = GenerateJoinControlPlaneConfig(*opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).ToNot(ContainElement(HaveField("Path", HaveSuffix("-chart-values.yaml"))))
+	})
+
+	It("should reject a cluster DNS out of the services CIDRs", func() {
+		opts.ServerConfig.ClusterDNS = "fd00:43::a"
+
+		_, _, err := newRKE2ServerConfig(*opts)
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 var _ = Describe("RKE2 Agent Config", func() {
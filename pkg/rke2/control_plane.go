@@ -49,6 +49,10 @@ type ControlPlane struct {
 	Machines             collections.Machines
 	machinesPatchHelpers map[string]*patch.Helper
 
+	// EtcdLeaderNodeName is the name of the node of the etcd leader, used by the AvoidEtcdLeader scale down policy.
+	// It is only set by the reconciler when this policy is used.
+	EtcdLeaderNodeName string
+
 	// reconciliationTime is the time of the current reconciliation, and should be used for all "now" calculations
 	reconciliationTime metav1.Time
 
@@ -138,9 +142,10 @@ func (c *ControlPlane) AsOwnerReference() *metav1.OwnerReference {
 
 // MachineInFailureDomainWithMostMachines returns the first matching failure domain with machines that has the most control-plane machines on it.
 func (c *ControlPlane) MachineInFailureDomainWithMostMachines(ctx context.Context, machines collections.Machines) (*clusterv1.Machine, error) {
+	machines = c.MachinesEligibleForScaleDown(machines)
 	fd := c.FailureDomainWithMostMachines(ctx, machines)
 	machinesInFailureDomain := machines.Filter(collections.InFailureDomains(fd))
-	machineToMark := machinesInFailureDomain.Oldest()
+	machineToMark := c.machineForScaleDownPolicy(machinesInFailureDomain)
 
 	if machineToMark == nil {
 		return nil, errors.New("failed to pick control plane Machine to mark for deletion")
@@ -149,6 +154,35 @@ func (c *ControlPlane) MachineInFailureDomainWithMostMachines(ctx context.Contex
 	return machineToMark, nil
 }
 
+// MachinesEligibleForScaleDown returns the machines which can be selected for scale down, that is all the machines except
+// the ready and healthy ones annotated with ScaleDownProtectedAnnotation.
+func (c *ControlPlane) MachinesEligibleForScaleDown(machines collections.Machines) collections.Machines {
+	return machines.Filter(collections.Or(
+		collections.Not(collections.HasAnnotationKey(controlplanev1.ScaleDownProtectedAnnotation)),
+		collections.Not(collections.IsReady()),
+		collections.HasUnhealthyCondition,
+	))
+}
+
+// machineForScaleDownPolicy returns the machine selected by the RKE2ControlPlane scale down policy among the given machines.
+func (c *ControlPlane) machineForScaleDownPolicy(machines collections.Machines) *clusterv1.Machine {
+	switch c.RCP.Spec.ScaleDownPolicy {
+	case controlplanev1.NewestScaleDownPolicy:
+		return machines.Newest()
+	case controlplanev1.AvoidEtcdLeaderScaleDownPolicy:
+		if followers := machines.Filter(collections.Not(c.isEtcdLeader)); followers.Len() > 0 {
+			return followers.Oldest()
+		}
+	}
+
+	return machines.Oldest()
+}
+
+// isEtcdLeader returns true if the machine hosts the etcd leader.
+func (c *ControlPlane) isEtcdLeader(machine *clusterv1.Machine) bool {
+	return c.EtcdLeaderNodeName != "" && machine.Status.NodeRef != nil && machine.Status.NodeRef.Name == c.EtcdLeaderNodeName
+}
+
 // MachineWithDeleteAnnotation returns a machine that has been annotated with DeleteMachineAnnotation key.
 func (c *ControlPlane) MachineWithDeleteAnnotation(machines collections.Machines) collections.Machines {
 	// See if there are any machines with DeleteMachineAnnotation key.
@@ -277,6 +311,35 @@ func (c *ControlPlane) MachinesNeedingRollout() collections.Machines {
 	return machines.AnyFilter(
 		// Machines that do not match with RCP config.
 		collections.Not(matchesRCPConfiguration(c.infraResources, c.rke2Configs, c.RCP)),
+		// Machines whose certificates are about to expire.
+		shouldRolloutBefore(&c.reconciliationTime, c.RCP.Spec.RolloutBefore),
+		// Machines created before the rolloutAfter time.
+		shouldRolloutAfter(&c.reconciliationTime, c.RCP.Spec.RolloutAfter),
+		// Machines created before the rotation of the agent token, whose servers accept the previous agent token.
+		shouldRolloutAfter(&c.reconciliationTime, c.RCP.Status.LastAgentTokenRotationTime),
+	)
+}
+
+// MachinesNeedingReplacement returns the machines that need to be rolled out for any other reason
+// than a version change, and therefore can't be upgraded in place.
+func (c *ControlPlane) MachinesNeedingReplacement() collections.Machines {
+	machines := c.Machines.Filter(collections.Not(collections.HasDeletionTimestamp))
+
+	return machines.AnyFilter(
+		collections.Not(matchesRKE2BootstrapConfig(c.rke2Configs, c.RCP)),
+		collections.Not(matchesTemplateClonedFrom(c.infraResources, c.RCP)),
+		shouldRolloutBefore(&c.reconciliationTime, c.RCP.Spec.RolloutBefore),
+		shouldRolloutAfter(&c.reconciliationTime, c.RCP.Spec.RolloutAfter),
+		shouldRolloutAfter(&c.reconciliationTime, c.RCP.Status.LastAgentTokenRotationTime),
+	)
+}
+
+// MachinesNeedingRolloutAfter returns the machines that need to be rolled out because they
+// were created before spec.rolloutAfter.
+func (c *ControlPlane) MachinesNeedingRolloutAfter() collections.Machines {
+	return c.Machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		shouldRolloutAfter(&c.reconciliationTime, c.RCP.Spec.RolloutAfter),
 	)
 }
 
@@ -352,6 +415,11 @@ func (c *ControlPlane) HasUnhealthyMachine() bool {
 	return len(c.UnhealthyMachines()) > 0
 }
 
+// HasHealthyMachineStillProvisioning returns true if any healthy machine in the control plane is still in the process of being provisioned.
+func (c *ControlPlane) HasHealthyMachineStillProvisioning() bool {
+	return len(c.HealthyMachines().Filter(collections.Not(collections.HasNode()))) > 0
+}
+
 // PatchMachines patches the machines in the control plane.
 func (c *ControlPlane) PatchMachines(ctx context.Context) error {
 	errList := []error{}
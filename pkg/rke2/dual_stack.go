@@ -0,0 +1,132 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/util"
+)
+
+// DualStack returns true if the pods or the services of the cluster have CIDRs of both IP families.
+func DualStack(cluster *clusterv1.Cluster) bool {
+	if cluster.Spec.ClusterNetwork == nil {
+		return false
+	}
+
+	return (cluster.Spec.ClusterNetwork.Pods != nil && len(cluster.Spec.ClusterNetwork.Pods.CIDRBlocks) > 1) ||
+		(cluster.Spec.ClusterNetwork.Services != nil && len(cluster.Spec.ClusterNetwork.Services.CIDRBlocks) > 1)
+}
+
+// NodeIPs returns the first IPv4 and the first IPv6 machine addresses of the given type, comma-separated,
+// in the order of the machine addresses.
+func NodeIPs(addresses clusterv1.MachineAddresses, addressType clusterv1.MachineAddressType) string {
+	ips := []string{}
+	families := map[controlplanev1.IPFamily]bool{}
+
+	for _, address := range addresses {
+		if address.Type != addressType {
+			continue
+		}
+
+		family := util.IPFamilyOf(address.Address)
+		if family == "" || families[family] {
+			continue
+		}
+
+		families[family] = true
+
+		ips = append(ips, address.Address)
+	}
+
+	return strings.Join(ips, ",")
+}
+
+// validateClusterNetwork checks that the pods and services CIDRs of the cluster hold one CIDR per IP family at most,
+// of the same IP families in the same order, and that the cluster DNS addresses are in the services CIDRs of their family.
+func validateClusterNetwork(cluster *clusterv1.Cluster, clusterDNS string) error {
+	if cluster.Spec.ClusterNetwork == nil {
+		return nil
+	}
+
+	podFamilies, err := cidrFamilies(cluster.Spec.ClusterNetwork.Pods)
+	if err != nil {
+		return fmt.Errorf("invalid pods CIDR blocks: %w", err)
+	}
+
+	serviceFamilies, err := cidrFamilies(cluster.Spec.ClusterNetwork.Services)
+	if err != nil {
+		return fmt.Errorf("invalid services CIDR blocks: %w", err)
+	}
+
+	if len(podFamilies) > 0 && len(serviceFamilies) > 0 && !slices.Equal(podFamilies, serviceFamilies) {
+		return fmt.Errorf("pods CIDR blocks of families %v do not match services CIDR blocks of families %v", podFamilies, serviceFamilies)
+	}
+
+	if clusterDNS == "" || cluster.Spec.ClusterNetwork.Services == nil {
+		return nil
+	}
+
+	for _, address := range strings.Split(clusterDNS, ",") {
+		ip := net.ParseIP(strings.TrimSpace(address))
+		if ip == nil {
+			return fmt.Errorf("cluster DNS %q is not an IP address", address)
+		}
+
+		inServices := slices.ContainsFunc(cluster.Spec.ClusterNetwork.Services.CIDRBlocks, func(cidr string) bool {
+			_, ipNet, err := net.ParseCIDR(cidr)
+
+			return err == nil && ipNet.Contains(ip)
+		})
+		if !inServices {
+			return fmt.Errorf("cluster DNS %s is not in the services CIDR blocks %v", address, cluster.Spec.ClusterNetwork.Services.CIDRBlocks)
+		}
+	}
+
+	return nil
+}
+
+// cidrFamilies returns the IP families of the given CIDR blocks, in order.
+func cidrFamilies(blocks *clusterv1.NetworkRanges) ([]controlplanev1.IPFamily, error) {
+	families := []controlplanev1.IPFamily{}
+
+	if blocks == nil {
+		return families, nil
+	}
+
+	for _, cidr := range blocks.CIDRBlocks {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		family := util.IPFamilyOf(ip.String())
+		if slices.Contains(families, family) {
+			return nil, fmt.Errorf("more than one %s CIDR block", family)
+		}
+
+		families = append(families, family)
+	}
+
+	return families, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func dualStackCluster(pods, services []string) *clusterv1.Cluster {
+	return &clusterv1.Cluster{
+		Spec: clusterv1.ClusterSpec{
+			ClusterNetwork: &clusterv1.ClusterNetwork{
+				Pods:     &clusterv1.NetworkRanges{CIDRBlocks: pods},
+				Services: &clusterv1.NetworkRanges{CIDRBlocks: services},
+			},
+		},
+	}
+}
+
+func TestDualStack(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(DualStack(&clusterv1.Cluster{})).To(BeFalse())
+	g.Expect(DualStack(dualStackCluster([]string{"10.42.0.0/16"}, []string{"10.43.0.0/16"}))).To(BeFalse())
+	g.Expect(DualStack(dualStackCluster([]string{"10.42.0.0/16", "fd00:42::/56"}, []string{"10.43.0.0/16", "fd00:43::/112"}))).To(BeTrue())
+}
+
+func TestNodeIPs(t *testing.T) {
+	g := NewWithT(t)
+
+	addresses := clusterv1.MachineAddresses{
+		{Type: clusterv1.MachineHostName, Address: "node"},
+		{Type: clusterv1.MachineInternalIP, Address: "fd00::3"},
+		{Type: clusterv1.MachineInternalIP, Address: "10.0.0.3"},
+		{Type: clusterv1.MachineInternalIP, Address: "10.0.0.4"},
+		{Type: clusterv1.MachineExternalIP, Address: "201.55.56.77"},
+	}
+
+	g.Expect(NodeIPs(addresses, clusterv1.MachineInternalIP)).To(Equal("fd00::3,10.0.0.3"))
+	g.Expect(NodeIPs(addresses, clusterv1.MachineExternalIP)).To(Equal("201.55.56.77"))
+	g.Expect(NodeIPs(addresses, clusterv1.MachineInternalDNS)).To(BeEmpty())
+}
+
+func TestValidateClusterNetwork(t *testing.T) {
+	tests := []struct {
+		name       string
+		pods       []string
+		services   []string
+		clusterDNS string
+		wantErr    bool
+	}{
+		{
+			name:       "single-stack",
+			pods:       []string{"10.42.0.0/16"},
+			services:   []string{"10.43.0.0/16"},
+			clusterDNS: "10.43.0.10",
+		},
+		{
+			name:       "dual-stack",
+			pods:       []string{"10.42.0.0/16", "fd00:42::/56"},
+			services:   []string{"10.43.0.0/16", "fd00:43::/112"},
+			clusterDNS: "10.43.0.10,fd00:43::a",
+		},
+		{
+			name:     "invalid CIDR",
+			pods:     []string{"10.42.0.0"},
+			services: []string{"10.43.0.0/16"},
+			wantErr:  true,
+		},
+		{
+			name:     "two CIDRs of the same family",
+			pods:     []string{"10.42.0.0/16", "10.44.0.0/16"},
+			services: []string{"10.43.0.0/16", "10.45.0.0/16"},
+			wantErr:  true,
+		},
+		{
+			name:     "families in a different order",
+			pods:     []string{"fd00:42::/56", "10.42.0.0/16"},
+			services: []string{"10.43.0.0/16", "fd00:43::/112"},
+			wantErr:  true,
+		},
+		{
+			name:       "cluster DNS of another family",
+			pods:       []string{"10.42.0.0/16"},
+			services:   []string{"10.43.0.0/16"},
+			clusterDNS: "fd00:43::a",
+			wantErr:    true,
+		},
+		{
+			name:       "cluster DNS out of the services CIDRs",
+			pods:       []string{"10.42.0.0/16", "fd00:42::/56"},
+			services:   []string{"10.43.0.0/16", "fd00:43::/112"},
+			clusterDNS: "10.43.0.10,fd00:44::a",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := validateClusterNetwork(dualStackCluster(tt.pods, tt.services), tt.clusterDNS)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"fmt"
+	"strings"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
+)
+
+// JoinServerFailoverScriptLocation is the location of the script selecting the server to join on the nodes.
+const JoinServerFailoverScriptLocation = "/opt/rke2-join-server-failover.sh"
+
+// joinServerFailoverScript checks the servers in turn, a few times, and sets the first one answering
+// on the supervisor ping endpoint as the server to join in the RKE2 configuration file.
+// The configured server is kept if none of them answers. The URL globbing of curl is disabled, for the IPv6 addresses in brackets.
+const joinServerFailoverScript = `#!/bin/sh
+for attempt in 1 2 3; do
+  for server in %[1]s; do
+    if curl -gsfk --connect-timeout 5 --max-time 10 "${server}/ping" > /dev/null; then
+      sed -i "s|^server: .*|server: ${server}|" %[2]s
+      exit 0
+    fi
+  done
+  sleep 10
+done
+echo "No RKE2 server is reachable, keeping the configured server" >&2
+`
+
+// GenerateJoinServerFailoverFile generates the script selecting the first reachable server to join
+// among the passed server URLs, in order, before RKE2 is started.
+func GenerateJoinServerFailoverFile(serverURLs []string) bootstrapv1.File {
+	quotedURLs := make([]string, 0, len(serverURLs))
+	for _, serverURL := range serverURLs {
+		quotedURLs = append(quotedURLs, "'"+serverURL+"'")
+	}
+
+	return bootstrapv1.File{
+		Path:        JoinServerFailoverScriptLocation,
+		Content:     fmt.Sprintf(joinServerFailoverScript, strings.Join(quotedURLs, " "), DefaultRKE2ConfigLocation),
+		Owner:       consts.DefaultFileOwner,
+		Permissions: consts.FileModeRootExecutable,
+	}
+}
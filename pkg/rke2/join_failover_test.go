@@ -0,0 +1,36 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
+)
+
+func TestGenerateJoinServerFailoverFile(t *testing.T) {
+	g := NewWithT(t)
+
+	file := GenerateJoinServerFailoverFile([]string{"https://10.0.0.2:9345", "https://10.0.0.1:9345"})
+
+	g.Expect(file.Path).To(Equal(JoinServerFailoverScriptLocation))
+	g.Expect(file.Permissions).To(Equal(consts.FileModeRootExecutable))
+	g.Expect(file.Content).To(ContainSubstring("for server in 'https://10.0.0.2:9345' 'https://10.0.0.1:9345'; do"))
+	g.Expect(file.Content).To(ContainSubstring(`sed -i "s|^server: .*|server: ${server}|" ` + DefaultRKE2ConfigLocation))
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"text/template"
+
+	bootstrapv1 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta1"
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/consts"
+)
+
+const (
+	// KubeVIPManifestLocation is the location of the kube-vip DaemonSet manifest on the servers.
+	KubeVIPManifestLocation = "/var/lib/rancher/rke2/server/manifests/kube-vip.yaml"
+
+	// KubeVIPRBACManifestLocation is the location of the kube-vip RBAC manifest on the servers.
+	KubeVIPRBACManifestLocation = "/var/lib/rancher/rke2/server/manifests/kube-vip-rbac.yaml"
+
+	ipv4HostPrefixLength = 32
+	ipv6HostPrefixLength = 128
+)
+
+const kubeVIPRBACManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: kube-vip
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  annotations:
+    rbac.authorization.kubernetes.io/autoupdate: "true"
+  name: system:kube-vip-role
+rules:
+  - apiGroups: [""]
+    resources: ["services", "services/status", "nodes", "endpoints"]
+    verbs: ["list", "get", "watch", "update"]
+  - apiGroups: ["coordination.k8s.io"]
+    resources: ["leases"]
+    verbs: ["list", "get", "watch", "update", "create"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: system:kube-vip-binding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:kube-vip-role
+subjects:
+  - kind: ServiceAccount
+    name: kube-vip
+    namespace: kube-system
+`
+
+var kubeVIPManifestTemplate = template.Must(template.New("kube-vip").Parse(`apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: kube-vip
+  namespace: kube-system
+  labels:
+    app.kubernetes.io/name: kube-vip
+spec:
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: kube-vip
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: kube-vip
+    spec:
+      nodeSelector:
+        node-role.kubernetes.io/control-plane: "true"
+      tolerations:
+        - effect: NoSchedule
+          operator: Exists
+        - effect: NoExecute
+          operator: Exists
+      hostNetwork: true
+      serviceAccountName: kube-vip
+      containers:
+        - name: kube-vip
+          image: {{ .Image }}
+          imagePullPolicy: IfNotPresent
+          args:
+            - manager
+          env:
+            - name: address
+              value: "{{ .Address }}"
+            - name: vip_interface
+              value: "{{ .Interface }}"
+            - name: vip_cidr
+              value: "{{ .CIDR }}"
+            - name: port
+              value: "6443"
+            - name: cp_enable
+              value: "true"
+            - name: cp_namespace
+              value: kube-system
+{{- if .BGP }}
+            - name: bgp_enable
+              value: "true"
+            - name: bgp_routerinterface
+              value: "{{ .Interface }}"
+            - name: bgp_as
+              value: "{{ .BGP.AS }}"
+            - name: bgp_peers
+              value: "{{ .BGP.PeerAddress }}:{{ .BGP.PeerAS }}::false"
+{{- else }}
+            - name: vip_arp
+              value: "true"
+            - name: vip_leaderelection
+              value: "true"
+            - name: vip_leasename
+              value: plndr-cp-lock
+            - name: vip_leaseduration
+              value: "5"
+            - name: vip_renewdeadline
+              value: "3"
+            - name: vip_retryperiod
+              value: "1"
+{{- end }}
+          securityContext:
+            capabilities:
+              add:
+                - NET_ADMIN
+                - NET_RAW
+`))
+
+// kubeVIPManifestValues are the values of the kube-vip DaemonSet manifest template.
+type kubeVIPManifestValues struct {
+	Image     string
+	Address   string
+	Interface string
+	CIDR      int
+	BGP       *controlplanev1.KubeVIPBGP
+}
+
+// GenerateKubeVIPFiles generates the kube-vip DaemonSet and RBAC manifests, deployed by RKE2 on the servers,
+// which manage the virtual IP of the API server on the control plane nodes.
+func GenerateKubeVIPFiles(kubeVIP *controlplanev1.KubeVIP) ([]bootstrapv1.File, error) {
+	ip := net.ParseIP(kubeVIP.Address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid kube-vip address %q", kubeVIP.Address)
+	}
+
+	values := kubeVIPManifestValues{
+		Image:     kubeVIP.Image,
+		Address:   kubeVIP.Address,
+		Interface: kubeVIP.Interface,
+		CIDR:      ipv6HostPrefixLength,
+	}
+
+	if values.Image == "" {
+		values.Image = controlplanev1.DefaultKubeVIPImage
+	}
+
+	if ip.To4() != nil {
+		values.CIDR = ipv4HostPrefixLength
+	}
+
+	if kubeVIP.Mode == controlplanev1.BGPKubeVIPMode {
+		if kubeVIP.BGP == nil {
+			return nil, fmt.Errorf("kube-vip BGP configuration is required in BGP mode")
+		}
+
+		values.BGP = kubeVIP.BGP
+	}
+
+	manifest := &bytes.Buffer{}
+	if err := kubeVIPManifestTemplate.Execute(manifest, values); err != nil {
+		return nil, fmt.Errorf("failed to generate kube-vip manifest: %w", err)
+	}
+
+	return []bootstrapv1.File{
+		{
+			Path:        KubeVIPRBACManifestLocation,
+			Content:     kubeVIPRBACManifest,
+			Owner:       consts.DefaultFileOwner,
+			Permissions: consts.DefaultFileMode,
+		},
+		{
+			Path:        KubeVIPManifestLocation,
+			Content:     manifest.String(),
+			Owner:       consts.DefaultFileOwner,
+			Permissions: consts.DefaultFileMode,
+		},
+	}, nil
+}
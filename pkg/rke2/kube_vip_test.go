@@ -0,0 +1,109 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+func TestGenerateKubeVIPFiles(t *testing.T) {
+	tests := []struct {
+		name         string
+		kubeVIP      *controlplanev1.KubeVIP
+		wantErr      bool
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			name: "ARP mode with an IPv4 address",
+			kubeVIP: &controlplanev1.KubeVIP{
+				Address:   "192.168.1.100",
+				Interface: "eth0",
+				Mode:      controlplanev1.ARPKubeVIPMode,
+			},
+			wantContains: []string{
+				"image: " + controlplanev1.DefaultKubeVIPImage,
+				"value: \"192.168.1.100\"",
+				"value: \"eth0\"",
+				"name: vip_cidr\n              value: \"32\"",
+				"name: vip_arp",
+			},
+			wantMissing: []string{"bgp_enable"},
+		},
+		{
+			name: "BGP mode with an IPv6 address and a custom image",
+			kubeVIP: &controlplanev1.KubeVIP{
+				Address:   "fd00::100",
+				Interface: "lo",
+				Mode:      controlplanev1.BGPKubeVIPMode,
+				BGP:       &controlplanev1.KubeVIPBGP{AS: 65000, PeerAddress: "fd00::1", PeerAS: 65001},
+				Image:     "registry.example.com/kube-vip:v0.8.0",
+			},
+			wantContains: []string{
+				"image: registry.example.com/kube-vip:v0.8.0",
+				"name: vip_cidr\n              value: \"128\"",
+				"name: bgp_as\n              value: \"65000\"",
+				"value: \"fd00::1:65001::false\"",
+			},
+			wantMissing: []string{"vip_arp"},
+		},
+		{
+			name: "BGP mode without BGP configuration",
+			kubeVIP: &controlplanev1.KubeVIP{
+				Address:   "192.168.1.100",
+				Interface: "eth0",
+				Mode:      controlplanev1.BGPKubeVIPMode,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid address",
+			kubeVIP: &controlplanev1.KubeVIP{Address: "kube-vip.example.com", Interface: "eth0"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			files, err := GenerateKubeVIPFiles(tt.kubeVIP)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+
+				return
+			}
+
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(files).To(HaveLen(2))
+			g.Expect(files[0].Path).To(Equal(KubeVIPRBACManifestLocation))
+			g.Expect(files[1].Path).To(Equal(KubeVIPManifestLocation))
+
+			for _, s := range tt.wantContains {
+				g.Expect(files[1].Content).To(ContainSubstring(s))
+			}
+
+			for _, s := range tt.wantMissing {
+				g.Expect(files[1].Content).ToNot(ContainSubstring(s))
+			}
+		})
+	}
+}
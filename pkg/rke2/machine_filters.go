@@ -3,7 +3,9 @@ package rke2
 import (
 	"encoding/json"
 	"reflect"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -77,10 +79,11 @@ func matchServerConfig(rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.
 		machineServerConfig = &controlplanev1.RKE2ServerConfig{}
 	}
 
-	rcpServerConfig := &rcp.Spec.ServerConfig
-	if rcpServerConfig == nil {
-		rcpServerConfig = &controlplanev1.RKE2ServerConfig{}
-	}
+	rcpServerConfig := rcp.Spec.ServerConfig.DeepCopy()
+
+	// The chart values are applied to the workload cluster, they do not require a rollout.
+	machineServerConfig.ChartValues = nil
+	rcpServerConfig.ChartValues = nil
 
 	// Compare and return
 	return reflect.DeepEqual(machineServerConfig, rcpServerConfig)
@@ -142,3 +145,50 @@ func matchesKubernetesOrRKE2Version(rke2Version string) func(*clusterv1.Machine)
 		return bsutil.CompareVersions(*machine.Spec.Version, rcpKubeVersion)
 	}
 }
+
+// shouldRolloutBefore returns a filter to find all machines whose certificates will
+// expire within the number of days configured in RolloutBefore.
+func shouldRolloutBefore(reconciliationTime *metav1.Time, rolloutBefore *controlplanev1.RolloutBefore) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil || rolloutBefore == nil || rolloutBefore.CertificatesExpiryDays == nil || reconciliationTime == nil {
+			return false
+		}
+
+		expiry := machineCertificatesExpiryDate(machine)
+		if expiry == nil {
+			return false
+		}
+
+		rolloutDeadline := reconciliationTime.Add(time.Duration(*rolloutBefore.CertificatesExpiryDays) * 24 * time.Hour)
+
+		return expiry.Before(rolloutDeadline)
+	}
+}
+
+// shouldRolloutAfter returns a filter to find all machines created before the RolloutAfter time,
+// once that time has been reached.
+func shouldRolloutAfter(reconciliationTime, rolloutAfter *metav1.Time) collections.Func {
+	return func(machine *clusterv1.Machine) bool {
+		if machine == nil || reconciliationTime == nil || rolloutAfter == nil {
+			return false
+		}
+
+		return reconciliationTime.After(rolloutAfter.Time) && machine.CreationTimestamp.Before(rolloutAfter)
+	}
+}
+
+// machineCertificatesExpiryDate returns the certificates expiry date recorded on the machine, preferring
+// the annotation over the status field which is only updated by the machine controller afterwards.
+func machineCertificatesExpiryDate(machine *clusterv1.Machine) *time.Time {
+	if value, ok := machine.GetAnnotations()[clusterv1.MachineCertificatesExpiryDateAnnotation]; ok {
+		if expiry, err := time.Parse(time.RFC3339, value); err == nil {
+			return &expiry
+		}
+	}
+
+	if machine.Status.CertificatesExpiryDate != nil {
+		return &machine.Status.CertificatesExpiryDate.Time
+	}
+
+	return nil
+}
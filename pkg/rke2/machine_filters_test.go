@@ -1,11 +1,15 @@
 package rke2
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/collections"
@@ -68,6 +72,18 @@ var _ = Describe("ServerConfigMatching", func() {
 		res := matchServerConfig(&rcp, &machine)
 		Expect(res).To(BeTrue())
 	})
+
+	It("should ignore the chart values", func() {
+		rcpWithChartValues := rcp.DeepCopy()
+		rcpWithChartValues.Spec.ServerConfig.ChartValues = map[string]apiextensionsv1.JSON{
+			"rke2-coredns": {Raw: []byte(`{"replicaCount":3}`)},
+		}
+
+		Expect(matchServerConfig(rcpWithChartValues, &machine)).To(BeTrue())
+
+		rcpWithChartValues.Spec.ServerConfig.ClusterDomain = "example.org"
+		Expect(matchServerConfig(rcpWithChartValues, &machine)).To(BeFalse())
+	})
 })
 
 var _ = Describe("matchAgentConfig", func() {
@@ -159,3 +175,108 @@ var _ = Describe("matching Kubernetes Version", func() {
 		machine.Spec.Version = &k8sMachineVersion
 	})
 })
+
+var _ = Describe("shouldRolloutBefore", func() {
+	var (
+		now           v1.Time
+		rolloutBefore *controlplanev1.RolloutBefore
+	)
+
+	BeforeEach(func() {
+		now = v1.Now()
+		rolloutBefore = &controlplanev1.RolloutBefore{CertificatesExpiryDays: ptr.To[int32](30)}
+	})
+
+	It("should not rollout when RolloutBefore is not set", func() {
+		m := machine.DeepCopy()
+		m.Status.CertificatesExpiryDate = &v1.Time{Time: now.Add(24 * time.Hour)}
+
+		Expect(shouldRolloutBefore(&now, nil)(m)).To(BeFalse())
+	})
+
+	It("should not rollout when the certificates expiry is unknown", func() {
+		Expect(shouldRolloutBefore(&now, rolloutBefore)(machine.DeepCopy())).To(BeFalse())
+	})
+
+	It("should not rollout when the certificates expire after the configured window", func() {
+		m := machine.DeepCopy()
+		m.Status.CertificatesExpiryDate = &v1.Time{Time: now.Add(60 * 24 * time.Hour)}
+
+		Expect(shouldRolloutBefore(&now, rolloutBefore)(m)).To(BeFalse())
+	})
+
+	It("should rollout when the certificates expire within the configured window", func() {
+		m := machine.DeepCopy()
+		m.Status.CertificatesExpiryDate = &v1.Time{Time: now.Add(10 * 24 * time.Hour)}
+
+		Expect(shouldRolloutBefore(&now, rolloutBefore)(m)).To(BeTrue())
+	})
+
+	It("should prefer the certificates expiry annotation over the status", func() {
+		m := machine.DeepCopy()
+		m.Annotations[clusterv1.MachineCertificatesExpiryDateAnnotation] = now.Add(10 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		m.Status.CertificatesExpiryDate = &v1.Time{Time: now.Add(60 * 24 * time.Hour)}
+
+		Expect(shouldRolloutBefore(&now, rolloutBefore)(m)).To(BeTrue())
+	})
+})
+
+var _ = Describe("shouldRolloutAfter", func() {
+	var (
+		now           v1.Time
+		createdBefore *clusterv1.Machine
+		createdAfter  *clusterv1.Machine
+	)
+
+	BeforeEach(func() {
+		now = v1.Now()
+
+		createdBefore = machine.DeepCopy()
+		createdBefore.CreationTimestamp = v1.NewTime(now.Add(-2 * time.Hour))
+
+		createdAfter = machine.DeepCopy()
+		createdAfter.CreationTimestamp = v1.NewTime(now.Add(-time.Minute))
+	})
+
+	It("should not rollout when RolloutAfter is not set", func() {
+		Expect(shouldRolloutAfter(&now, nil)(createdBefore)).To(BeFalse())
+	})
+
+	It("should not rollout before the RolloutAfter time is reached", func() {
+		rolloutAfter := v1.NewTime(now.Add(time.Hour))
+
+		Expect(shouldRolloutAfter(&now, &rolloutAfter)(createdBefore)).To(BeFalse())
+	})
+
+	It("should only rollout machines created before the RolloutAfter time", func() {
+		rolloutAfter := v1.NewTime(now.Add(-time.Hour))
+
+		Expect(shouldRolloutAfter(&now, &rolloutAfter)(createdBefore)).To(BeTrue())
+		Expect(shouldRolloutAfter(&now, &rolloutAfter)(createdAfter)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Rollout after the agent token rotation", func() {
+	It("should rollout the machines created before the last agent token rotation", func() {
+		now := v1.Now()
+
+		createdBefore := machine.DeepCopy()
+		createdBefore.Name = "created-before"
+		createdBefore.CreationTimestamp = v1.NewTime(now.Add(-2 * time.Hour))
+
+		createdAfter := machine.DeepCopy()
+		createdAfter.Name = "created-after"
+		createdAfter.CreationTimestamp = v1.NewTime(now.Add(-time.Minute))
+
+		controlPlane := &ControlPlane{
+			RCP:                rcp.DeepCopy(),
+			Machines:           collections.FromMachines(createdBefore, createdAfter),
+			reconciliationTime: now,
+		}
+		Expect(controlPlane.MachinesNeedingRollout()).To(BeEmpty())
+
+		controlPlane.RCP.Status.LastAgentTokenRotationTime = &v1.Time{Time: now.Add(-time.Hour)}
+		Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf("created-before"))
+		Expect(controlPlane.MachinesNeedingReplacement().Names()).To(ConsistOf("created-before"))
+	})
+})
@@ -0,0 +1,198 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+const manifestDecoderBufferSize = 4096
+
+// ErrInvalidManifestSources is returned when the manifest files cannot be read from the manifest sources as they are
+// configured, e.g. when several sources define the same file.
+var ErrInvalidManifestSources = errors.New("invalid manifest sources")
+
+// ManifestFile is a manifest file read from the data entry of a manifest source.
+type ManifestFile struct {
+	// Name is the name of the file, unique across the manifest sources.
+	Name string
+
+	// Content is the content of the file.
+	Content string
+}
+
+// GetManifestFiles returns the manifest files of the ManifestsConfigMapReference and the ManifestSources of a
+// RKE2ControlPlane, sorted by name.
+func GetManifestFiles(ctx context.Context, cl client.Reader, rcp *controlplanev1.RKE2ControlPlane) ([]ManifestFile, error) {
+	files := map[string]ManifestFile{}
+
+	if reference := rcp.Spec.ManifestsConfigMapReference; reference.Name != "" {
+		configMap := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: reference.Namespace, Name: reference.Name}, configMap); err != nil {
+			return nil, fmt.Errorf("failed to get manifests ConfigMap %s/%s: %w", reference.Namespace, reference.Name, err)
+		}
+
+		for key, content := range configMap.Data {
+			files[key] = ManifestFile{Name: key, Content: content}
+		}
+	}
+
+	for _, source := range rcp.Spec.ManifestSources {
+		data, err := manifestSourceData(ctx, cl, rcp.Namespace, source)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := source.Keys
+		if len(keys) == 0 {
+			keys = sortedKeys(data)
+		}
+
+		for _, key := range keys {
+			content, ok := data[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: key %s not found in %s %s/%s", ErrInvalidManifestSources, key, source.Kind, rcp.Namespace, source.Name)
+			}
+
+			name := source.FilenamePrefix + key
+			if _, ok := files[name]; ok {
+				return nil, fmt.Errorf("%w: manifest file %s is defined by several sources", ErrInvalidManifestSources, name)
+			}
+
+			files[name] = ManifestFile{Name: name, Content: content}
+		}
+	}
+
+	manifests := make([]ManifestFile, 0, len(files))
+	for _, name := range sortedKeys(files) {
+		manifests = append(manifests, files[name])
+	}
+
+	return manifests, nil
+}
+
+// manifestSourceData returns the data entries of a manifest source.
+func manifestSourceData(ctx context.Context, cl client.Reader, namespace string, source controlplanev1.ManifestSource) (map[string]string, error) {
+	key := client.ObjectKey{Namespace: namespace, Name: source.Name}
+
+	switch source.Kind {
+	case controlplanev1.ConfigMapManifestSource:
+		configMap := &corev1.ConfigMap{}
+		if err := cl.Get(ctx, key, configMap); err != nil {
+			return nil, fmt.Errorf("failed to get manifests ConfigMap %s: %w", key, err)
+		}
+
+		return configMap.Data, nil
+	case controlplanev1.SecretManifestSource:
+		secret := &corev1.Secret{}
+		if err := cl.Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to get manifests Secret %s: %w", key, err)
+		}
+
+		data := make(map[string]string, len(secret.Data))
+		for key, content := range secret.Data {
+			data[key] = string(content)
+		}
+
+		return data, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown manifest source kind %s", ErrInvalidManifestSources, source.Kind)
+	}
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// ParseManifests returns the objects of the manifest files, each file holding YAML or JSON documents.
+// The files are expected in the order of their names, so that the objects are applied in a stable order.
+func ParseManifests(files []ManifestFile) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+
+	for _, file := range files {
+		decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(file.Content), manifestDecoderBufferSize)
+
+		for {
+			object := &unstructured.Unstructured{}
+
+			err := decoder.Decode(&object.Object)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to parse manifest %s: %w", file.Name, err)
+			}
+
+			// Skip the empty documents.
+			if len(object.Object) == 0 {
+				continue
+			}
+
+			if object.GetAPIVersion() == "" || object.GetKind() == "" || object.GetName() == "" {
+				return nil, fmt.Errorf("manifest %s has an object without apiVersion, kind or name", file.Name)
+			}
+
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+// manifestObjectReference returns the reference of an object of the manifests.
+func manifestObjectReference(object *unstructured.Unstructured) controlplanev1.ManifestObjectReference {
+	return controlplanev1.ManifestObjectReference{
+		APIVersion: object.GetAPIVersion(),
+		Kind:       object.GetKind(),
+		Namespace:  object.GetNamespace(),
+		Name:       object.GetName(),
+	}
+}
+
+// sameManifestObject returns true if the references identify the same object, whatever the version of its API.
+func sameManifestObject(a, b controlplanev1.ManifestObjectReference) bool {
+	return apiGroup(a.APIVersion) == apiGroup(b.APIVersion) && a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// apiGroup returns the group of an API version, empty for the core group.
+func apiGroup(apiVersion string) string {
+	group, _, found := strings.Cut(apiVersion, "/")
+	if !found {
+		return ""
+	}
+
+	return group
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+const testManifests = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+data:
+  key: value
+---
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: second
+`
+
+// newManifestsTestClient returns a fake client which handles the server-side apply patches as a create or update,
+// the fake client not supporting them.
+func newManifestsTestClient(applyErr error, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+
+	restMapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion, rbacv1.SchemeGroupVersion})
+	restMapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	restMapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(restMapper).
+		WithObjects(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+
+				if applyErr != nil {
+					return applyErr
+				}
+
+				existing := obj.DeepCopyObject().(client.Object)
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+					if !apierrors.IsNotFound(err) {
+						return err
+					}
+
+					return c.Create(ctx, obj)
+				}
+
+				obj.SetResourceVersion(existing.GetResourceVersion())
+
+				return c.Update(ctx, obj)
+			},
+		}).
+		Build()
+}
+
+func TestParseManifests(t *testing.T) {
+	g := NewWithT(t)
+
+	objects, err := ParseManifests([]ManifestFile{
+		{Name: "a.json", Content: `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "zero"}}`},
+		{Name: "b.yaml", Content: testManifests},
+		{Name: "c.yaml", Content: ""},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(objects).To(HaveLen(3))
+	g.Expect(objects[0].GetName()).To(Equal("zero"))
+	g.Expect(objects[1].GetName()).To(Equal("first"))
+	g.Expect(objects[2].GetKind()).To(Equal("ClusterRole"))
+
+	_, err = ParseManifests([]ManifestFile{{Name: "invalid.yaml", Content: "apiVersion: v1\nkind: ConfigMap\n"}})
+	g.Expect(err).To(MatchError(ContainSubstring("invalid.yaml")))
+
+	_, err = ParseManifests([]ManifestFile{{Name: "invalid.yaml", Content: "apiVersion: [v1"}})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetManifestFiles(t *testing.T) {
+	ctx := context.Background()
+
+	objects := []client.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "legacy"},
+			Data:       map[string]string{"legacy.yaml": "legacy"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "manifests"},
+			Data:       map[string]string{"app.yaml": "app", "extra.yaml": "extra"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "credentials"},
+			Data:       map[string][]byte{"cloud.yaml": []byte("cloud")},
+		},
+	}
+
+	newControlPlane := func(sources ...controlplanev1.ManifestSource) *controlplanev1.RKE2ControlPlane {
+		return &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "test"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				ManifestsConfigMapReference: corev1.ObjectReference{Namespace: "other", Name: "legacy"},
+				ManifestSources:             sources,
+			},
+		}
+	}
+
+	t.Run("reads the files of all the sources", func(t *testing.T) {
+		g := NewWithT(t)
+
+		files, err := GetManifestFiles(ctx, newManifestsTestClient(nil, objects...), newControlPlane(
+			controlplanev1.ManifestSource{Kind: controlplanev1.ConfigMapManifestSource, Name: "manifests", Keys: []string{"app.yaml"}},
+			controlplanev1.ManifestSource{Kind: controlplanev1.SecretManifestSource, Name: "credentials", FilenamePrefix: "secret-"},
+		))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(files).To(Equal([]ManifestFile{
+			{Name: "app.yaml", Content: "app"},
+			{Name: "legacy.yaml", Content: "legacy"},
+			{Name: "secret-cloud.yaml", Content: "cloud"},
+		}))
+	})
+
+	t.Run("rejects the files defined by several sources", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := GetManifestFiles(ctx, newManifestsTestClient(nil, objects...), newControlPlane(
+			controlplanev1.ManifestSource{Kind: controlplanev1.ConfigMapManifestSource, Name: "manifests"},
+			controlplanev1.ManifestSource{Kind: controlplanev1.ConfigMapManifestSource, Name: "manifests", Keys: []string{"app.yaml"}},
+		))
+		g.Expect(err).To(MatchError(ErrInvalidManifestSources))
+		g.Expect(err).To(MatchError(ContainSubstring("app.yaml")))
+	})
+
+	t.Run("rejects the missing keys", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := GetManifestFiles(ctx, newManifestsTestClient(nil, objects...), newControlPlane(
+			controlplanev1.ManifestSource{Kind: controlplanev1.SecretManifestSource, Name: "credentials", Keys: []string{"missing.yaml"}},
+		))
+		g.Expect(err).To(MatchError(ErrInvalidManifestSources))
+	})
+
+	t.Run("returns the missing sources", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := GetManifestFiles(ctx, newManifestsTestClient(nil, objects...), newControlPlane(
+			controlplanev1.ManifestSource{Kind: controlplanev1.SecretManifestSource, Name: "missing"},
+		))
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+}
+
+func TestApplyManifests(t *testing.T) {
+	ctx := context.Background()
+
+	removed := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: "removed"}}
+	applied := []controlplanev1.ManifestObjectReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: metav1.NamespaceSystem, Name: "first"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: metav1.NamespaceSystem, Name: "removed"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: metav1.NamespaceSystem, Name: "already-deleted"},
+	}
+
+	t.Run("applies the manifests and deletes the removed objects", func(t *testing.T) {
+		g := NewWithT(t)
+
+		objects, err := ParseManifests([]ManifestFile{{Name: "manifests.yaml", Content: testManifests}})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		c := newManifestsTestClient(nil, removed.DeepCopy())
+		w := &Workload{Client: c}
+
+		managed, err := w.ApplyManifests(ctx, objects, applied)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(managed).To(ConsistOf(
+			controlplanev1.ManifestObjectReference{APIVersion: "v1", Kind: "ConfigMap", Namespace: metav1.NamespaceSystem, Name: "first"},
+			controlplanev1.ManifestObjectReference{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "second"},
+		))
+
+		configMap := &corev1.ConfigMap{}
+		g.Expect(c.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: "first"}, configMap)).To(Succeed())
+		g.Expect(configMap.Data).To(HaveKeyWithValue("key", "value"))
+		g.Expect(c.Get(ctx, client.ObjectKey{Name: "second"}, &rbacv1.ClusterRole{})).To(Succeed())
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(removed), &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("keeps the previously applied objects when the manifests cannot be applied", func(t *testing.T) {
+		g := NewWithT(t)
+
+		objects, err := ParseManifests([]ManifestFile{{Name: "manifests.yaml", Content: testManifests}})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		c := newManifestsTestClient(errors.New("apply failed"), removed.DeepCopy())
+		w := &Workload{Client: c}
+
+		managed, err := w.ApplyManifests(ctx, objects, applied)
+		g.Expect(err).To(MatchError(ContainSubstring("apply failed")))
+		g.Expect(managed).To(ConsistOf(applied))
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(removed), &corev1.ConfigMap{})).To(Succeed())
+	})
+
+	t.Run("deletes all the objects when the manifests are removed", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newManifestsTestClient(nil, removed.DeepCopy())
+		w := &Workload{Client: c}
+
+		managed, err := w.ApplyManifests(ctx, []*unstructured.Unstructured{}, applied)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(managed).To(BeEmpty())
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(removed), &corev1.ConfigMap{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+}
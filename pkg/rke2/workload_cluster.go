@@ -27,8 +27,10 @@ import (
 	"github.com/blang/semver/v4"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/rest"
@@ -44,6 +46,7 @@ import (
 
 	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
 	"github.com/rancher/cluster-api-provider-rke2/pkg/etcd"
+	"github.com/rancher/cluster-api-provider-rke2/pkg/proxy"
 )
 
 const (
@@ -53,6 +56,8 @@ const (
 	etcdCallTimeout           = 15 * time.Second
 	minimalNodeCount          = 2
 	rke2ServingSecretKey      = "rke2-serving" //nolint: gosec
+	apiServerPort             = 6443
+	apiServerCertCommonName   = "kube-apiserver"
 )
 
 // ErrControlPlaneMinNodes is returned when the control plane has fewer than 2 nodes.
@@ -75,13 +80,54 @@ type WorkloadCluster interface {
 	RemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error
 	ForwardEtcdLeadership(ctx context.Context, machine *clusterv1.Machine, leaderCandidate *clusterv1.Machine) error
 	ReconcileEtcdMembers(ctx context.Context, nodeNames []string, version semver.Version) ([]string, error)
+	ControlPlaneNodeNames(ctx context.Context) ([]string, error)
 	EtcdMembers(ctx context.Context) ([]string, error)
+	EtcdLeader(ctx context.Context) (string, error)
+
+	// Certificate related tasks.
+	GetAPIServerCertificateExpiry(ctx context.Context, nodeName string) (*time.Time, error)
+
+	// In-place upgrade related tasks.
+	CordonAndDrainNode(ctx context.Context, nodeName string) (bool, error)
+	UncordonNode(ctx context.Context, nodeName string) error
+	UpgradeNodeInPlace(ctx context.Context, nodeName, version, image string) (bool, error)
+
+	// Etcd snapshot related tasks.
+	SaveEtcdSnapshot(ctx context.Context, id, nodeName, snapshotName, image string) (*EtcdSnapshotResult, error)
+	StartEtcdSnapshotRestore(ctx context.Context, id, nodeName, snapshotName string, s3 bool, image string) error
+	GetEtcdSnapshotRestorePhase(ctx context.Context, id string) (EtcdSnapshotPhase, string, error)
+	RemoveStaleControlPlaneNodes(ctx context.Context, nodeNames []string) error
+
+	// Etcd maintenance related tasks.
+	EtcdMemberDBStatuses(ctx context.Context, nodeNames []string) ([]EtcdMemberDBStatus, error)
+	CompactEtcd(ctx context.Context, nodeName string, revision int64) error
+	DefragmentEtcdMember(ctx context.Context, nodeName string) error
+	DisarmEtcdAlarm(ctx context.Context, nodeName string, alarm etcd.AlarmType) error
+
+	// Token rotation related tasks.
+	RotateToken(ctx context.Context, id, nodeName, token, newToken, image string) (*TokenRotationResult, error)
+	CleanupTokenRotation(ctx context.Context, id string) error
+
+	// Secrets encryption related tasks.
+	RunSecretsEncryptionStep(ctx context.Context, id, nodeName, command, image string) (SecretsEncryptionStepPhase, string, error)
+	CleanupSecretsEncryptionStep(ctx context.Context, id string) error
+
+	// Packaged charts related tasks.
+	ReconcileChartValues(ctx context.Context, chartValues map[string]apiextensionsv1.JSON) error
+
+	// Manifests related tasks.
+	ApplyManifests(
+		ctx context.Context,
+		objects []*unstructured.Unstructured,
+		applied []controlplanev1.ManifestObjectReference,
+	) ([]controlplanev1.ManifestObjectReference, error)
 }
 
 // Workload defines operations on workload clusters.
 type Workload struct {
 	ctrlclient.Client
 
+	restConfig          *rest.Config
 	Nodes               map[string]*corev1.Node
 	nodePatchHelpers    map[string]*patch.Helper
 	etcdClientGenerator etcd.ClientFor
@@ -102,6 +148,7 @@ func (m *Management) NewWorkload(
 
 	restConfig = rest.CopyConfig(restConfig)
 	restConfig.Timeout = remoteEtcdTimeout
+	workload.restConfig = restConfig
 
 	// Retrieves the etcd CA key Pair
 	etcdKeyPair, err := m.getEtcdCAKeyPair(ctx, m.SecretCachingClient, clusterKey)
@@ -192,6 +239,43 @@ func (w *Workload) InitWorkload(ctx context.Context, cp *ControlPlane) error {
 	return nil
 }
 
+// GetAPIServerCertificateExpiry returns the expiry of the kube-apiserver serving certificate on the given node.
+func (w *Workload) GetAPIServerCertificateExpiry(ctx context.Context, nodeName string) (*time.Time, error) {
+	dialer, err := proxy.NewDialer(proxy.Proxy{
+		Kind:       "pods",
+		Namespace:  metav1.NamespaceSystem,
+		KubeConfig: w.restConfig,
+		Port:       apiServerPort,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get certificate expiry for kube-apiserver on Node/%s: failed to create dialer", nodeName)
+	}
+
+	rawConn, err := dialer.DialContextWithAddr(ctx, fmt.Sprintf("%s-%s", apiServerCertCommonName, nodeName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get certificate expiry for kube-apiserver on Node/%s: unable to dial to kube-apiserver", nodeName)
+	}
+
+	// The serving certificate is only inspected, not trusted, so verification is skipped.
+	conn := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+	if err := conn.HandshakeContext(ctx); err != nil {
+		_ = rawConn.Close()
+
+		return nil, errors.Wrapf(err, "unable to get certificate expiry for kube-apiserver on Node/%s: TLS handshake with the kube-apiserver failed", nodeName)
+	}
+
+	defer conn.Close()
+
+	for _, cert := range conn.ConnectionState().PeerCertificates {
+		if cert.Subject.CommonName == apiServerCertCommonName {
+			return &cert.NotAfter, nil
+		}
+	}
+
+	return nil, errors.Errorf("unable to get certificate expiry for kube-apiserver on Node/%s: couldn't get peer certificate with cn=%q",
+		nodeName, apiServerCertCommonName)
+}
+
 // ClusterStatus holds stats information about the cluster.
 type ClusterStatus struct {
 	// Nodes are a total count of nodes
@@ -202,6 +286,22 @@ type ClusterStatus struct {
 	HasRKE2ServingSecret bool
 }
 
+// ControlPlaneNodeNames returns the names of the control plane nodes of the workload cluster,
+// including the nodes without a machine, like the existing servers of an imported cluster.
+func (w *Workload) ControlPlaneNodeNames(ctx context.Context) ([]string, error) {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list control plane nodes")
+	}
+
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+
+	return nodeNames, nil
+}
+
 func (w *Workload) getControlPlaneNodes(ctx context.Context) (*corev1.NodeList, error) {
 	nodes := &corev1.NodeList{}
 	labels := map[string]string{
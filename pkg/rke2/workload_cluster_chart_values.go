@@ -0,0 +1,115 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ErrUnmanagedHelmChartConfig is returned when chart values are set for a chart whose HelmChartConfig was not
+// generated from the chart values of the control plane.
+var ErrUnmanagedHelmChartConfig = errors.New("HelmChartConfig not managed by the control plane")
+
+// ReconcileChartValues applies the values of the RKE2 packaged charts to the HelmChartConfigs of the workload cluster,
+// so that the changes are rolled out by the RKE2 Helm controller without replacing the servers. The HelmChartConfigs
+// generated for charts which no longer have values are deleted. Only the HelmChartConfigs with the ChartValuesLabel
+// are updated: the HelmChartConfigs created by other means are left untouched, and reported with
+// ErrUnmanagedHelmChartConfig once the other charts are reconciled.
+func (w *Workload) ReconcileChartValues(ctx context.Context, chartValues map[string]apiextensionsv1.JSON) error {
+	logger := log.FromContext(ctx)
+	unmanaged := []string{}
+
+	for _, chart := range sortedCharts(chartValues) {
+		desired, err := newHelmChartConfig(chart, chartValues[chart])
+		if err != nil {
+			return err
+		}
+
+		current := &unstructured.Unstructured{}
+		current.SetGroupVersionKind(HelmChartConfigGVK)
+
+		err = w.Client.Get(ctx, ctrlclient.ObjectKeyFromObject(desired), current)
+		if apierrors.IsNotFound(err) {
+			logger.Info("Creating HelmChartConfig", "chart", chart)
+
+			if err := w.Client.Create(ctx, desired); err != nil {
+				return errors.Wrapf(err, "failed to create HelmChartConfig %s", chart)
+			}
+
+			continue
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to get HelmChartConfig %s", chart)
+		}
+
+		if current.GetLabels()[ChartValuesLabel] != "true" {
+			unmanaged = append(unmanaged, chart)
+
+			continue
+		}
+
+		if equality.Semantic.DeepEqual(current.Object["spec"], desired.Object["spec"]) {
+			continue
+		}
+
+		logger.Info("Updating HelmChartConfig", "chart", chart)
+
+		current.Object["spec"] = desired.Object["spec"]
+
+		if err := w.Client.Update(ctx, current); err != nil {
+			return errors.Wrapf(err, "failed to update HelmChartConfig %s", chart)
+		}
+	}
+
+	helmChartConfigs := &unstructured.UnstructuredList{}
+	helmChartConfigs.SetGroupVersionKind(HelmChartConfigGVK.GroupVersion().WithKind(HelmChartConfigGVK.Kind + "List"))
+
+	if err := w.Client.List(ctx, helmChartConfigs,
+		ctrlclient.InNamespace(metav1.NamespaceSystem),
+		ctrlclient.MatchingLabels{ChartValuesLabel: "true"},
+	); err != nil {
+		return errors.Wrap(err, "failed to list HelmChartConfigs")
+	}
+
+	for i := range helmChartConfigs.Items {
+		helmChartConfig := &helmChartConfigs.Items[i]
+		if _, ok := chartValues[helmChartConfig.GetName()]; ok {
+			continue
+		}
+
+		logger.Info("Deleting HelmChartConfig", "chart", helmChartConfig.GetName())
+
+		if err := w.Client.Delete(ctx, helmChartConfig); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete HelmChartConfig %s", helmChartConfig.GetName())
+		}
+	}
+
+	if len(unmanaged) > 0 {
+		return errors.Wrapf(ErrUnmanagedHelmChartConfig, "charts %s", strings.Join(unmanaged, ", "))
+	}
+
+	return nil
+}
@@ -28,6 +28,7 @@ import (
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
+	"github.com/rancher/cluster-api-provider-rke2/pkg/etcd"
 	etcdutil "github.com/rancher/cluster-api-provider-rke2/pkg/etcd/util"
 )
 
@@ -261,3 +262,204 @@ func (w *Workload) EtcdMembers(ctx context.Context) ([]string, error) {
 
 	return names, nil
 }
+
+// EtcdLeader returns the name of the node of the etcd member which is currently the leader of the etcd cluster,
+// or an empty string for clusters without an etcd certificate secret.
+func (w *Workload) EtcdLeader(ctx context.Context) (string, error) {
+	// Return early for clusters without an etcd certificate secret
+	if w.etcdClientGenerator == nil {
+		return "", nil
+	}
+
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list control plane nodes")
+	}
+
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+
+	etcdClient, err := w.etcdClientGenerator.ForLeader(ctx, nodeNames)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list etcd members using etcd client")
+	}
+
+	for _, member := range members {
+		if member.ID != etcdClient.LeaderID {
+			continue
+		}
+
+		// RKE2 names the etcd members after their node with a suffix, the leader is mapped back to its node the way
+		// the members are looked up for a node. The longest matching node name wins, e.g. cp-10 over cp-1.
+		leaderNodeName := ""
+
+		for _, nodeName := range nodeNames {
+			if etcdutil.MemberForName([]*etcd.Member{member}, nodeName) != nil && len(nodeName) > len(leaderNodeName) {
+				leaderNodeName = nodeName
+			}
+		}
+
+		if leaderNodeName == "" {
+			return "", errors.Errorf("failed to find the node of the etcd leader %s", member.Name)
+		}
+
+		return leaderNodeName, nil
+	}
+
+	return "", errors.Errorf("failed to find the etcd leader with ID %d in the etcd members", etcdClient.LeaderID)
+}
+
+// EtcdMemberDBStatus describes the database and the alarms of the etcd member running on a node.
+type EtcdMemberDBStatus struct {
+	NodeName    string
+	MemberID    uint64
+	Leader      bool
+	DBSize      int64
+	DBSizeInUse int64
+	Revision    int64
+	Alarms      []etcd.AlarmType
+}
+
+// HasAlarm returns true if the member raised the given alarm.
+func (s EtcdMemberDBStatus) HasAlarm(alarm etcd.AlarmType) bool {
+	for _, a := range s.Alarms {
+		if a == alarm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EtcdMemberDBStatuses returns the status of the etcd member running on each of the given nodes,
+// as reported by the member itself.
+func (w *Workload) EtcdMemberDBStatuses(ctx context.Context, nodeNames []string) ([]EtcdMemberDBStatus, error) {
+	// Return early for clusters without an etcd certificate secret
+	if w.etcdClientGenerator == nil {
+		return []EtcdMemberDBStatus{}, nil
+	}
+
+	statuses := make([]EtcdMemberDBStatus, 0, len(nodeNames))
+
+	for _, nodeName := range nodeNames {
+		status, err := w.etcdMemberDBStatus(ctx, nodeName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get status of etcd member on node %s", nodeName)
+		}
+
+		statuses = append(statuses, *status)
+	}
+
+	return statuses, nil
+}
+
+func (w *Workload) etcdMemberDBStatus(ctx context.Context, nodeName string) (*EtcdMemberDBStatus, error) {
+	etcdClient, err := w.etcdClientGenerator.ForFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	memberStatus, err := etcdClient.MemberStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	alarms, err := etcdClient.Alarms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &EtcdMemberDBStatus{
+		NodeName:    nodeName,
+		MemberID:    memberStatus.ID,
+		Leader:      memberStatus.ID == memberStatus.LeaderID,
+		DBSize:      memberStatus.DBSize,
+		DBSizeInUse: memberStatus.DBSizeInUse,
+		Revision:    memberStatus.Revision,
+		Alarms:      []etcd.AlarmType{},
+	}
+
+	for _, alarm := range alarms {
+		if alarm.MemberID == memberStatus.ID {
+			status.Alarms = append(status.Alarms, alarm.Type)
+		}
+	}
+
+	return status, nil
+}
+
+// CompactEtcd compacts the etcd key-value store history up to the given revision, through the etcd member
+// running on the given node. Compaction is replicated to all the members.
+func (w *Workload) CompactEtcd(ctx context.Context, nodeName string, revision int64) error {
+	// Return early for clusters without an etcd certificate secret
+	if w.etcdClientGenerator == nil {
+		return nil
+	}
+
+	etcdClient, err := w.etcdClientGenerator.ForFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	return etcdClient.Compact(ctx, revision)
+}
+
+// DefragmentEtcdMember defragments the database of the etcd member running on the given node.
+// The member does not serve requests while it is being defragmented.
+func (w *Workload) DefragmentEtcdMember(ctx context.Context, nodeName string) error {
+	// Return early for clusters without an etcd certificate secret
+	if w.etcdClientGenerator == nil {
+		return nil
+	}
+
+	etcdClient, err := w.etcdClientGenerator.ForFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	if err := etcdClient.Defragment(ctx); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Defragmented etcd member", "node", nodeName)
+
+	return nil
+}
+
+// DisarmEtcdAlarm disarms the given alarm raised by the etcd member running on the given node.
+func (w *Workload) DisarmEtcdAlarm(ctx context.Context, nodeName string, alarm etcd.AlarmType) error {
+	// Return early for clusters without an etcd certificate secret
+	if w.etcdClientGenerator == nil {
+		return nil
+	}
+
+	etcdClient, err := w.etcdClientGenerator.ForFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	memberStatus, err := etcdClient.MemberStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := etcdClient.DisarmAlarm(ctx, memberStatus.ID, alarm); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Disarmed etcd alarm", "node", nodeName, "alarm", etcd.AlarmTypeName[alarm])
+
+	return nil
+}
@@ -474,6 +474,112 @@ func TestReconcileEtcdMembers(t *testing.T) {
 	}
 }
 
+func TestEtcdMaintenance(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	clients := map[string]*etcdfake.FakeEtcdClient{}
+	for i, nodeName := range []string{"cp1", "cp2"} {
+		clients[nodeName] = &etcdfake.FakeEtcdClient{
+			EtcdEndpoints: []string{"etcd-" + nodeName},
+			StatusResponse: &clientv3.StatusResponse{
+				Header:      &pb.ResponseHeader{MemberId: uint64(i + 1), Revision: 42},
+				Leader:      1,
+				DbSize:      200,
+				DbSizeInUse: 50,
+			},
+			AlarmResponse: &clientv3.AlarmResponse{
+				Alarms: []*pb.AlarmMember{{MemberID: 2, Alarm: pb.AlarmType_NOSPACE}},
+			},
+		}
+	}
+
+	w := &Workload{
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forNodesClientFunc: func(n []string) (*etcd.Client, error) {
+				return &etcd.Client{
+					EtcdClient:  clients[n[0]],
+					Endpoint:    clients[n[0]].EtcdEndpoints[0],
+					CallTimeout: etcd.DefaultCallTimeout,
+				}, nil
+			},
+		},
+	}
+
+	statuses, err := w.EtcdMemberDBStatuses(ctx, []string{"cp1", "cp2"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(statuses).To(Equal([]EtcdMemberDBStatus{
+		{NodeName: "cp1", MemberID: 1, Leader: true, DBSize: 200, DBSizeInUse: 50, Revision: 42, Alarms: []etcd.AlarmType{}},
+		{NodeName: "cp2", MemberID: 2, DBSize: 200, DBSizeInUse: 50, Revision: 42, Alarms: []etcd.AlarmType{etcd.AlarmNoSpace}},
+	}))
+	g.Expect(statuses[1].HasAlarm(etcd.AlarmNoSpace)).To(BeTrue())
+
+	g.Expect(w.CompactEtcd(ctx, "cp1", 42)).To(Succeed())
+	g.Expect(clients["cp1"].CompactedRevision).To(Equal(int64(42)))
+
+	g.Expect(w.DefragmentEtcdMember(ctx, "cp2")).To(Succeed())
+	g.Expect(clients["cp2"].Defragmented).To(Equal([]string{"etcd-cp2"}))
+	g.Expect(clients["cp1"].Defragmented).To(BeEmpty())
+
+	g.Expect(w.DisarmEtcdAlarm(ctx, "cp2", etcd.AlarmNoSpace)).To(Succeed())
+	g.Expect(clients["cp2"].DisarmedAlarms).To(ConsistOf(&clientv3.AlarmMember{MemberID: 2, Alarm: pb.AlarmType_NOSPACE}))
+
+	clients["cp2"].ErrorResponse = errors.New("etcd is unavailable")
+
+	_, err = w.EtcdMemberDBStatuses(ctx, []string{"cp1", "cp2"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEtcdLeader(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	fakeEtcdClient := &etcdfake.FakeEtcdClient{
+		MemberListResponse: &clientv3.MemberListResponse{
+			Members: []*pb.Member{
+				{Name: "cp-1-4e8b2a1c", ID: uint64(101)},
+				{Name: "cp-10-9f3d7c2e", ID: uint64(102)},
+			},
+		},
+		AlarmResponse: &clientv3.AlarmResponse{
+			Alarms: []*pb.AlarmMember{},
+		},
+	}
+	w := &Workload{
+		Client: &fakeClient{list: &corev1.NodeList{
+			Items: []corev1.Node{nodeNamed("cp-1"), nodeNamed("cp-10")},
+		}},
+		etcdClientGenerator: &fakeEtcdClientGenerator{
+			forLeaderClient: &etcd.Client{EtcdClient: fakeEtcdClient, LeaderID: 102},
+		},
+	}
+
+	leader, err := w.EtcdLeader(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(leader).To(Equal("cp-10"))
+
+	w.etcdClientGenerator = &fakeEtcdClientGenerator{
+		forLeaderClient: &etcd.Client{EtcdClient: fakeEtcdClient, LeaderID: 101},
+	}
+
+	leader, err = w.EtcdLeader(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(leader).To(Equal("cp-1"))
+
+	w.etcdClientGenerator = &fakeEtcdClientGenerator{
+		forLeaderClient: &etcd.Client{EtcdClient: fakeEtcdClient, LeaderID: 555},
+	}
+
+	_, err = w.EtcdLeader(ctx)
+	g.Expect(err).To(HaveOccurred())
+
+	w.etcdClientGenerator = nil
+
+	leader, err = w.EtcdLeader(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(leader).To(BeEmpty())
+}
+
 type fakeEtcdClientGenerator struct {
 	forNodesClient     *etcd.Client
 	forNodesClientFunc func([]string) (*etcd.Client, error)
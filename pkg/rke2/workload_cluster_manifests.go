@@ -0,0 +1,117 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta1"
+)
+
+// ManifestsFieldOwner is the field manager of the manifests applied to the workload cluster.
+const ManifestsFieldOwner = "rke2-control-plane"
+
+// ApplyManifests applies the objects of the manifests to the workload cluster with server-side apply, then deletes
+// the objects of the previously applied manifests which are not among them anymore. The objects without a namespace
+// are applied to the kube-system namespace, as RKE2 does for the manifests of the servers.
+// It returns the references of the objects managed afterwards, which include the previously applied objects
+// when some objects could not be applied, so that they are pruned once all the manifests are applied.
+func (w *Workload) ApplyManifests(
+	ctx context.Context,
+	objects []*unstructured.Unstructured,
+	applied []controlplanev1.ManifestObjectReference,
+) ([]controlplanev1.ManifestObjectReference, error) {
+	logger := log.FromContext(ctx)
+
+	managed := []controlplanev1.ManifestObjectReference{}
+	errs := []error{}
+
+	for _, object := range objects {
+		object = object.DeepCopy()
+
+		if object.GetNamespace() == "" {
+			namespaced, err := w.Client.IsObjectNamespaced(object)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "failed to get the scope of %s %s", object.GetKind(), object.GetName()))
+
+				continue
+			}
+
+			if namespaced {
+				object.SetNamespace(metav1.NamespaceSystem)
+			}
+		}
+
+		if err := w.Client.Patch(ctx, object, ctrlclient.Apply,
+			ctrlclient.FieldOwner(ManifestsFieldOwner), ctrlclient.ForceOwnership); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to apply %s %s", object.GetKind(), ctrlclient.ObjectKeyFromObject(object)))
+
+			continue
+		}
+
+		managed = append(managed, manifestObjectReference(object))
+	}
+
+	if len(errs) > 0 {
+		// Keep the previously applied objects, they are pruned once all the manifests are applied.
+		for _, reference := range applied {
+			if !containsManifestObject(managed, reference) {
+				managed = append(managed, reference)
+			}
+		}
+
+		return managed, kerrors.NewAggregate(errs)
+	}
+
+	for _, reference := range applied {
+		if containsManifestObject(managed, reference) {
+			continue
+		}
+
+		object := &unstructured.Unstructured{}
+		object.SetAPIVersion(reference.APIVersion)
+		object.SetKind(reference.Kind)
+		object.SetNamespace(reference.Namespace)
+		object.SetName(reference.Name)
+
+		logger.Info("Deleting object removed from the manifests", "kind", reference.Kind, "object", ctrlclient.ObjectKeyFromObject(object))
+
+		if err := w.Client.Delete(ctx, object); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			errs = append(errs, errors.Wrapf(err, "failed to delete %s %s", reference.Kind, ctrlclient.ObjectKeyFromObject(object)))
+			managed = append(managed, reference)
+		}
+	}
+
+	return managed, kerrors.NewAggregate(errs)
+}
+
+// containsManifestObject returns true if the object is among the references.
+func containsManifestObject(references []controlplanev1.ManifestObjectReference, reference controlplanev1.ManifestObjectReference) bool {
+	return slices.ContainsFunc(references, func(r controlplanev1.ManifestObjectReference) bool {
+		return sameManifestObject(r, reference)
+	})
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	secretsEncryptionJobPrefix = "rke2-secrets-encrypt-"
+	secretsEncryptionJobLabel  = "secrets-encryption.cluster.x-k8s.io/name"
+	secretsEncryptionJobTTL    = 3600
+
+	// SecretsEncryptionPrepareCommand adds a new encryption key, not used to encrypt yet.
+	SecretsEncryptionPrepareCommand = "prepare"
+
+	// SecretsEncryptionRotateCommand makes the new encryption key the one used to encrypt.
+	SecretsEncryptionRotateCommand = "rotate"
+
+	// SecretsEncryptionReencryptCommand encrypts all the Secrets with the new key and removes the previous key.
+	SecretsEncryptionReencryptCommand = "reencrypt"
+)
+
+// SecretsEncryptionStepPhase describes the progress of a step of the rotation of the secrets encryption keys.
+type SecretsEncryptionStepPhase string
+
+const (
+	// SecretsEncryptionStepRunning is the phase of a step being run.
+	SecretsEncryptionStepRunning SecretsEncryptionStepPhase = "Running"
+
+	// SecretsEncryptionStepSucceeded is the phase of a step run successfully.
+	SecretsEncryptionStepSucceeded SecretsEncryptionStepPhase = "Succeeded"
+
+	// SecretsEncryptionStepFailed is the phase of a step that could not be run.
+	SecretsEncryptionStepFailed SecretsEncryptionStepPhase = "Failed"
+)
+
+// RunSecretsEncryptionStep runs a step of the rotation of the secrets encryption keys on the given server node:
+// the `rke2 secrets-encrypt` command, when one is given, followed by a restart of the RKE2 server.
+// As the API server is restarted with the RKE2 server, the step is run by a transient systemd unit on the host,
+// launched and waited for by a privileged Job pinned to the node. The Job is identified by the given id, so that
+// subsequent calls report the progress of the same step, and uses the given image tagged with the RKE2 version of the node.
+func (w *Workload) RunSecretsEncryptionStep(
+	ctx context.Context, id, nodeName, command, image string,
+) (SecretsEncryptionStepPhase, string, error) {
+	name := secretsEncryptionJobName(id)
+	job := &batchv1.Job{}
+
+	err := w.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: metav1.NamespaceSystem, Name: name}, job)
+	if apierrors.IsNotFound(err) {
+		jobImage, err := w.nodeJobImage(ctx, nodeName, image)
+		if err != nil {
+			return "", "", err
+		}
+
+		log.FromContext(ctx).Info("Creating secrets encryption job", "node", nodeName, "command", command)
+
+		if err := w.Client.Create(ctx, newSecretsEncryptionJob(name, nodeName, command, jobImage)); err != nil {
+			return "", "", errors.Wrapf(err, "failed to create secrets encryption job on Node/%s", nodeName)
+		}
+
+		return SecretsEncryptionStepRunning, "", nil
+	} else if err != nil {
+		return "", "", errors.Wrapf(err, "failed to get secrets encryption job on Node/%s", nodeName)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type { //nolint:exhaustive
+		case batchv1.JobFailed:
+			return SecretsEncryptionStepFailed, condition.Message, nil
+		case batchv1.JobComplete:
+			return SecretsEncryptionStepSucceeded, "", nil
+		}
+	}
+
+	return SecretsEncryptionStepRunning, "", nil
+}
+
+// CleanupSecretsEncryptionStep deletes the Job of the step of the rotation of the secrets encryption keys with the given id.
+func (w *Workload) CleanupSecretsEncryptionStep(ctx context.Context, id string) error {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: secretsEncryptionJobName(id)}}
+	if err := w.Client.Delete(ctx, job, ctrlclient.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete secrets encryption job")
+	}
+
+	return nil
+}
+
+// secretsEncryptionJobName returns a name for the secrets encryption job which fits the label value length limit.
+func secretsEncryptionJobName(id string) string {
+	hash := sha256.Sum256([]byte(id))
+
+	return secretsEncryptionJobPrefix + fmt.Sprintf("%x", hash)[:jobNameHashLength]
+}
+
+// secretsEncryptionScript returns the script running the `rke2 secrets-encrypt` command, if any, and restarting the RKE2 server.
+// The reencryption runs in the background, the RKE2 server is only restarted once it is finished.
+func secretsEncryptionScript(command string) string {
+	script := "set -e; export PATH=$PATH:/usr/local/bin:/opt/rke2/bin; "
+
+	if command != "" {
+		script += fmt.Sprintf("rke2 secrets-encrypt %s; ", command)
+	}
+
+	if command == SecretsEncryptionReencryptCommand {
+		script += "until rke2 secrets-encrypt status | grep -q reencrypt_finished; do sleep 5; done; "
+	}
+
+	return script + "systemctl restart rke2-server"
+}
+
+func newSecretsEncryptionJob(name, nodeName, command, image string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				secretsEncryptionJobLabel: name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			// A failed step is not retried before the Job is garbage collected, as the RKE2 server may have to be looked at.
+			BackoffLimit:            ptr.To[int32](0),
+			TTLSecondsAfterFinished: ptr.To[int32](secretsEncryptionJobTTL),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						secretsEncryptionJobLabel: name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostPID:       true,
+					HostNetwork:   true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "secrets-encrypt",
+							Image: image,
+							Command: []string{
+								"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--",
+								"systemd-run", "--unit", name, "--collect", "--wait", "/bin/sh", "-c", secretsEncryptionScript(command),
+							},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: ptr.To(true),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunSecretsEncryptionStep(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.3+rke2r1"}},
+	}
+	w := &Workload{Client: fake.NewClientBuilder().WithObjects(node).Build()}
+	key := client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: secretsEncryptionJobName("uid")}
+
+	phase, _, err := w.RunSecretsEncryptionStep(ctx, "uid", "cp1", SecretsEncryptionPrepareCommand, "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(phase).To(Equal(SecretsEncryptionStepRunning))
+
+	job := &batchv1.Job{}
+	g.Expect(w.Client.Get(ctx, key, job)).To(Succeed())
+	g.Expect(job.Spec.Template.Spec.NodeName).To(Equal("cp1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("rancher/rke2-upgrade:v1.29.3-rke2r1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElement(
+		"set -e; export PATH=$PATH:/usr/local/bin:/opt/rke2/bin; rke2 secrets-encrypt prepare; systemctl restart rke2-server"))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "boom"}}
+	g.Expect(w.Client.Status().Update(ctx, job)).To(Succeed())
+
+	phase, message, err := w.RunSecretsEncryptionStep(ctx, "uid", "cp1", SecretsEncryptionPrepareCommand, "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(phase).To(Equal(SecretsEncryptionStepFailed))
+	g.Expect(message).To(Equal("boom"))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	g.Expect(w.Client.Status().Update(ctx, job)).To(Succeed())
+
+	phase, _, err = w.RunSecretsEncryptionStep(ctx, "uid", "cp1", SecretsEncryptionPrepareCommand, "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(phase).To(Equal(SecretsEncryptionStepSucceeded))
+
+	g.Expect(w.CleanupSecretsEncryptionStep(ctx, "uid")).To(Succeed())
+	g.Expect(apierrors.IsNotFound(w.Client.Get(ctx, key, &batchv1.Job{}))).To(BeTrue())
+	g.Expect(w.CleanupSecretsEncryptionStep(ctx, "uid")).To(Succeed())
+}
+
+func TestSecretsEncryptionScript(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(secretsEncryptionScript("")).To(Equal("set -e; export PATH=$PATH:/usr/local/bin:/opt/rke2/bin; systemctl restart rke2-server"))
+	g.Expect(secretsEncryptionScript(SecretsEncryptionReencryptCommand)).To(Equal(
+		"set -e; export PATH=$PATH:/usr/local/bin:/opt/rke2/bin; rke2 secrets-encrypt reencrypt; " +
+			"until rke2 secrets-encrypt status | grep -q reencrypt_finished; do sleep 5; done; systemctl restart rke2-server"))
+}
@@ -0,0 +1,391 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	etcdSnapshotJobPrefix  = "rke2-etcd-snapshot-"
+	etcdSnapshotJobLabel   = "etcd-snapshot.cluster.x-k8s.io/name"
+	etcdSnapshotJobTTL     = 3600
+	etcdSnapshotJobBackoff = 2
+
+	etcdSnapshotRestoreJobPrefix = "rke2-etcd-restore-"
+	defaultEtcdSnapshotDir       = "/var/lib/rancher/rke2/server/db/snapshots"
+)
+
+// etcdSnapshotFileGVK is the kind RKE2 uses to publish the snapshots taken on the cluster.
+var etcdSnapshotFileGVK = schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "ETCDSnapshotFileList"}
+
+// EtcdSnapshotPhase describes the progress of an etcd snapshot.
+type EtcdSnapshotPhase string
+
+const (
+	// EtcdSnapshotRunning is the phase of a snapshot being taken.
+	EtcdSnapshotRunning EtcdSnapshotPhase = "Running"
+
+	// EtcdSnapshotSucceeded is the phase of a snapshot taken successfully.
+	EtcdSnapshotSucceeded EtcdSnapshotPhase = "Succeeded"
+
+	// EtcdSnapshotFailed is the phase of a snapshot that could not be taken.
+	EtcdSnapshotFailed EtcdSnapshotPhase = "Failed"
+)
+
+// EtcdSnapshotResult holds the information about an etcd snapshot taken on a node.
+type EtcdSnapshotResult struct {
+	Phase          EtcdSnapshotPhase
+	Message        string
+	SnapshotName   string
+	Location       string
+	Size           *resource.Quantity
+	CompletionTime *metav1.Time
+}
+
+// SaveEtcdSnapshot runs `rke2 etcd-snapshot save` on the given node, using the snapshot directory and S3 target
+// configured for the RKE2 server on the node. The snapshot is taken by a privileged Job pinned to the node,
+// identified by the given id so that subsequent calls report the progress of the same snapshot.
+// The Job uses the RKE2 upgrade image matching the RKE2 version of the node, which is already needed for in-place upgrades.
+func (w *Workload) SaveEtcdSnapshot(ctx context.Context, id, nodeName, snapshotName, image string) (*EtcdSnapshotResult, error) {
+	job := &batchv1.Job{}
+
+	err := w.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: metav1.NamespaceSystem, Name: etcdSnapshotJobName(id)}, job)
+	if apierrors.IsNotFound(err) {
+		jobImage, err := w.nodeJobImage(ctx, nodeName, image)
+		if err != nil {
+			return nil, err
+		}
+
+		log.FromContext(ctx).Info("Creating etcd snapshot job", "node", nodeName, "snapshot", snapshotName)
+
+		if err := w.Client.Create(ctx, newEtcdSnapshotJob(id, nodeName, snapshotName, jobImage)); err != nil {
+			return nil, errors.Wrapf(err, "failed to create etcd snapshot job on Node/%s", nodeName)
+		}
+
+		return &EtcdSnapshotResult{Phase: EtcdSnapshotRunning}, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get etcd snapshot job on Node/%s", nodeName)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type { //nolint:exhaustive
+		case batchv1.JobFailed:
+			return &EtcdSnapshotResult{Phase: EtcdSnapshotFailed, Message: condition.Message}, nil
+		case batchv1.JobComplete:
+			result, err := w.getEtcdSnapshotFile(ctx, nodeName, snapshotName, job.CreationTimestamp.Time)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Phase = EtcdSnapshotSucceeded
+			if result.CompletionTime == nil {
+				result.CompletionTime = job.Status.CompletionTime
+			}
+
+			return result, nil
+		}
+	}
+
+	return &EtcdSnapshotResult{Phase: EtcdSnapshotRunning}, nil
+}
+
+// getEtcdSnapshotFile looks up the ETCDSnapshotFile published by RKE2 for a snapshot taken on the node after the given time.
+// Snapshots uploaded to S3 are preferred over local ones. Older RKE2 versions do not publish ETCDSnapshotFiles,
+// in which case only the snapshot name prefix is known.
+func (w *Workload) getEtcdSnapshotFile(ctx context.Context, nodeName, snapshotName string, after time.Time) (*EtcdSnapshotResult, error) {
+	result := &EtcdSnapshotResult{SnapshotName: fmt.Sprintf("%s-%s", snapshotName, nodeName)}
+
+	files := &unstructured.UnstructuredList{}
+	files.SetGroupVersionKind(etcdSnapshotFileGVK)
+
+	if err := w.Client.List(ctx, files); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return result, nil
+		}
+
+		return nil, errors.Wrap(err, "failed to list etcd snapshot files")
+	}
+
+	for _, file := range files.Items {
+		name, _, _ := unstructured.NestedString(file.Object, "spec", "snapshotName")
+		node, _, _ := unstructured.NestedString(file.Object, "spec", "nodeName")
+		location, _, _ := unstructured.NestedString(file.Object, "spec", "location")
+		_, isS3, _ := unstructured.NestedMap(file.Object, "spec", "s3")
+
+		if !strings.HasPrefix(name, result.SnapshotName+"-") || file.GetCreationTimestamp().Time.Before(after.Truncate(time.Second)) {
+			continue
+		}
+
+		if node != nodeName && !isS3 {
+			continue
+		}
+
+		// Prefer S3 snapshots as they survive the loss of the node.
+		if result.Location != "" && !isS3 {
+			continue
+		}
+
+		result.SnapshotName = name
+		result.Location = location
+
+		if size, found, _ := unstructured.NestedString(file.Object, "status", "size"); found {
+			if quantity, err := resource.ParseQuantity(size); err == nil {
+				result.Size = &quantity
+			}
+		}
+
+		if creationTime, found, _ := unstructured.NestedString(file.Object, "status", "creationTime"); found {
+			if parsed, err := time.Parse(time.RFC3339, creationTime); err == nil {
+				result.CompletionTime = &metav1.Time{Time: parsed}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// etcdSnapshotJobName returns a name for the snapshot job which fits the label value length limit.
+func etcdSnapshotJobName(id string) string {
+	hash := sha256.Sum256([]byte(id))
+
+	return etcdSnapshotJobPrefix + fmt.Sprintf("%x", hash)[:jobNameHashLength]
+}
+
+func newEtcdSnapshotJob(id, nodeName, snapshotName, image string) *batchv1.Job {
+	hostPathDirectory := corev1.HostPathDirectory
+	name := etcdSnapshotJobName(id)
+
+	// The rke2 binary and its configuration, including the snapshot directory and S3 settings, are read from the host.
+	script := fmt.Sprintf("PATH=$PATH:/usr/local/bin:/opt/rke2/bin rke2 etcd-snapshot save --name %q", snapshotName)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				etcdSnapshotJobLabel: name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](etcdSnapshotJobBackoff),
+			TTLSecondsAfterFinished: ptr.To[int32](etcdSnapshotJobTTL),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						etcdSnapshotJobLabel: name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostNetwork:   true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:    "etcd-snapshot",
+							Image:   image,
+							Command: []string{"chroot", hostRootMountPath, "/bin/sh", "-c", script},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: ptr.To(true),
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "host-root", MountPath: hostRootMountPath},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "host-root",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+									Type: &hostPathDirectory,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// StartEtcdSnapshotRestore resets etcd on the given node from the snapshot, by running
+// `rke2 server --cluster-reset --cluster-reset-restore-path` and restarting the RKE2 server.
+// As the RKE2 server, and the API server with it, is stopped during the reset, the commands are run by a transient
+// systemd unit on the host, launched by a privileged Job pinned to the node. The Job is identified by the given id
+// and uses the given image tagged with the RKE2 version of the node.
+func (w *Workload) StartEtcdSnapshotRestore(ctx context.Context, id, nodeName, snapshotName string, s3 bool, image string) error {
+	jobImage, err := w.nodeJobImage(ctx, nodeName, image)
+	if err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Creating etcd snapshot restore job", "node", nodeName, "snapshot", snapshotName)
+
+	job := newEtcdSnapshotRestoreJob(id, nodeName, snapshotName, s3, jobImage)
+	if err := w.Client.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create etcd snapshot restore job on Node/%s", nodeName)
+	}
+
+	return nil
+}
+
+// GetEtcdSnapshotRestorePhase returns the progress of the restore started by StartEtcdSnapshotRestore with the given id.
+// The restore Job is created after the snapshot was taken, so it is no longer known once etcd has been reset
+// from the snapshot: its absence is what reports the restore as succeeded.
+func (w *Workload) GetEtcdSnapshotRestorePhase(ctx context.Context, id string) (EtcdSnapshotPhase, string, error) {
+	job := &batchv1.Job{}
+
+	err := w.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: metav1.NamespaceSystem, Name: etcdSnapshotRestoreJobName(id)}, job)
+	if apierrors.IsNotFound(err) {
+		return EtcdSnapshotSucceeded, "", nil
+	} else if err != nil {
+		return "", "", errors.Wrap(err, "failed to get etcd snapshot restore job")
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return EtcdSnapshotFailed, condition.Message, nil
+		}
+	}
+
+	return EtcdSnapshotRunning, "", nil
+}
+
+// RemoveStaleControlPlaneNodes deletes the control plane Nodes other than the given ones.
+// After an etcd restore, the Nodes of the control plane machines deleted before the restore are restored as well.
+func (w *Workload) RemoveStaleControlPlaneNodes(ctx context.Context, nodeNames []string) error {
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list control plane nodes")
+	}
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if slices.Contains(nodeNames, node.Name) {
+			continue
+		}
+
+		log.FromContext(ctx).Info("Deleting stale control plane node", "node", node.Name)
+
+		if err := w.Client.Delete(ctx, node); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete Node/%s", node.Name)
+		}
+	}
+
+	return nil
+}
+
+// etcdSnapshotRestoreJobName returns a name for the restore job which fits the label value length limit.
+func etcdSnapshotRestoreJobName(id string) string {
+	hash := sha256.Sum256([]byte(id))
+
+	return etcdSnapshotRestoreJobPrefix + fmt.Sprintf("%x", hash)[:jobNameHashLength]
+}
+
+// etcdSnapshotRestorePath returns the path of a local snapshot file, relative names being looked up in the default snapshot directory.
+// Snapshots stored in S3 are referenced by their name.
+func etcdSnapshotRestorePath(snapshotName string, s3 bool) string {
+	if s3 || path.IsAbs(snapshotName) {
+		return snapshotName
+	}
+
+	return path.Join(defaultEtcdSnapshotDir, snapshotName)
+}
+
+func newEtcdSnapshotRestoreJob(id, nodeName, snapshotName string, s3 bool, image string) *batchv1.Job {
+	name := etcdSnapshotRestoreJobName(id)
+
+	resetArgs := fmt.Sprintf("--cluster-reset --cluster-reset-restore-path=%q", etcdSnapshotRestorePath(snapshotName, s3))
+	if s3 {
+		resetArgs += " --etcd-s3"
+	}
+
+	// The RKE2 server is started again even if the reset fails, to bring the cluster back in its previous state.
+	script := fmt.Sprintf("export PATH=$PATH:/usr/local/bin:/opt/rke2/bin; systemctl stop rke2-server && rke2 server %s; systemctl start rke2-server",
+		resetArgs)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				etcdSnapshotJobLabel: name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			// The Job must not be garbage collected, as its absence reports the restore as completed.
+			BackoffLimit: ptr.To[int32](0),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						etcdSnapshotJobLabel: name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostPID:       true,
+					HostNetwork:   true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "etcd-snapshot-restore",
+							Image: image,
+							Command: []string{
+								"nsenter", "--target", "1", "--mount", "--uts", "--ipc", "--net", "--pid", "--",
+								"systemd-run", "--unit", name, "--collect", "/bin/sh", "-c", script,
+							},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: ptr.To(true),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
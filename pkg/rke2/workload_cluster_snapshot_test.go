@@ -0,0 +1,98 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEtcdSnapshotRestore(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.3+rke2r1"}},
+	}
+	w := &Workload{Client: fake.NewClientBuilder().WithObjects(node).Build()}
+
+	g.Expect(w.StartEtcdSnapshotRestore(ctx, "uid", "cp1", "snap-cp1-1700000000", false, "rancher/rke2-upgrade")).To(Succeed())
+	// Starting the restore again is a no-op.
+	g.Expect(w.StartEtcdSnapshotRestore(ctx, "uid", "cp1", "snap-cp1-1700000000", false, "rancher/rke2-upgrade")).To(Succeed())
+
+	job := &batchv1.Job{}
+	g.Expect(w.Client.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: etcdSnapshotRestoreJobName("uid")}, job)).To(Succeed())
+	g.Expect(job.Spec.TTLSecondsAfterFinished).To(BeNil())
+	g.Expect(job.Spec.Template.Spec.NodeName).To(Equal("cp1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("rancher/rke2-upgrade:v1.29.3-rke2r1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElement(
+		ContainSubstring(`--cluster-reset-restore-path="/var/lib/rancher/rke2/server/db/snapshots/snap-cp1-1700000000"`)))
+
+	phase, _, err := w.GetEtcdSnapshotRestorePhase(ctx, "uid")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(phase).To(Equal(EtcdSnapshotRunning))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "boom"}}
+	g.Expect(w.Client.Status().Update(ctx, job)).To(Succeed())
+
+	phase, message, err := w.GetEtcdSnapshotRestorePhase(ctx, "uid")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(phase).To(Equal(EtcdSnapshotFailed))
+	g.Expect(message).To(Equal("boom"))
+
+	// Once etcd is reset from the snapshot, the restore job is no longer known.
+	g.Expect(w.Client.Delete(ctx, job)).To(Succeed())
+
+	phase, _, err = w.GetEtcdSnapshotRestorePhase(ctx, "uid")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(phase).To(Equal(EtcdSnapshotSucceeded))
+}
+
+func TestEtcdSnapshotRestorePath(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(etcdSnapshotRestorePath("snap", false)).To(Equal("/var/lib/rancher/rke2/server/db/snapshots/snap"))
+	g.Expect(etcdSnapshotRestorePath("/data/snap", false)).To(Equal("/data/snap"))
+	g.Expect(etcdSnapshotRestorePath("snap", true)).To(Equal("snap"))
+}
+
+func TestRemoveStaleControlPlaneNodes(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	controlPlaneNode := func(name string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{labelNodeRoleControlPlane: "true"}}}
+	}
+	worker := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+
+	w := &Workload{Client: fake.NewClientBuilder().WithObjects(controlPlaneNode("cp1"), controlPlaneNode("cp2"), worker).Build()}
+
+	g.Expect(w.RemoveStaleControlPlaneNodes(ctx, []string{"cp1"})).To(Succeed())
+
+	nodes := &corev1.NodeList{}
+	g.Expect(w.Client.List(ctx, nodes)).To(Succeed())
+	g.Expect(nodes.Items).To(HaveLen(2))
+	g.Expect([]string{nodes.Items[0].Name, nodes.Items[1].Name}).To(ConsistOf("cp1", "worker"))
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	tokenRotationJobPrefix  = "rke2-token-rotate-"
+	tokenRotationJobLabel   = "token-rotation.cluster.x-k8s.io/name"
+	tokenRotationJobTTL     = 3600
+	tokenRotationJobBackoff = 2
+
+	tokenRotationTokenKey    = "token"
+	tokenRotationNewTokenKey = "new-token"
+)
+
+// TokenRotationPhase describes the progress of a token rotation.
+type TokenRotationPhase string
+
+const (
+	// TokenRotationRunning is the phase of a token being rotated.
+	TokenRotationRunning TokenRotationPhase = "Running"
+
+	// TokenRotationSucceeded is the phase of a token rotated successfully.
+	TokenRotationSucceeded TokenRotationPhase = "Succeeded"
+
+	// TokenRotationFailed is the phase of a token that could not be rotated.
+	TokenRotationFailed TokenRotationPhase = "Failed"
+)
+
+// TokenRotationResult holds the information about a token rotation.
+type TokenRotationResult struct {
+	Phase    TokenRotationPhase
+	Message  string
+	NewToken string
+}
+
+// RotateToken replaces the server token of the cluster with the new token, by running `rke2 token rotate` on the given server node.
+// The rotation is run by a privileged Job pinned to the node, identified by the given id so that subsequent calls report
+// the progress of the same rotation. The tokens are passed to the Job through a Secret, created with the Job: the new token
+// of a rotation already started is kept, and returned once the rotation succeeded. The Job uses the given image tagged with
+// the RKE2 version of the node.
+func (w *Workload) RotateToken(ctx context.Context, id, nodeName, token, newToken, image string) (*TokenRotationResult, error) {
+	name := tokenRotationJobName(id)
+	job := &batchv1.Job{}
+
+	err := w.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: metav1.NamespaceSystem, Name: name}, job)
+	if apierrors.IsNotFound(err) {
+		jobImage, err := w.nodeJobImage(ctx, nodeName, image)
+		if err != nil {
+			return nil, err
+		}
+
+		log.FromContext(ctx).Info("Creating token rotation job", "node", nodeName)
+
+		secret := newTokenRotationSecret(name, token, newToken)
+		if err := w.Client.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+			return nil, errors.Wrap(err, "failed to create token rotation secret")
+		}
+
+		if err := w.Client.Create(ctx, newTokenRotationJob(name, nodeName, jobImage)); err != nil {
+			return nil, errors.Wrapf(err, "failed to create token rotation job on Node/%s", nodeName)
+		}
+
+		return &TokenRotationResult{Phase: TokenRotationRunning}, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get token rotation job on Node/%s", nodeName)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type { //nolint:exhaustive
+		case batchv1.JobFailed:
+			return &TokenRotationResult{Phase: TokenRotationFailed, Message: condition.Message}, nil
+		case batchv1.JobComplete:
+			secret := &corev1.Secret{}
+			if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: metav1.NamespaceSystem, Name: name}, secret); err != nil {
+				return nil, errors.Wrap(err, "failed to get token rotation secret")
+			}
+
+			return &TokenRotationResult{Phase: TokenRotationSucceeded, NewToken: string(secret.Data[tokenRotationNewTokenKey])}, nil
+		}
+	}
+
+	return &TokenRotationResult{Phase: TokenRotationRunning}, nil
+}
+
+// CleanupTokenRotation deletes the Job and the Secret of the token rotation with the given id, so the tokens
+// are not kept in the workload cluster once the rotation is over.
+func (w *Workload) CleanupTokenRotation(ctx context.Context, id string) error {
+	name := tokenRotationJobName(id)
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: name}}
+	if err := w.Client.Delete(ctx, job, ctrlclient.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete token rotation job")
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: name}}
+	if err := w.Client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete token rotation secret")
+	}
+
+	return nil
+}
+
+// tokenRotationJobName returns a name for the token rotation job which fits the label value length limit.
+func tokenRotationJobName(id string) string {
+	hash := sha256.Sum256([]byte(id))
+
+	return tokenRotationJobPrefix + fmt.Sprintf("%x", hash)[:jobNameHashLength]
+}
+
+func newTokenRotationSecret(name, token, newToken string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				tokenRotationJobLabel: name,
+			},
+		},
+		Data: map[string][]byte{
+			tokenRotationTokenKey:    []byte(token),
+			tokenRotationNewTokenKey: []byte(newToken),
+		},
+	}
+}
+
+func newTokenRotationJob(name, nodeName, image string) *batchv1.Job {
+	hostPathDirectory := corev1.HostPathDirectory
+
+	// The rke2 binary is run from the host, the tokens being read from the environment of the container.
+	script := `PATH=$PATH:/usr/local/bin:/opt/rke2/bin rke2 token rotate --token "$TOKEN" --new-token "$NEW_TOKEN"`
+
+	secretEnv := func(envName, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: envName,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Key:                  key,
+				},
+			},
+		}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				tokenRotationJobLabel: name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](tokenRotationJobBackoff),
+			TTLSecondsAfterFinished: ptr.To[int32](tokenRotationJobTTL),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						tokenRotationJobLabel: name,
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostNetwork:   true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:    "token-rotate",
+							Image:   image,
+							Command: []string{"chroot", hostRootMountPath, "/bin/sh", "-c", script},
+							Env: []corev1.EnvVar{
+								secretEnv("TOKEN", tokenRotationTokenKey),
+								secretEnv("NEW_TOKEN", tokenRotationNewTokenKey),
+							},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: ptr.To(true),
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "host-root", MountPath: hostRootMountPath},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "host-root",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+									Type: &hostPathDirectory,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
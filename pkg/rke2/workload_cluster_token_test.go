@@ -0,0 +1,97 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRotateToken(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.3+rke2r1"}},
+	}
+	w := &Workload{Client: fake.NewClientBuilder().WithObjects(node).Build()}
+	key := client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: tokenRotationJobName("uid")}
+
+	result, err := w.RotateToken(ctx, "uid", "cp1", "old", "new", "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Phase).To(Equal(TokenRotationRunning))
+
+	// A rotation already started keeps its new token.
+	result, err = w.RotateToken(ctx, "uid", "cp1", "old", "other", "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Phase).To(Equal(TokenRotationRunning))
+
+	job := &batchv1.Job{}
+	g.Expect(w.Client.Get(ctx, key, job)).To(Succeed())
+	g.Expect(job.Spec.Template.Spec.NodeName).To(Equal("cp1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("rancher/rke2-upgrade:v1.29.3-rke2r1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElement(ContainSubstring("rke2 token rotate")))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	g.Expect(w.Client.Status().Update(ctx, job)).To(Succeed())
+
+	result, err = w.RotateToken(ctx, "uid", "cp1", "old", "other", "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Phase).To(Equal(TokenRotationSucceeded))
+	g.Expect(result.NewToken).To(Equal("new"))
+
+	g.Expect(w.CleanupTokenRotation(ctx, "uid")).To(Succeed())
+	g.Expect(apierrors.IsNotFound(w.Client.Get(ctx, key, &batchv1.Job{}))).To(BeTrue())
+	g.Expect(apierrors.IsNotFound(w.Client.Get(ctx, key, &corev1.Secret{}))).To(BeTrue())
+
+	// Cleaning up again is a no-op.
+	g.Expect(w.CleanupTokenRotation(ctx, "uid")).To(Succeed())
+}
+
+func TestRotateTokenFailed(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.29.3+rke2r1"}},
+	}
+	w := &Workload{Client: fake.NewClientBuilder().WithObjects(node).Build()}
+
+	_, err := w.RotateToken(ctx, "uid", "cp1", "old", "new", "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	job := &batchv1.Job{}
+	g.Expect(w.Client.Get(ctx, client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: tokenRotationJobName("uid")}, job)).To(Succeed())
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "boom"}}
+	g.Expect(w.Client.Status().Update(ctx, job)).To(Succeed())
+
+	result, err := w.RotateToken(ctx, "uid", "cp1", "old", "new", "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Phase).To(Equal(TokenRotationFailed))
+	g.Expect(result.Message).To(Equal("boom"))
+}
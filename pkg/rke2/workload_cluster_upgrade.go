@@ -0,0 +1,293 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	inPlaceUpgradeJobPrefix      = "rke2-upgrade-"
+	inPlaceUpgradeNodeLabel      = "upgrade.cluster.x-k8s.io/node"
+	inPlaceUpgradeJobTTL         = 600
+	inPlaceUpgradeJobBackoff     = 2
+	hostRootMountPath            = "/host"
+	jobNameHashLength            = 10
+	mirrorPodAnnotation          = "kubernetes.io/config.mirror"
+	daemonSetOwnerKind           = "DaemonSet"
+	podEvictionGracePeriodSecond = 30
+)
+
+// CordonAndDrainNode marks the node as unschedulable and evicts the pods running on it.
+// DaemonSet and static pods are left untouched, as they are not rescheduled elsewhere.
+// It returns true once no evictable pods are left on the node.
+func (w *Workload) CordonAndDrainNode(ctx context.Context, nodeName string) (bool, error) {
+	node := &corev1.Node{}
+	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: nodeName}, node); err != nil {
+		return false, errors.Wrapf(err, "failed to get Node/%s", nodeName)
+	}
+
+	if !node.Spec.Unschedulable {
+		patch := ctrlclient.MergeFrom(node.DeepCopy())
+		node.Spec.Unschedulable = true
+
+		if err := w.Client.Patch(ctx, node, patch); err != nil {
+			return false, errors.Wrapf(err, "failed to cordon Node/%s", nodeName)
+		}
+	}
+
+	pods := &corev1.PodList{}
+	if err := w.Client.List(ctx, pods, ctrlclient.MatchingFields{"spec.nodeName": nodeName}); err != nil {
+		return false, errors.Wrapf(err, "failed to list pods on Node/%s", nodeName)
+	}
+
+	drained := true
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isEvictablePod(pod) {
+			continue
+		}
+
+		drained = false
+
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		eviction := &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+			DeleteOptions: &metav1.DeleteOptions{
+				GracePeriodSeconds: ptr.To[int64](podEvictionGracePeriodSecond),
+			},
+		}
+
+		if err := w.Client.SubResource("eviction").Create(ctx, pod, eviction); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+
+			// Evictions blocked by a PodDisruptionBudget are retried on the next reconciliation.
+			if apierrors.IsTooManyRequests(err) {
+				log.FromContext(ctx).Info("Pod eviction blocked by a disruption budget", "pod", ctrlclient.ObjectKeyFromObject(pod))
+
+				continue
+			}
+
+			return false, errors.Wrapf(err, "failed to evict pod %s/%s from Node/%s", pod.Namespace, pod.Name, nodeName)
+		}
+	}
+
+	return drained, nil
+}
+
+// UncordonNode marks the node as schedulable.
+func (w *Workload) UncordonNode(ctx context.Context, nodeName string) error {
+	node := &corev1.Node{}
+	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: nodeName}, node); err != nil {
+		return errors.Wrapf(err, "failed to get Node/%s", nodeName)
+	}
+
+	if !node.Spec.Unschedulable {
+		return nil
+	}
+
+	patch := ctrlclient.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = false
+
+	if err := w.Client.Patch(ctx, node, patch); err != nil {
+		return errors.Wrapf(err, "failed to uncordon Node/%s", nodeName)
+	}
+
+	return nil
+}
+
+// UpgradeNodeInPlace replaces the RKE2 binaries of the node with the given version and restarts the RKE2 service,
+// following the system-upgrade-controller model: a privileged Job pinned to the node runs the upgrade image against
+// the host filesystem. It returns true once the node reports the desired version.
+func (w *Workload) UpgradeNodeInPlace(ctx context.Context, nodeName, version, image string) (bool, error) {
+	node := &corev1.Node{}
+	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: nodeName}, node); err != nil {
+		return false, errors.Wrapf(err, "failed to get Node/%s", nodeName)
+	}
+
+	if node.Status.NodeInfo.KubeletVersion == version {
+		return true, nil
+	}
+
+	job := &batchv1.Job{}
+
+	err := w.Client.Get(ctx, ctrlclient.ObjectKey{Namespace: metav1.NamespaceSystem, Name: inPlaceUpgradeJobName(nodeName, version)}, job)
+	if apierrors.IsNotFound(err) {
+		log.FromContext(ctx).Info("Creating in-place upgrade job", "node", nodeName, "version", version)
+
+		if err := w.Client.Create(ctx, newInPlaceUpgradeJob(nodeName, version, image)); err != nil {
+			return false, errors.Wrapf(err, "failed to create in-place upgrade job for Node/%s", nodeName)
+		}
+
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "failed to get in-place upgrade job for Node/%s", nodeName)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			// Remove the failed job so that the upgrade is attempted again on the next reconciliation.
+			if err := w.Client.Delete(ctx, job, ctrlclient.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+				return false, errors.Wrapf(err, "failed to delete failed in-place upgrade job for Node/%s", nodeName)
+			}
+
+			return false, errors.Errorf("in-place upgrade job for Node/%s failed: %s", nodeName, condition.Message)
+		}
+	}
+
+	// The node is considered upgraded only once the restarted kubelet reports the new version.
+	return false, nil
+}
+
+// inPlaceUpgradeJobName returns a name for the upgrade job of a node which is unique per version
+// and fits the label value length limit.
+func inPlaceUpgradeJobName(nodeName, version string) string {
+	hash := sha256.Sum256([]byte(nodeName + version))
+
+	return inPlaceUpgradeJobPrefix + fmt.Sprintf("%x", hash)[:jobNameHashLength]
+}
+
+// inPlaceUpgradeImage returns the upgrade image tagged with the given RKE2 version.
+// The "+" of RKE2 versions is not valid in image tags and is replaced by "-", as done by the system-upgrade-controller.
+func inPlaceUpgradeImage(image, version string) string {
+	return image + ":" + strings.ReplaceAll(version, "+", "-")
+}
+
+// nodeJobImage returns the upgrade image tagged with the RKE2 version running on the node, as reported by its kubelet.
+// Jobs running the rke2 binary of the host use it rather than the desired version of the control plane, which the node
+// may not run yet.
+func (w *Workload) nodeJobImage(ctx context.Context, nodeName, image string) (string, error) {
+	node := &corev1.Node{}
+	if err := w.Client.Get(ctx, ctrlclient.ObjectKey{Name: nodeName}, node); err != nil {
+		return "", errors.Wrapf(err, "failed to get Node/%s", nodeName)
+	}
+
+	if node.Status.NodeInfo.KubeletVersion == "" {
+		return "", errors.Errorf("Node/%s does not report its RKE2 version yet", nodeName)
+	}
+
+	return inPlaceUpgradeImage(image, node.Status.NodeInfo.KubeletVersion), nil
+}
+
+func newInPlaceUpgradeJob(nodeName, version, image string) *batchv1.Job {
+	hostPathDirectory := corev1.HostPathDirectory
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      inPlaceUpgradeJobName(nodeName, version),
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				inPlaceUpgradeNodeLabel: nodeName,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](inPlaceUpgradeJobBackoff),
+			TTLSecondsAfterFinished: ptr.To[int32](inPlaceUpgradeJobTTL),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						inPlaceUpgradeNodeLabel: nodeName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostIPC:       true,
+					HostPID:       true,
+					HostNetwork:   true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:  "upgrade",
+							Image: inPlaceUpgradeImage(image, version),
+							Args:  []string{"upgrade"},
+							Env: []corev1.EnvVar{
+								{Name: "SYSTEM_UPGRADE_NODE_NAME", Value: nodeName},
+								{Name: "SYSTEM_UPGRADE_PLAN_LATEST_VERSION", Value: version},
+							},
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: ptr.To(true),
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "host-root", MountPath: hostRootMountPath},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "host-root",
+							VolumeSource: corev1.VolumeSource{
+								HostPath: &corev1.HostPathVolumeSource{
+									Path: "/",
+									Type: &hostPathDirectory,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// isEvictablePod returns true if the pod has to be evicted when draining a node.
+func isEvictablePod(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == daemonSetOwnerKind {
+			return false
+		}
+	}
+
+	// The upgrade job itself must keep running on the node.
+	if _, ok := pod.Labels[inPlaceUpgradeNodeLabel]; ok {
+		return false
+	}
+
+	return true
+}
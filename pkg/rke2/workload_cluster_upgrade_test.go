@@ -0,0 +1,131 @@
+/*
+Copyright 2024 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCordonAndDrainNode(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cp1"}}
+	workloadPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "cp1"},
+	}
+	daemonSetPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "daemon",
+			Namespace:       "kube-system",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "daemon"}},
+		},
+		Spec: corev1.PodSpec{NodeName: "cp1"},
+	}
+	staticPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "kube-apiserver-cp1",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{mirrorPodAnnotation: "hash"},
+		},
+		Spec: corev1.PodSpec{NodeName: "cp1"},
+	}
+	otherNodePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "cp2"},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithObjects(node, workloadPod, daemonSetPod, staticPod, otherNodePod).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(o client.Object) []string {
+			return []string{o.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+	w := &Workload{Client: cl}
+
+	drained, err := w.CordonAndDrainNode(ctx, "cp1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(drained).To(BeFalse())
+
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+	g.Expect(node.Spec.Unschedulable).To(BeTrue())
+
+	g.Expect(apierrors.IsNotFound(cl.Get(ctx, client.ObjectKeyFromObject(workloadPod), &corev1.Pod{}))).To(BeTrue())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(daemonSetPod), &corev1.Pod{})).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(staticPod), &corev1.Pod{})).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(otherNodePod), &corev1.Pod{})).To(Succeed())
+
+	drained, err = w.CordonAndDrainNode(ctx, "cp1")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(drained).To(BeTrue())
+
+	g.Expect(w.UncordonNode(ctx, "cp1")).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+	g.Expect(node.Spec.Unschedulable).To(BeFalse())
+}
+
+func TestUpgradeNodeInPlace(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	version := "v1.29.3+rke2r1"
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "cp1"},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.28.8+rke2r1"},
+		},
+	}
+
+	cl := fake.NewClientBuilder().WithObjects(node).Build()
+	w := &Workload{Client: cl}
+
+	upgraded, err := w.UpgradeNodeInPlace(ctx, "cp1", version, "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(upgraded).To(BeFalse())
+
+	job := &batchv1.Job{}
+	jobKey := client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: inPlaceUpgradeJobName("cp1", version)}
+	g.Expect(cl.Get(ctx, jobKey, job)).To(Succeed())
+	g.Expect(job.Spec.Template.Spec.NodeName).To(Equal("cp1"))
+	g.Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("rancher/rke2-upgrade:v1.29.3-rke2r1"))
+
+	// A failed job is reported and removed so that it is created again.
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	g.Expect(cl.Status().Update(ctx, job)).To(Succeed())
+
+	_, err = w.UpgradeNodeInPlace(ctx, "cp1", version, "rancher/rke2-upgrade")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(apierrors.IsNotFound(cl.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+	// The node is upgraded once the kubelet reports the desired version.
+	node.Status.NodeInfo.KubeletVersion = version
+	g.Expect(cl.Status().Update(ctx, node)).To(Succeed())
+
+	upgraded, err = w.UpgradeNodeInPlace(ctx, "cp1", version, "rancher/rke2-upgrade")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(upgraded).To(BeTrue())
+}
@@ -166,6 +166,10 @@ type ExternalCertificate struct {
 	Purpose   Purpose
 	Generated bool
 	KeyPair   *certs.KeyPair
+
+	// Source is the name prefix and namespace of the secret holding the certificate, defaulting to
+	// the cluster secrets in the kube-system namespace.
+	Source client.ObjectKey
 }
 
 // SaveGenerated implements Certificate.
@@ -230,6 +234,22 @@ func NewCertificatesForInitialControlPlane() Certificates {
 	return certificates
 }
 
+// NewCertificatesForImportedControlPlane returns a list of the certificates of an existing control plane, read from the
+// secrets with the source name prefix and namespace.
+func NewCertificatesForImportedControlPlane(reader client.Reader, source client.ObjectKey) Certificates {
+	certificates := Certificates{}
+
+	for _, purpose := range []Purpose{ClusterCA, ClientClusterCA, EtcdCA, EtcdServerCA} {
+		certificates = append(certificates, &ExternalCertificate{
+			Reader:  reader,
+			Purpose: purpose,
+			Source:  source,
+		})
+	}
+
+	return certificates
+}
+
 // GetByPurpose returns a certificate by the given name.
 // This could be removed if we use a map instead of a slice to hold certificates, however other code becomes more complex.
 func (c Certificates) GetByPurpose(purpose Purpose) Certificate {
@@ -319,6 +339,13 @@ func (c *ExternalCertificate) Lookup(ctx context.Context, _ client.Reader, _ cli
 		Namespace: metav1.NamespaceSystem,
 	}
 
+	if c.Source.Name != "" {
+		key = client.ObjectKey{
+			Name:      Name(c.Source.Name, c.GetPurpose()),
+			Namespace: c.Source.Namespace,
+		}
+	}
+
 	if err := c.Get(ctx, key, s); err != nil {
 		if apierrors.IsNotFound(err) {
 			if c.IsExternal() {
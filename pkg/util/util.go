@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"regexp"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,6 +38,16 @@ import (
 const (
 	// RKE2_CIS_VERSION_CHANGE is the version where the CIS benchmark changed in RKE2 (because of PSPs).
 	RKE2_CIS_VERSION_CHANGE = "v1.25.0"
+
+	// KubeletSkewVersionChange is the version from which the kubelet may be three minor versions older than the API server.
+	KubeletSkewVersionChange = "v1.28.0"
+
+	// MaxWorkerMinorVersionSkew is the maximum number of minor versions a worker may be behind the control plane.
+	MaxWorkerMinorVersionSkew = 3
+
+	// maxWorkerMinorVersionSkewBefore128 is the maximum number of minor versions a worker may be behind
+	// a control plane older than v1.28.
+	maxWorkerMinorVersionSkewBefore128 = 2
 )
 
 // ErrControlPlaneNotFound is returned when a control plane is not found.
@@ -107,11 +118,30 @@ func Random(size int) (string, error) {
 	return hex.EncodeToString(token), err
 }
 
+// IPFamilyOf returns the IP family of the given address, or an empty family if it is not an IP address.
+func IPFamilyOf(address string) controlplanev1.IPFamily {
+	ip := net.ParseIP(address)
+
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return controlplanev1.IPv4IPFamily
+	default:
+		return controlplanev1.IPv6IPFamily
+	}
+}
+
 // TokenName returns a token name from the cluster name.
 func TokenName(clusterName string) string {
 	return fmt.Sprintf("%s-token", clusterName)
 }
 
+// AgentTokenName returns an agent token name from the cluster name.
+func AgentTokenName(clusterName string) string {
+	return fmt.Sprintf("%s-agent-token", clusterName)
+}
+
 // Rke2ToKubeVersion converts an RKE2 version to a Kubernetes version.
 func Rke2ToKubeVersion(rk2Version string) (kubeVersion string, err error) {
 	regexStr := "v(\\d\\.\\d{2}\\.\\d)\\+rke2r\\d"
@@ -167,6 +197,47 @@ func CompareVersions(v1 string, v2 string) bool {
 	return v1 == v2
 }
 
+// ControlPlaneVersion returns the lowest version running on the control plane machines, falling back to the
+// desired version while the control plane has not reported one yet.
+func ControlPlaneVersion(rcp *controlplanev1.RKE2ControlPlane) string {
+	if rcp.Status.Version != nil && *rcp.Status.Version != "" {
+		return *rcp.Status.Version
+	}
+
+	return rcp.GetDesiredVersion()
+}
+
+// CheckWorkerVersionSkew returns an error if the worker version is newer than the control plane version,
+// or if it is more minor versions behind the control plane than the Kubernetes version skew policy allows.
+// Both versions can either be RKE2 or Kubernetes versions.
+func CheckWorkerVersionSkew(workerVersion, controlPlaneVersion string) error {
+	worker, err := version.ParseGeneric(workerVersion)
+	if err != nil {
+		return fmt.Errorf("parsing worker version %q: %w", workerVersion, err)
+	}
+
+	controlPlane, err := version.ParseGeneric(controlPlaneVersion)
+	if err != nil {
+		return fmt.Errorf("parsing control plane version %q: %w", controlPlaneVersion, err)
+	}
+
+	if !controlPlane.AtLeast(worker) {
+		return fmt.Errorf("worker version %s is newer than control plane version %s", workerVersion, controlPlaneVersion)
+	}
+
+	maxSkew := uint(maxWorkerMinorVersionSkewBefore128)
+	if controlPlane.AtLeast(version.MustParseGeneric(KubeletSkewVersionChange)) {
+		maxSkew = MaxWorkerMinorVersionSkew
+	}
+
+	if worker.Major() != controlPlane.Major() || controlPlane.Minor()-worker.Minor() > maxSkew {
+		return fmt.Errorf("worker version %s is more than %d minor versions behind control plane version %s",
+			workerVersion, maxSkew, controlPlaneVersion)
+	}
+
+	return nil
+}
+
 // GetMapKeysAsString returns a comma separated string of keys from a map.
 func GetMapKeysAsString(m map[string][]byte) (keys string) {
 	for k := range m {
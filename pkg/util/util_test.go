@@ -39,3 +39,24 @@ var _ = Describe("Testing IsRKE2Version", func() {
 		Expect(IsRKE2Version(k8sVersion)).To(BeFalse())
 	})
 })
+
+var _ = Describe("Testing CheckWorkerVersionSkew", func() {
+	It("Should allow a worker running the control plane version", func() {
+		Expect(CheckWorkerVersionSkew("v1.29.4+rke2r1", "v1.29.4+rke2r1")).To(Succeed())
+	})
+
+	It("Should allow a worker within the allowed minor version skew", func() {
+		Expect(CheckWorkerVersionSkew("v1.26.15+rke2r1", "v1.29.4+rke2r1")).To(Succeed())
+		Expect(CheckWorkerVersionSkew("1.25.16", "v1.27.13+rke2r1")).To(Succeed())
+	})
+
+	It("Should refuse a worker newer than the control plane", func() {
+		Expect(CheckWorkerVersionSkew("v1.29.5+rke2r1", "v1.29.4+rke2r1")).ToNot(Succeed())
+		Expect(CheckWorkerVersionSkew("v1.30.0+rke2r1", "v1.29.4+rke2r1")).ToNot(Succeed())
+	})
+
+	It("Should refuse a worker too many minor versions behind the control plane", func() {
+		Expect(CheckWorkerVersionSkew("v1.25.16+rke2r1", "v1.29.4+rke2r1")).ToNot(Succeed())
+		Expect(CheckWorkerVersionSkew("v1.24.17+rke2r1", "v1.27.13+rke2r1")).ToNot(Succeed())
+	})
+})